@@ -3,15 +3,53 @@ package api
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"io"
 
 	"net/http"
 	db "simple_bank/db/sqlc"
 	"simple_bank/token"
+	"simple_bank/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 )
 
+// error codes returned alongside business rule failures of an account
+const (
+	errCodeInvalidAccountStatus = "invalid_account_status"
+	errCodeAccountNotActive     = "account_not_active"
+	errCodeAccountNotEmpty      = "account_not_empty"
+)
+
+// accountResponse is an account along with its balances
+// the ledger balance counts every posted entry, the available balance also takes out the funds held on the account
+type accountResponse struct {
+	db.Account
+	LedgerBalance    int64 `json:"ledger_balance"`
+	AvailableBalance int64 `json:"available_balance"`
+}
+
+func newAccountResponse(account db.Account, heldAmount int64) accountResponse {
+	return accountResponse{
+		Account:          account,
+		LedgerBalance:    account.Balance,
+		AvailableBalance: account.Balance - heldAmount,
+	}
+}
+
+// accountWithBalances queries the funds held on the account to return it along with its balances
+func (server *Server) accountWithBalances(ctx *gin.Context, account db.Account) (accountResponse, error) {
+	heldAmount, err := server.store.GetAccountHeldAmount(ctx, account.ID)
+
+	if err != nil {
+		return accountResponse{}, err
+	}
+
+	return newAccountResponse(account, heldAmount), nil
+}
+
 // CreateAccountRequest stores the create account requests
 type CreateAccountRequest struct {
 	// remove Owner to add authorization rule
@@ -59,7 +97,8 @@ func (server *Server) createAcccount(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	// a new account has no held funds
+	ctx.JSON(http.StatusOK, newAccountResponse(account, 0))
 
 }
 
@@ -68,40 +107,20 @@ type GetAccountRequest struct {
 	ID int64 `uri:"id" binding:"required,min=1"`
 }
 
+// getAccount returns the account of the uri along with its balances
+// the account was looked up and authorized by accountMiddleware
 func (server *Server) getAccount(ctx *gin.Context) {
-	var req GetAccountRequest
+	// notice type assertion to the Account type at the end
+	account := ctx.MustGet(authorizationResourceKey).(db.Account)
 
-	err := ctx.ShouldBindUri(&req)
+	rsp, err := server.accountWithBalances(ctx, account)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-
-		return
-	}
-
-	account, err := server.store.GetAccount(ctx, req.ID)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	// create the authorization rule here before returning the account
-	// a logged in user can only get details of account he/she owns
-	// notice type assertion to the Payload interface at the end
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-
-	if account.Owner != authPayload.Username {
-		err := errors.New("account doesn't belong to the authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
-		return
-	}
-
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, rsp)
 
 }
 
@@ -133,9 +152,116 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 		Offset: (req.PageID - 1) * req.PageSize,
 	}
 
-	account, err := server.store.ListAccounts(ctx, arg)
+	accounts, err := server.store.ListAccounts(ctx, arg)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]accountResponse, len(accounts))
+
+	for i, account := range accounts {
+		rsp[i], err = server.accountWithBalances(ctx, account)
+
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+
+}
+
+// changeAccountStatus moves the account of the uri to the new status
+// the account was looked up and authorized by accountMiddleware
+func (server *Server) changeAccountStatus(ctx *gin.Context, newStatus string) {
+	account := ctx.MustGet(authorizationResourceKey).(db.Account)
+
+	// the deposits, fees and interest of every customer go through the system accounts
+	if utils.IsSystemAccountOwner(account.Owner) {
+		err := errors.New("the status of a system account of the bank cannot change")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	if !utils.CanTransitionAccountStatus(account.Status, newStatus) {
+		err := fmt.Errorf("account status cannot change from %s to %s", account.Status, newStatus)
+		ctx.JSON(http.StatusConflict, codedErrorResponse(errCodeInvalidAccountStatus, err))
+		return
+	}
+
+	// the update only applies if no one else changed the status in the meantime
+	account, err := server.store.UpdateAccountStatus(ctx, db.UpdateAccountStatusParams{
+		ID:        account.ID,
+		OldStatus: account.Status,
+		NewStatus: newStatus,
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err := errors.New("account status was changed concurrently")
+			ctx.JSON(http.StatusConflict, codedErrorResponse(errCodeInvalidAccountStatus, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, account)
+
+}
+
+// freezeAccount blocks all money movements of an account until it is unfrozen
+// the owner can freeze his/her own account, e.g. after losing a card
+func (server *Server) freezeAccount(ctx *gin.Context) {
+	server.changeAccountStatus(ctx, utils.AccountStatusFrozen)
+}
+
+// unfreezeAccount lets money move again through a frozen account
+// only bankers and admins can unfreeze, so a freeze placed by the bank cannot be lifted by the owner
+func (server *Server) unfreezeAccount(ctx *gin.Context) {
+	server.changeAccountStatus(ctx, utils.AccountStatusActive)
+}
+
+// SetOverdraftLimitRequest stores the set overdraft limit requests
+// a limit of zero keeps the balance from going below zero
+type SetOverdraftLimitRequest struct {
+	OverdraftLimit *int64 `json:"overdraft_limit" binding:"required,min=0"`
+}
+
+// setOverdraftLimit sets how far below zero the balance of the account of the uri may go
+// only bankers and admins can set it, the account was looked up by accountMiddleware
+func (server *Server) setOverdraftLimit(ctx *gin.Context) {
+	var req SetOverdraftLimitRequest
+
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account := ctx.MustGet(authorizationResourceKey).(db.Account)
+
+	// the funds of the system accounts are never checked, so a limit on them would mean nothing
+	if utils.IsSystemAccountOwner(account.Owner) {
+		err := errors.New("the overdraft limit of a system account of the bank cannot change")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	account, err = server.store.UpdateAccountOverdraftLimit(ctx, db.UpdateAccountOverdraftLimitParams{
+		ID:             account.ID,
+		OverdraftLimit: *req.OverdraftLimit,
+	})
 
 	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -143,3 +269,145 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, account)
 
 }
+
+// CloseAccountRequest stores the close account requests
+// the sweep account is only needed when the account still holds money
+type CloseAccountRequest struct {
+	SweepAccountID int64 `json:"sweep_account_id" binding:"omitempty,min=1"`
+}
+
+// closeAccount permanently closes an account
+// the account was looked up and authorized by accountMiddleware
+func (server *Server) closeAccount(ctx *gin.Context) {
+	var req CloseAccountRequest
+
+	// the body is optional
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account := ctx.MustGet(authorizationResourceKey).(db.Account)
+
+	// the deposits, fees and interest of every customer go through the system accounts
+	if utils.IsSystemAccountOwner(account.Owner) {
+		err := errors.New("the status of a system account of the bank cannot change")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	// the sweep skips the transfer checks and limits, so it can only go to another account of the same owner and currency
+	if req.SweepAccountID != 0 && req.SweepAccountID != account.ID {
+		sweepAccount, valid := server.existingAccount(ctx, req.SweepAccountID)
+		if !valid {
+			return
+		}
+
+		if sweepAccount.Owner != account.Owner {
+			ctx.JSON(http.StatusForbidden, errorResponse(db.ErrSweepAccountOwner))
+			return
+		}
+
+		if sweepAccount.Currency != account.Currency {
+			ctx.JSON(http.StatusBadRequest, errorResponse(db.ErrCurrencyMismatch))
+			return
+		}
+	}
+
+	result, err := server.store.CloseAccountTx(ctx, db.CloseAccountTxParams{
+		AccountID:      account.ID,
+		SweepAccountID: req.SweepAccountID,
+	})
+
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		case errors.Is(err, db.ErrAccountNotActive):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeAccountNotActive, err))
+		case errors.Is(err, db.ErrAccountNotEmpty):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeAccountNotEmpty, err))
+		case errors.Is(err, db.ErrSweepAccountOwner):
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		case errors.Is(err, db.ErrCurrencyMismatch),
+			errors.Is(err, db.ErrCashAccount),
+			errors.Is(err, db.ErrFeeAccount),
+			errors.Is(err, db.ErrInterestAccount):
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+
+}
+
+// GetAccountBalanceRequest stores the get account balance requests
+type GetAccountBalanceRequest struct {
+	At time.Time `form:"at" binding:"required"`
+}
+
+// accountBalanceResponse is the balance of an account at a point in time
+type accountBalanceResponse struct {
+	AccountID int64     `json:"account_id"`
+	Currency  string    `json:"currency"`
+	Balance   int64     `json:"balance"`
+	At        time.Time `json:"at"`
+}
+
+// getAccountBalance returns the balance an account had at the given time
+// it is computed from the entries, starting from the last daily snapshot before that time
+func (server *Server) getAccountBalance(ctx *gin.Context) {
+	var req GetAccountBalanceRequest
+
+	err := ctx.ShouldBindQuery(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.At.After(time.Now()) {
+		err := errors.New("at must not be in the future")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account := ctx.MustGet(authorizationResourceKey).(db.Account)
+
+	balance, err := server.store.GetAccountBalanceAt(ctx, db.GetAccountBalanceAtParams{
+		AccountID: account.ID,
+		At:        req.At,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accountBalanceResponse{
+		AccountID: account.ID,
+		Currency:  account.Currency,
+		Balance:   balance,
+		At:        req.At,
+	})
+
+}
+
+// accountMiddleware looks up the account of the uri, which its owner and the roles granted perm may access
+func (server *Server) accountMiddleware(perm permission) gin.HandlerFunc {
+	return ownershipMiddleware("account", perm, func(ctx *gin.Context) (interface{}, []string, bool) {
+		id, valid := resourceID(ctx)
+		if !valid {
+			return nil, nil, false
+		}
+
+		account, valid := server.existingAccount(ctx, id)
+
+		return account, []string{account.Owner}, valid
+	})
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// auditSessionIDKey lets a handler record the session a call started, like a login, in the audit log
+	auditSessionIDKey = "audit_session_id"
+	// maxAuditSummarySize is the largest request body kept in the audit log, only the size of larger ones is kept
+	maxAuditSummarySize = 4 << 10
+	// maxAuditedBodySize is the largest request body an audited call may send, the body is read whole before the handler runs
+	maxAuditedBodySize = 1 << 20
+	redactedValue      = "[REDACTED]"
+)
+
+// auditedMethods are the http methods of the calls that change something
+var auditedMethods = map[string]bool{
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// auditedRoutes are the routes that change something even though they are called with a GET
+var auditedRoutes = map[string]bool{
+	"/users/verify_email": true,
+}
+
+// redactedFields are the request body fields that are never written to the audit log
+var redactedFields = map[string]bool{
+	"password":      true,
+	"old_password":  true,
+	"new_password":  true,
+	"refresh_token": true,
+	"secret_code":   true,
+}
+
+// auditMiddleware writes every mutating call to the audit log once it is handled, whether it succeeded or not
+func (server *Server) auditMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !auditedMethods[ctx.Request.Method] && !auditedRoutes[ctx.FullPath()] {
+			ctx.Next()
+			return
+		}
+
+		// the body is read ahead of the handler, so it is put back for the handler to bind
+		var body []byte
+
+		if ctx.Request.Body != nil {
+			var err error
+
+			body, err = io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxAuditedBodySize))
+
+			if err != nil {
+				// the limited reader fails once it has read the whole limit
+				if len(body) >= maxAuditedBodySize {
+					ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, errorResponse(err))
+					return
+				}
+				ctx.AbortWithStatusJSON(http.StatusBadRequest, errorResponse(err))
+				return
+			}
+
+			ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		ctx.Next()
+
+		// calls to routes that do not exist change nothing
+		route := ctx.FullPath()
+
+		if route == "" {
+			return
+		}
+
+		arg := server.newAuditLog(ctx, body)
+		arg.Route = route
+
+		_, err := server.store.CreateAuditLog(ctx, arg)
+
+		if err != nil {
+			// the response is already written, so the failure can only be logged
+			log.Printf("cannot write audit log of %s %s: %v", arg.Method, arg.Route, err)
+		}
+	}
+}
+
+// newAuditLog builds the audit log of a handled call out of its request body, uri and authorization
+func (server *Server) newAuditLog(ctx *gin.Context, body []byte) db.CreateAuditLogParams {
+	arg := db.CreateAuditLogParams{
+		ClientIp: ctx.ClientIP(),
+		Method:   ctx.Request.Method,
+		Status:   int32(ctx.Writer.Status()),
+	}
+
+	fields := map[string]interface{}{}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// keeps the ids exactly as they were sent
+	decoder.UseNumber()
+
+	if decoder.Decode(&fields) != nil {
+		fields = map[string]interface{}{}
+	}
+
+	// the calls made with a GET, like verifying an email, send their fields in the query
+	for key, values := range ctx.Request.URL.Query() {
+		if _, ok := fields[key]; !ok {
+			fields[key] = values[0]
+		}
+	}
+
+	targetIDs := map[string]string{}
+
+	for key, value := range fields {
+		if key == "username" || strings.HasSuffix(key, "_id") {
+			targetIDs[key] = fmt.Sprint(value)
+		}
+	}
+
+	for _, param := range ctx.Params {
+		targetIDs[param.Key] = param.Value
+	}
+
+	if payload, ok := ctx.Get(authorizationPayloadKey); ok {
+		authPayload := payload.(*token.Payload)
+
+		arg.Actor = authPayload.Username
+		arg.ActorRole = authPayload.Role
+		arg.SessionID = uuid.NullUUID{UUID: authPayload.SessionID, Valid: authPayload.SessionID != uuid.Nil}
+	} else if refreshToken, ok := fields["refresh_token"].(string); ok {
+		// logouts and renewals are made with a refresh token instead of an access token
+		refreshPayload, err := server.tokenMaker.VerifyToken(refreshToken, token.TokenTypeRefreshToken)
+
+		if err == nil {
+			arg.Actor = refreshPayload.Username
+			arg.ActorRole = refreshPayload.Role
+			arg.SessionID = uuid.NullUUID{UUID: refreshPayload.ID, Valid: true}
+		}
+	} else if username, ok := fields["username"].(string); ok && ctx.FullPath() == "/users/login" {
+		// a login is made by the user it names, even when it fails
+		arg.Actor = username
+	}
+
+	if sessionID, ok := ctx.Get(auditSessionIDKey); ok {
+		arg.SessionID = uuid.NullUUID{UUID: sessionID.(uuid.UUID), Valid: true}
+	}
+
+	var summary interface{} = redactFields(fields)
+
+	if len(body) > maxAuditSummarySize {
+		summary = gin.H{"size": len(body)}
+	}
+
+	arg.RequestSummary, _ = json.Marshal(summary)
+	arg.TargetIds, _ = json.Marshal(targetIDs)
+
+	return arg
+}
+
+// redactFields replaces the secrets of a request body, at any depth, so they never reach the audit log
+func redactFields(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(value))
+
+		for key, field := range value {
+			if redactedFields[key] {
+				redacted[key] = redactedValue
+				continue
+			}
+			redacted[key] = redactFields(field)
+		}
+
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+
+		for i, item := range value {
+			redacted[i] = redactFields(item)
+		}
+
+		return redacted
+	}
+
+	return value
+}
+
+// ListAuditLogsRequest stores the list audit logs requests
+// the filters that are not set match every log
+type ListAuditLogsRequest struct {
+	PageID    int32     `form:"page_id" binding:"required,min=1"`
+	PageSize  int32     `form:"page_size" binding:"required,min=1,max=50"`
+	Actor     string    `form:"actor"`
+	SessionID string    `form:"session_id" binding:"omitempty,uuid"`
+	Method    string    `form:"method" binding:"omitempty,oneof=GET POST PUT PATCH DELETE"`
+	Route     string    `form:"route"`
+	TargetID  string    `form:"target_id"`
+	Status    int32     `form:"status" binding:"omitempty,min=100,max=599"`
+	From      time.Time `form:"from"`
+	To        time.Time `form:"to"`
+}
+
+// listAuditLogs searches the audit log, the most recent calls first
+func (server *Server) listAuditLogs(ctx *gin.Context) {
+	var req ListAuditLogsRequest
+
+	err := ctx.ShouldBindQuery(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !req.From.IsZero() && !req.To.IsZero() && req.From.After(req.To) {
+		err := errors.New("from date must be before to date")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListAuditLogsParams{
+		Actor:    sql.NullString{String: req.Actor, Valid: req.Actor != ""},
+		Method:   sql.NullString{String: req.Method, Valid: req.Method != ""},
+		Route:    sql.NullString{String: req.Route, Valid: req.Route != ""},
+		TargetID: sql.NullString{String: req.TargetID, Valid: req.TargetID != ""},
+		Status:   sql.NullInt32{Int32: req.Status, Valid: req.Status > 0},
+		FromTime: sql.NullTime{Time: req.From, Valid: !req.From.IsZero()},
+		ToTime:   sql.NullTime{Time: req.To, Valid: !req.To.IsZero()},
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	}
+
+	if req.SessionID != "" {
+		arg.SessionID = uuid.NullUUID{UUID: uuid.MustParse(req.SessionID), Valid: true}
+	}
+
+	auditLogs, err := server.store.ListAuditLogs(ctx, arg)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, auditLogs)
+
+}
@@ -0,0 +1,464 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"simple_bank/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	user, password := randomUser(t)
+	account := randomAccount(user.Username)
+
+	secretCode, err := newSecretCode()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		body       gin.H
+		setupAuth  func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs func(store *mockdb.MockStore)
+		checkLogs  func(t *testing.T, recorder *httptest.ResponseRecorder, logs []db.CreateAuditLogParams)
+	}{
+		{
+			name:   "AdminCall",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/users/%s/role", user.Username),
+			body: gin.H{
+				"role": utils.BankerRole,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+			},
+			checkLogs: func(t *testing.T, recorder *httptest.ResponseRecorder, logs []db.CreateAuditLogParams) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Len(t, logs, 1)
+
+				log := logs[0]
+				require.Equal(t, "admin", log.Actor)
+				require.Equal(t, utils.AdminRole, log.ActorRole)
+				require.True(t, log.SessionID.Valid)
+				require.Equal(t, http.MethodPatch, log.Method)
+				require.Equal(t, "/users/:username/role", log.Route)
+				require.Equal(t, int32(http.StatusOK), log.Status)
+				require.JSONEq(t, fmt.Sprintf(`{"username": %q}`, user.Username), string(log.TargetIds))
+				require.JSONEq(t, fmt.Sprintf(`{"role": %q}`, utils.BankerRole), string(log.RequestSummary))
+			},
+		},
+		{
+			name:   "FailedLogin",
+			method: http.MethodPost,
+			url:    "/users/login",
+			body: gin.H{
+				"username": user.Username,
+				"password": "wrong-password",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkLogs: func(t *testing.T, recorder *httptest.ResponseRecorder, logs []db.CreateAuditLogParams) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Len(t, logs, 1)
+
+				log := logs[0]
+				require.Equal(t, user.Username, log.Actor)
+				require.Empty(t, log.ActorRole)
+				require.False(t, log.SessionID.Valid)
+				require.Equal(t, int32(http.StatusUnauthorized), log.Status)
+				require.NotContains(t, string(log.RequestSummary), "wrong-password")
+				require.JSONEq(t, fmt.Sprintf(`{"username": %q, "password": %q}`, user.Username, redactedValue), string(log.RequestSummary))
+			},
+		},
+		{
+			name:   "Login",
+			method: http.MethodPost,
+			url:    "/users/login",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+						return db.Session{
+							ID:       arg.ID,
+							Username: arg.Username,
+							FamilyID: arg.FamilyID,
+						}, nil
+					})
+			},
+			checkLogs: func(t *testing.T, recorder *httptest.ResponseRecorder, logs []db.CreateAuditLogParams) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Len(t, logs, 1)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+
+				log := logs[0]
+				require.Equal(t, user.Username, log.Actor)
+				require.Equal(t, uuid.NullUUID{UUID: rsp.SessionID, Valid: true}, log.SessionID)
+				require.NotContains(t, string(log.RequestSummary), password)
+			},
+		},
+		{
+			name:   "NoAuthorization",
+			method: http.MethodPost,
+			url:    "/transfers",
+			body: gin.H{
+				"from_account_id": account.ID,
+				"to_account_id":   account.ID + 1,
+				"amount":          10,
+				"currency":        account.Currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkLogs: func(t *testing.T, recorder *httptest.ResponseRecorder, logs []db.CreateAuditLogParams) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Len(t, logs, 1)
+
+				log := logs[0]
+				require.Empty(t, log.Actor)
+				require.Equal(t, "/transfers", log.Route)
+				require.Equal(t, int32(http.StatusUnauthorized), log.Status)
+				require.JSONEq(t, fmt.Sprintf(`{"from_account_id": "%d", "to_account_id": "%d"}`, account.ID, account.ID+1), string(log.TargetIds))
+			},
+		},
+		{
+			name:   "VerifyEmail",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/users/verify_email?email_id=%d&secret_code=%s", 7, secretCode),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmailTxResult{User: user}, nil)
+			},
+			checkLogs: func(t *testing.T, recorder *httptest.ResponseRecorder, logs []db.CreateAuditLogParams) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Len(t, logs, 1)
+
+				log := logs[0]
+				require.Equal(t, http.MethodGet, log.Method)
+				require.Equal(t, "/users/verify_email", log.Route)
+				require.JSONEq(t, `{"email_id": "7"}`, string(log.TargetIds))
+				require.NotContains(t, string(log.RequestSummary), secretCode)
+				require.JSONEq(t, fmt.Sprintf(`{"email_id": "7", "secret_code": %q}`, redactedValue), string(log.RequestSummary))
+			},
+		},
+		{
+			name:   "BodyTooLarge",
+			method: http.MethodPost,
+			url:    "/users",
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": strings.Repeat("a", maxAuditedBodySize),
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkLogs: func(t *testing.T, recorder *httptest.ResponseRecorder, logs []db.CreateAuditLogParams) {
+				require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+				require.Empty(t, logs)
+			},
+		},
+		{
+			name:   "ReadNotAudited",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					GetAccountHeldAmount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkLogs: func(t *testing.T, recorder *httptest.ResponseRecorder, logs []db.CreateAuditLogParams) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, logs)
+			},
+		},
+		{
+			name:   "UnknownRouteNotAudited",
+			method: http.MethodPost,
+			url:    "/unknown",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+			},
+			checkLogs: func(t *testing.T, recorder *httptest.ResponseRecorder, logs []db.CreateAuditLogParams) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Empty(t, logs)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			// record the audit logs instead of the catch all stub of the test server
+			var logs []db.CreateAuditLogParams
+
+			store.EXPECT().
+				CreateAuditLog(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				DoAndReturn(func(_ context.Context, arg db.CreateAuditLogParams) (db.AuditLog, error) {
+					logs = append(logs, arg)
+					return db.AuditLog{}, nil
+				})
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var data []byte
+
+			if tc.body != nil {
+				var err error
+				data, err = json.Marshal(tc.body)
+				require.NoError(t, err)
+			}
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkLogs(t, recorder, logs)
+		})
+	}
+}
+
+func TestListAuditLogsAPI(t *testing.T) {
+	sessionID := uuid.New()
+	from := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	auditLogs := []db.AuditLog{
+		{
+			ID:             2,
+			Actor:          "admin",
+			ActorRole:      utils.AdminRole,
+			SessionID:      uuid.NullUUID{UUID: sessionID, Valid: true},
+			Method:         http.MethodPatch,
+			Route:          "/users/:username/role",
+			TargetIds:      json.RawMessage(`{"username":"42"}`),
+			RequestSummary: json.RawMessage(`{"role":"banker"}`),
+			Status:         http.StatusOK,
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			query: url.Values{
+				"page_id":    {"2"},
+				"page_size":  {"5"},
+				"actor":      {"admin"},
+				"session_id": {sessionID.String()},
+				"method":     {http.MethodPatch},
+				"target_id":  {"42"},
+				"status":     {"200"},
+				"from":       {from.Format(time.RFC3339)},
+				"to":         {to.Format(time.RFC3339)},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAuditLogsParams{
+					Actor:     sql.NullString{String: "admin", Valid: true},
+					SessionID: uuid.NullUUID{UUID: sessionID, Valid: true},
+					Method:    sql.NullString{String: http.MethodPatch, Valid: true},
+					TargetID:  sql.NullString{String: "42", Valid: true},
+					Status:    sql.NullInt32{Int32: http.StatusOK, Valid: true},
+					FromTime:  sql.NullTime{Time: from, Valid: true},
+					ToTime:    sql.NullTime{Time: to, Valid: true},
+					Limit:     5,
+					Offset:    5,
+				}
+
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(auditLogs, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotAuditLogs []db.AuditLog
+				err := json.Unmarshal(recorder.Body.Bytes(), &gotAuditLogs)
+				require.NoError(t, err)
+				require.Equal(t, auditLogs, gotAuditLogs)
+			},
+		},
+		{
+			name: "BankerForbidden",
+			query: url.Values{
+				"page_id":   {"1"},
+				"page_size": {"5"},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "banker", utils.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidSessionID",
+			query: url.Values{
+				"page_id":    {"1"},
+				"page_size":  {"5"},
+				"session_id": {"not-a-uuid"},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "FromAfterTo",
+			query: url.Values{
+				"page_id":   {"1"},
+				"page_size": {"5"},
+				"from":      {to.Format(time.RFC3339)},
+				"to":        {from.Format(time.RFC3339)},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			query: url.Values{
+				"page_id":   {"1"},
+				"page_size": {"5"},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.AuditLog{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/audit-logs?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
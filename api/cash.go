@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	db "simple_bank/db/sqlc"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CashRequest stores the deposit and withdrawal requests
+// the currency must be the one of the account
+type CashRequest struct {
+	Amount   int64  `json:"amount" binding:"required,min=1"`
+	Currency string `json:"currency" binding:"required,currency"`
+}
+
+// createDeposit pays cash into the account of the uri
+func (server *Server) createDeposit(ctx *gin.Context) {
+	server.moveCash(ctx, server.store.DepositTx)
+}
+
+// createWithdrawal pays cash out of the account of the uri
+func (server *Server) createWithdrawal(ctx *gin.Context) {
+	server.moveCash(ctx, server.store.WithdrawTx)
+}
+
+// moveCash runs the deposit or withdrawal transaction against the account of the uri
+func (server *Server) moveCash(ctx *gin.Context, cashTx func(context.Context, db.CashTxParams) (db.TransferTxResult, error)) {
+	var uri GetAccountRequest
+
+	err := ctx.ShouldBindUri(&uri)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req CashRequest
+
+	err = ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, valid := server.validAccount(ctx, uri.ID, req.Currency)
+	if !valid {
+		return
+	}
+
+	result, err := cashTx(ctx, db.CashTxParams{
+		AccountID: uri.ID,
+		Amount:    req.Amount,
+	})
+
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		case errors.Is(err, db.ErrCashAccount), errors.Is(err, db.ErrFeeAccount), errors.Is(err, db.ErrInterestAccount):
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		case errors.Is(err, db.ErrInsufficientFunds):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeInsufficientFunds, err))
+		case errors.Is(err, db.ErrAccountNotActive):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeAccountNotActive, err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	db "simple_bank/db/sqlc"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListEntriesRequest stores the list entries requests
+// From and To are optional RFC3339 timestamps used to filter the statement
+type ListEntriesRequest struct {
+	PageID   int32     `form:"page_id" binding:"required,min=1"`
+	PageSize int32     `form:"page_size" binding:"required,min=1,max=50"`
+	From     time.Time `form:"from"`
+	To       time.Time `form:"to"`
+}
+
+// listEntries returns the statement of the account of the uri
+// the account was looked up and authorized by accountMiddleware
+func (server *Server) listEntries(ctx *gin.Context) {
+	var req ListEntriesRequest
+
+	err := ctx.ShouldBindQuery(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// an empty to date means the statement runs up to now
+	if req.To.IsZero() {
+		req.To = time.Now()
+	}
+
+	if req.From.After(req.To) {
+		err := errors.New("from date must be before to date")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account := ctx.MustGet(authorizationResourceKey).(db.Account)
+
+	arg := db.ListAccountStatementParams{
+		AccountID: account.ID,
+		FromTime:  req.From,
+		ToTime:    req.To,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	}
+
+	entries, err := server.store.ListAccountStatement(ctx, arg)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entries)
+
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	db "simple_bank/db/sqlc"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// FeeTierRequest stores a tier of the create fee schedule requests
+// the fee of an amount is the flat fee plus the basis points of the amount,
+// using the highest tier whose min amount is not above the amount
+type FeeTierRequest struct {
+	MinAmount   int64 `json:"min_amount" binding:"min=0"`
+	FlatFee     int64 `json:"flat_fee" binding:"min=0"`
+	BasisPoints int32 `json:"basis_points" binding:"min=0,max=10000"`
+}
+
+// CreateFeeScheduleRequest stores the create fee schedule requests
+// a flat or a percentage fee is a single tier starting at 0, the schedule applies
+// from the effective time on, right away when it is omitted
+type CreateFeeScheduleRequest struct {
+	Currency     string           `json:"currency" binding:"required,currency"`
+	TransferType string           `json:"transfer_type" binding:"required,oneof=transfer withdrawal"`
+	EffectiveAt  time.Time        `json:"effective_at"`
+	Tiers        []FeeTierRequest `json:"tiers" binding:"required,min=1,dive"`
+}
+
+// feeScheduleResponse is a version of a fee schedule along with its tiers
+type feeScheduleResponse struct {
+	db.FeeSchedule
+	Tiers []db.FeeTier `json:"tiers"`
+}
+
+// createFeeSchedule creates the next version of the fee schedule of a currency and transfer type
+func (server *Server) createFeeSchedule(ctx *gin.Context) {
+	var req CreateFeeScheduleRequest
+
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.CreateFeeScheduleTxParams{
+		CreateFeeScheduleParams: db.CreateFeeScheduleParams{
+			Currency:     req.Currency,
+			TransferType: req.TransferType,
+			EffectiveAt:  req.EffectiveAt,
+		},
+	}
+
+	if arg.EffectiveAt.IsZero() {
+		arg.EffectiveAt = time.Now()
+	}
+
+	minAmounts := make(map[int64]bool, len(req.Tiers))
+
+	for _, tier := range req.Tiers {
+		if minAmounts[tier.MinAmount] {
+			err := fmt.Errorf("two tiers start at the same min amount %d", tier.MinAmount)
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		minAmounts[tier.MinAmount] = true
+
+		arg.Tiers = append(arg.Tiers, db.CreateFeeTierParams{
+			MinAmount:   tier.MinAmount,
+			FlatFee:     tier.FlatFee,
+			BasisPoints: tier.BasisPoints,
+		})
+	}
+
+	result, err := server.store.CreateFeeScheduleTx(ctx, arg)
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				// another version of the same schedule was created at the same time
+				ctx.JSON(http.StatusConflict, errorResponse(err))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, feeScheduleResponse{
+		FeeSchedule: result.FeeSchedule,
+		Tiers:       result.Tiers,
+	})
+
+}
+
+// GetFeeScheduleRequest stores the get fee schedule requests
+type GetFeeScheduleRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getFeeSchedule returns a version of a fee schedule along with its tiers
+func (server *Server) getFeeSchedule(ctx *gin.Context) {
+	var req GetFeeScheduleRequest
+
+	err := ctx.ShouldBindUri(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	feeSchedule, err := server.store.GetFeeSchedule(ctx, req.ID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	tiers, err := server.store.ListFeeTiers(ctx, feeSchedule.ID)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, feeScheduleResponse{
+		FeeSchedule: feeSchedule,
+		Tiers:       tiers,
+	})
+
+}
+
+// ListFeeSchedulesRequest stores the list fee schedules requests
+type ListFeeSchedulesRequest struct {
+	Currency     string `form:"currency" binding:"required,currency"`
+	TransferType string `form:"transfer_type" binding:"required,oneof=transfer withdrawal"`
+	PageID       int32  `form:"page_id" binding:"required,min=1"`
+	PageSize     int32  `form:"page_size" binding:"required,min=1,max=50"`
+}
+
+// listFeeSchedules returns the versions of the fee schedule of a currency and transfer type, oldest first
+func (server *Server) listFeeSchedules(ctx *gin.Context) {
+	var req ListFeeSchedulesRequest
+
+	err := ctx.ShouldBindQuery(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	feeSchedules, err := server.store.ListFeeSchedules(ctx, db.ListFeeSchedulesParams{
+		Currency:     req.Currency,
+		TransferType: req.TransferType,
+		Limit:        req.PageSize,
+		Offset:       (req.PageID - 1) * req.PageSize,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, feeSchedules)
+
+}
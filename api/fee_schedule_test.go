@@ -0,0 +1,478 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateFeeScheduleAPI(t *testing.T) {
+	feeSchedule, tiers := randomFeeSchedule()
+
+	tiersBody := []gin.H{}
+	for _, tier := range tiers {
+		tiersBody = append(tiersBody, gin.H{
+			"min_amount":   tier.MinAmount,
+			"flat_fee":     tier.FlatFee,
+			"basis_points": tier.BasisPoints,
+		})
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"currency":      feeSchedule.Currency,
+				"transfer_type": feeSchedule.TransferType,
+				"effective_at":  feeSchedule.EffectiveAt,
+				"tiers":         tiersBody,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateFeeScheduleTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateFeeScheduleTxParams) (db.CreateFeeScheduleTxResult, error) {
+						require.Equal(t, feeSchedule.Currency, arg.Currency)
+						require.Equal(t, feeSchedule.TransferType, arg.TransferType)
+						require.WithinDuration(t, feeSchedule.EffectiveAt, arg.EffectiveAt, time.Second)
+						require.Len(t, arg.Tiers, len(tiers))
+
+						for i, tier := range arg.Tiers {
+							require.Equal(t, tiers[i].MinAmount, tier.MinAmount)
+							require.Equal(t, tiers[i].FlatFee, tier.FlatFee)
+							require.Equal(t, tiers[i].BasisPoints, tier.BasisPoints)
+						}
+
+						return db.CreateFeeScheduleTxResult{FeeSchedule: feeSchedule, Tiers: tiers}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchFeeSchedule(t, recorder.Body, feeSchedule, tiers)
+			},
+		},
+		{
+			name: "EffectiveNow",
+			body: gin.H{
+				"currency":      feeSchedule.Currency,
+				"transfer_type": feeSchedule.TransferType,
+				"tiers":         tiersBody,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateFeeScheduleTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateFeeScheduleTxParams) (db.CreateFeeScheduleTxResult, error) {
+						require.WithinDuration(t, time.Now(), arg.EffectiveAt, time.Second)
+						return db.CreateFeeScheduleTxResult{FeeSchedule: feeSchedule, Tiers: tiers}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "BankerForbidden",
+			body: gin.H{
+				"currency":      feeSchedule.Currency,
+				"transfer_type": feeSchedule.TransferType,
+				"tiers":         tiersBody,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "banker", utils.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateFeeScheduleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{
+				"currency":      feeSchedule.Currency,
+				"transfer_type": feeSchedule.TransferType,
+				"tiers":         tiersBody,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateFeeScheduleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidTransferType",
+			body: gin.H{
+				"currency":      feeSchedule.Currency,
+				"transfer_type": utils.EntryTypeReversal,
+				"tiers":         tiersBody,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateFeeScheduleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoTiers",
+			body: gin.H{
+				"currency":      feeSchedule.Currency,
+				"transfer_type": feeSchedule.TransferType,
+				"tiers":         []gin.H{},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateFeeScheduleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidBasisPoints",
+			body: gin.H{
+				"currency":      feeSchedule.Currency,
+				"transfer_type": feeSchedule.TransferType,
+				"tiers":         []gin.H{{"min_amount": 0, "basis_points": 10001}},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateFeeScheduleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "DuplicateTier",
+			body: gin.H{
+				"currency":      feeSchedule.Currency,
+				"transfer_type": feeSchedule.TransferType,
+				"tiers":         []gin.H{{"min_amount": 0, "flat_fee": 1}, {"min_amount": 0, "flat_fee": 2}},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateFeeScheduleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ConcurrentVersion",
+			body: gin.H{
+				"currency":      feeSchedule.Currency,
+				"transfer_type": feeSchedule.TransferType,
+				"tiers":         tiersBody,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateFeeScheduleTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateFeeScheduleTxResult{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"currency":      feeSchedule.Currency,
+				"transfer_type": feeSchedule.TransferType,
+				"tiers":         tiersBody,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateFeeScheduleTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateFeeScheduleTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/fee-schedules"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestGetFeeScheduleAPI(t *testing.T) {
+	feeSchedule, tiers := randomFeeSchedule()
+
+	testCases := []struct {
+		name          string
+		feeScheduleID int64
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:          "OK",
+			feeScheduleID: feeSchedule.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetFeeSchedule(gomock.Any(), gomock.Eq(feeSchedule.ID)).Times(1).Return(feeSchedule, nil)
+				store.EXPECT().ListFeeTiers(gomock.Any(), gomock.Eq(feeSchedule.ID)).Times(1).Return(tiers, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchFeeSchedule(t, recorder.Body, feeSchedule, tiers)
+			},
+		},
+		{
+			name:          "CustomerForbidden",
+			feeScheduleID: feeSchedule.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "customer", utils.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:          "NotFound",
+			feeScheduleID: feeSchedule.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetFeeSchedule(gomock.Any(), gomock.Eq(feeSchedule.ID)).Times(1).Return(db.FeeSchedule{}, sql.ErrNoRows)
+				store.EXPECT().ListFeeTiers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:          "InvalidID",
+			feeScheduleID: 0,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:          "ListFeeTiersError",
+			feeScheduleID: feeSchedule.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetFeeSchedule(gomock.Any(), gomock.Eq(feeSchedule.ID)).Times(1).Return(feeSchedule, nil)
+				store.EXPECT().ListFeeTiers(gomock.Any(), gomock.Eq(feeSchedule.ID)).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/fee-schedules/%d", tc.feeScheduleID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListFeeSchedulesAPI(t *testing.T) {
+	feeSchedule, _ := randomFeeSchedule()
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: fmt.Sprintf("currency=%s&transfer_type=%s&page_id=2&page_size=5", feeSchedule.Currency, feeSchedule.TransferType),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListFeeSchedulesParams{
+					Currency:     feeSchedule.Currency,
+					TransferType: feeSchedule.TransferType,
+					Limit:        5,
+					Offset:       5,
+				}
+				store.EXPECT().ListFeeSchedules(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.FeeSchedule{feeSchedule}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "MissingTransferType",
+			query: fmt.Sprintf("currency=%s&page_id=1&page_size=5", feeSchedule.Currency),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListFeeSchedules(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: fmt.Sprintf("currency=%s&transfer_type=%s&page_id=1&page_size=5", feeSchedule.Currency, feeSchedule.TransferType),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListFeeSchedules(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := "/fee-schedules?" + tc.query
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// randomFeeSchedule returns a tiered fee schedule, a flat fee below 1000 and a percentage above
+func randomFeeSchedule() (db.FeeSchedule, []db.FeeTier) {
+	feeSchedule := db.FeeSchedule{
+		ID:           utils.RandomInt(1, 1000),
+		Currency:     utils.USD,
+		TransferType: utils.EntryTypeTransfer,
+		Version:      int32(utils.RandomInt(1, 10)),
+		EffectiveAt:  time.Now().Add(time.Hour).Truncate(time.Second),
+		CreatedAt:    time.Now().Truncate(time.Second),
+	}
+
+	tiers := []db.FeeTier{
+		{ID: 1, FeeScheduleID: feeSchedule.ID, MinAmount: 0, FlatFee: utils.RandomInt(1, 10)},
+		{ID: 2, FeeScheduleID: feeSchedule.ID, MinAmount: 1000, BasisPoints: int32(utils.RandomInt(1, 100))},
+	}
+
+	return feeSchedule, tiers
+}
+
+func requireBodyMatchFeeSchedule(t *testing.T, body *bytes.Buffer, feeSchedule db.FeeSchedule, tiers []db.FeeTier) {
+	data, err := ioutil.ReadAll(body)
+	require.NoError(t, err)
+
+	var gotFeeSchedule feeScheduleResponse
+	err = json.Unmarshal(data, &gotFeeSchedule)
+	require.NoError(t, err)
+
+	require.Equal(t, feeSchedule.ID, gotFeeSchedule.ID)
+	require.Equal(t, feeSchedule.Version, gotFeeSchedule.Version)
+	require.WithinDuration(t, feeSchedule.EffectiveAt, gotFeeSchedule.EffectiveAt, time.Second)
+	require.Equal(t, tiers, gotFeeSchedule.Tiers)
+}
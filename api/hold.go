@@ -0,0 +1,266 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// error codes returned alongside business rule failures of a hold
+const (
+	errCodeHoldNotActive = "hold_not_active"
+)
+
+// PlaceHoldRequest stores the place hold requests
+// the funds of the account are reserved for the to account until the hold is captured, voided or expires
+type PlaceHoldRequest struct {
+	AccountID   int64  `json:"account_id" binding:"required,min=1"`
+	ToAccountID int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount      int64  `json:"amount" binding:"required,min=1"`
+	Currency    string `json:"currency" binding:"required,currency"`
+}
+
+func (server *Server) placeHold(ctx *gin.Context) {
+	var req PlaceHoldRequest
+
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.AccountID == req.ToAccountID {
+		err := errors.New("account and to account must differ")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.validAccount(ctx, req.AccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	// a logged in user can only hold funds of an account he/she owns
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if account.Owner != authPayload.Username {
+		err := errors.New("account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if !server.requireVerifiedEmail(ctx, authPayload.Username) {
+		return
+	}
+
+	// a hold is captured without knowing the exchange rate of that time, so it never converts currencies
+	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	result, err := server.store.PlaceHoldTx(ctx, db.PlaceHoldTxParams{
+		CreateHoldParams: db.CreateHoldParams{
+			AccountID:   req.AccountID,
+			ToAccountID: req.ToAccountID,
+			Amount:      req.Amount,
+			ExpiresAt:   time.Now().Add(server.config.HoldDuration),
+		},
+	})
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "foreign_key_violation":
+				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
+		}
+
+		switch {
+		case err == sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		case errors.Is(err, db.ErrInsufficientFunds):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeInsufficientFunds, err))
+		case errors.Is(err, db.ErrAccountNotActive):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeAccountNotActive, err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+
+}
+
+// getHold returns the hold of the uri
+// the hold was looked up and authorized by holdMiddleware
+func (server *Server) getHold(ctx *gin.Context) {
+	hold := ctx.MustGet(authorizationResourceKey).(db.Hold)
+
+	ctx.JSON(http.StatusOK, hold)
+
+}
+
+// CaptureHoldRequest stores the capture hold requests
+// the whole hold is captured unless a smaller amount is given, the remainder is then released
+type CaptureHoldRequest struct {
+	Amount int64 `json:"amount" binding:"omitempty,min=1"`
+}
+
+// captureHold transfers the held funds to the to account
+// the hold was looked up and authorized by holdMiddleware
+func (server *Server) captureHold(ctx *gin.Context) {
+	var req CaptureHoldRequest
+
+	// the body is optional
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hold := ctx.MustGet(authorizationResourceKey).(db.Hold)
+
+	if req.Amount == 0 {
+		req.Amount = hold.Amount
+	}
+
+	result, err := server.store.CaptureHoldTx(ctx, db.CaptureHoldTxParams{
+		HoldID: hold.ID,
+		Amount: req.Amount,
+	})
+
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		case errors.Is(err, db.ErrHoldNotActive):
+			ctx.JSON(http.StatusConflict, codedErrorResponse(errCodeHoldNotActive, err))
+		case errors.Is(err, db.ErrCaptureExceedsHold), errors.Is(err, db.ErrCashAccount),
+			errors.Is(err, db.ErrFeeAccount), errors.Is(err, db.ErrInterestAccount):
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		case errors.Is(err, db.ErrInsufficientFunds):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeInsufficientFunds, err))
+		case errors.Is(err, db.ErrAccountNotActive):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeAccountNotActive, err))
+		case errors.Is(err, db.ErrTransactionLimitExceeded):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeTransactionLimit, err))
+		case errors.Is(err, db.ErrDailyLimitExceeded):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeDailyLimit, err))
+		case errors.Is(err, db.ErrMonthlyLimitExceeded):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeMonthlyLimit, err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+
+}
+
+// voidHold releases the held funds without moving any money
+// the hold was looked up and authorized by holdMiddleware
+func (server *Server) voidHold(ctx *gin.Context) {
+	hold := ctx.MustGet(authorizationResourceKey).(db.Hold)
+
+	// the void only applies if the hold is still held and has not expired
+	hold, err := server.store.VoidHold(ctx, hold.ID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err := errors.New("only an active hold can be voided")
+			ctx.JSON(http.StatusConflict, codedErrorResponse(errCodeHoldNotActive, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, hold)
+
+}
+
+// ListHoldsRequest stores the list holds requests
+type ListHoldsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=1,max=50"`
+}
+
+// listHolds returns the holds placed on an account, in the order they were placed
+// the account was looked up and authorized by accountMiddleware
+func (server *Server) listHolds(ctx *gin.Context) {
+	var req ListHoldsRequest
+
+	err := ctx.ShouldBindQuery(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account := ctx.MustGet(authorizationResourceKey).(db.Account)
+
+	holds, err := server.store.ListHolds(ctx, db.ListHoldsParams{
+		AccountID: account.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, holds)
+
+}
+
+// holdMiddleware looks up the hold of the uri, which the owners of its accounts and the roles granted perm may access
+// when settle is true only the payee owns the hold, the payer cannot release the funds he/she owes before it expires
+func (server *Server) holdMiddleware(perm permission, settle bool) gin.HandlerFunc {
+	return ownershipMiddleware("hold", perm, func(ctx *gin.Context) (interface{}, []string, bool) {
+		id, valid := resourceID(ctx)
+		if !valid {
+			return nil, nil, false
+		}
+
+		hold, err := server.store.GetHold(ctx, id)
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return nil, nil, false
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return nil, nil, false
+		}
+
+		account, valid := server.existingAccount(ctx, hold.AccountID)
+		if !valid {
+			return nil, nil, false
+		}
+
+		toAccount, valid := server.existingAccount(ctx, hold.ToAccountID)
+		if !valid {
+			return nil, nil, false
+		}
+
+		if settle {
+			return hold, []string{toAccount.Owner}, true
+		}
+
+		return hold, []string{account.Owner, toAccount.Owner}, true
+	})
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	db "simple_bank/db/sqlc"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeaderKey = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+)
+
+// hashRequest returns a hex encoded sha256 hash of the bound request
+// hashing the bound struct rather than the raw body ignores whitespace and key order
+func hashRequest(req interface{}) (string, error) {
+	data, err := json.Marshal(req)
+
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// newIdempotencyKey returns the idempotency key to claim for the current request
+// it returns false when the key is invalid, an error response has then already been written
+func (server *Server) newIdempotencyKey(ctx *gin.Context, username, key string, req interface{}) (db.CreateIdempotencyKeyParams, bool) {
+	if len(key) > maxIdempotencyKeyLength {
+		err := fmt.Errorf("idempotency key must be at most %d characters", maxIdempotencyKeyLength)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.CreateIdempotencyKeyParams{}, false
+	}
+
+	requestHash, err := hashRequest(req)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.CreateIdempotencyKeyParams{}, false
+	}
+
+	return db.CreateIdempotencyKeyParams{
+		Username:    username,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().Add(server.config.IdempotencyKeyTTL),
+	}, true
+}
+
+// replayIdempotentResponse writes the stored response of the request that used the idempotency key
+// the key can only be replayed by the same request
+func (server *Server) replayIdempotentResponse(ctx *gin.Context, key db.CreateIdempotencyKeyParams) {
+	stored, err := server.store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username: key.Username,
+		Key:      key.Key,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if stored.RequestHash != key.RequestHash {
+		err := errors.New("idempotency key was already used with a different request")
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+		return
+	}
+
+	ctx.Data(int(stored.ResponseStatus), gin.MIMEJSON, stored.ResponseBody)
+}
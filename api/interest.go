@@ -0,0 +1,120 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	db "simple_bank/db/sqlc"
+	"simple_bank/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetInterestRateRequest stores the set interest rate requests
+// a rate of zero stops the account from earning interest
+type SetInterestRateRequest struct {
+	AnnualRateBps      int32  `json:"annual_rate_bps" binding:"min=0,max=10000"`
+	DayCountConvention string `json:"day_count_convention" binding:"required,day_count_convention"`
+}
+
+// setInterestRate sets the annual interest rate of the account of the uri, from the next accrued day on
+func (server *Server) setInterestRate(ctx *gin.Context) {
+	var uri GetAccountRequest
+
+	err := ctx.ShouldBindUri(&uri)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req SetInterestRateRequest
+
+	err = ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.existingAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	// the accounts of the bank itself never earn interest
+	if account.Owner == utils.CashAccountOwner || account.Owner == utils.FeeAccountOwner || account.Owner == utils.InterestAccountOwner {
+		err := errors.New("the accounts of the bank do not earn interest")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	rate, err := server.store.UpsertAccountInterestRate(ctx, db.UpsertAccountInterestRateParams{
+		AccountID:          account.ID,
+		AnnualRateBps:      req.AnnualRateBps,
+		DayCountConvention: req.DayCountConvention,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rate)
+
+}
+
+// getInterestRate returns the interest rate of the account of the uri
+// the account was looked up and authorized by accountMiddleware
+func (server *Server) getInterestRate(ctx *gin.Context) {
+	account := ctx.MustGet(authorizationResourceKey).(db.Account)
+
+	rate, err := server.store.GetAccountInterestRate(ctx, account.ID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rate)
+
+}
+
+// ListInterestAccrualsRequest stores the list interest accruals requests
+type ListInterestAccrualsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=1,max=50"`
+}
+
+// listInterestAccruals returns the daily interest accrued on the account of the uri, oldest day first
+// the account was looked up and authorized by accountMiddleware
+func (server *Server) listInterestAccruals(ctx *gin.Context) {
+	var req ListInterestAccrualsRequest
+
+	err := ctx.ShouldBindQuery(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account := ctx.MustGet(authorizationResourceKey).(db.Account)
+
+	accruals, err := server.store.ListInterestAccruals(ctx, db.ListInterestAccrualsParams{
+		AccountID: account.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accruals)
+
+}
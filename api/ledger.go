@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// verifyLedger checks that the account balances and transfers agree with the entries
+// mismatches are part of the report, so it is returned with 200 whether the books balance or not
+func (server *Server) verifyLedger(ctx *gin.Context) {
+	result, err := server.store.VerifyLedgerTx(ctx)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyLedgerAPI(t *testing.T) {
+	balanced := db.VerifyLedgerTxResult{
+		Balanced:           true,
+		AccountMismatches:  []db.ListAccountBalanceMismatchesRow{},
+		TransferMismatches: []db.ListUnbalancedTransfersRow{},
+		OrphanEntries:      []db.Entry{},
+	}
+
+	unbalanced := db.VerifyLedgerTxResult{
+		AccountMismatches: []db.ListAccountBalanceMismatchesRow{
+			{ID: 1, Owner: utils.RandomOwner(), Currency: utils.USD, Balance: 100, EntriesTotal: 90},
+		},
+		TransferMismatches: []db.ListUnbalancedTransfersRow{
+			{ID: 2, FromAccountID: 1, ToAccountID: 3, Amount: 10, ToAmount: 10, FromCurrency: utils.USD, ToCurrency: utils.USD, DebitEntries: 1},
+		},
+		OrphanEntries: []db.Entry{
+			{ID: 4, AccountID: 1, Amount: 10, TransactionType: utils.EntryTypeTransfer},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Balanced",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyLedgerTx(gomock.Any()).
+					Times(1).
+					Return(balanced, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchLedgerReport(t, recorder, balanced)
+			},
+		},
+		{
+			name: "Unbalanced",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyLedgerTx(gomock.Any()).
+					Times(1).
+					Return(unbalanced, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchLedgerReport(t, recorder, unbalanced)
+			},
+		},
+		{
+			name: "BankerForbidden",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "banker", utils.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyLedgerTx(gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyLedgerTx(gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "admin", utils.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyLedgerTx(gomock.Any()).
+					Times(1).
+					Return(db.VerifyLedgerTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := "/ledger/verify"
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func requireBodyMatchLedgerReport(t *testing.T, recorder *httptest.ResponseRecorder, report db.VerifyLedgerTxResult) {
+	data, err := ioutil.ReadAll(recorder.Body)
+	require.NoError(t, err)
+
+	var gotReport db.VerifyLedgerTxResult
+	err = json.Unmarshal(data, &gotReport)
+	require.NoError(t, err)
+	require.Equal(t, report, gotReport)
+}
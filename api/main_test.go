@@ -2,25 +2,60 @@ package api
 
 import (
 	"os"
+	mockdb "simple_bank/db/mock"
 	db "simple_bank/db/sqlc"
+	"simple_bank/mail"
 	"simple_bank/utils"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 )
 
+func newTestConfig() utils.Config {
+	return utils.Config{
+		TokenSymmeticKey:             utils.RandomString(32),
+		AccessTokenDuration:          time.Minute,
+		IdempotencyKeyTTL:            time.Minute,
+		FXRates:                      "USD/KES=130.5",
+		AppBaseURL:                   "http://localhost:8080",
+		VerifyEmailDuration:          time.Minute,
+		ResetPasswordDuration:        time.Minute,
+		ResetPasswordURL:             "http://localhost:3000/reset-password",
+		SchedulerInterval:            time.Minute,
+		ScheduledTransferMaxAttempts: 3,
+		ScheduledTransferRetryDelay:  time.Minute,
+		HoldDuration:                 time.Hour,
+	}
+}
+
 func newTestServer(t *testing.T, store db.Store) *Server {
 	// create a new config object
-	config := utils.Config{
-		TokenSymmeticKey:    utils.RandomString(32),
-		AccessTokenDuration: time.Minute,
-	}
+	config := newTestConfig()
 
 	server, err := NewServer(config, store)
 	require.NoError(t, err)
 
+	// keep emails in memory instead of sending them
+	server.mailer = mail.NewFakeSender()
+
+	// authMiddleware checks every token against the last password and role changes,
+	// by default the passwords and roles were never changed.
+	// stubs added by a test case before this one take precedence
+	if mockStore, ok := store.(*mockdb.MockStore); ok {
+		mockStore.EXPECT().
+			GetUserAuthChangeAt(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(db.GetUserAuthChangeAtRow{}, nil)
+
+		// every mutating call is written to the audit log
+		mockStore.EXPECT().
+			CreateAuditLog(gomock.Any(), gomock.Any()).
+			AnyTimes()
+	}
+
 	return server
 
 }
@@ -1,9 +1,11 @@
 package api
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
+	db "simple_bank/db/sqlc"
 	"simple_bank/token"
 	"strings"
 
@@ -11,12 +13,13 @@ import (
 )
 
 const (
-	authorizationHeaderKey  = "authorization"
-	authorizationBearerType = "bearer" // allows only for Bearer type
-	authorizationPayloadKey = "authorization_payload"
+	authorizationHeaderKey   = "authorization"
+	authorizationBearerType  = "bearer" // allows only for Bearer type
+	authorizationPayloadKey  = "authorization_payload"
+	authorizationResourceKey = "authorization_resource" // the resource of the route, loaded by ownershipMiddleware
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+func authMiddleware(tokenMaker token.Maker, store db.Store) gin.HandlerFunc {
 	// return an anonymous function - the authetication middleware func that we must implement
 	return func(ctx *gin.Context) {
 		// first extract the authorization header
@@ -67,7 +70,7 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 		accessToken := fields[1]
 
 		// verify our token
-		payload, err := tokenMaker.VerifyToken(accessToken)
+		payload, err := tokenMaker.VerifyToken(accessToken, token.TokenTypeAccessToken)
 
 		if err != nil {
 			// abort the request and send a json response to the client
@@ -78,6 +81,32 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 
 		}
 
+		// tokens issued before the last password change are no longer valid,
+		// nor the ones still carrying the role the user had before the last role change
+		authChangeAt, err := store.GetUserAuthChangeAt(ctx, payload.Username)
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if payload.IssuedAt.Before(authChangeAt.PasswordChangeAt) {
+			err := errors.New("token was issued before the last password change")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		// renewing the access token grants the new role
+		if payload.IssuedAt.Before(authChangeAt.RoleChangeAt) {
+			err := errors.New("token was issued before the last role change")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
 		// store the authorization payload to the context by passing a key:value pair
 		ctx.Set(authorizationPayloadKey, payload)
 
@@ -86,3 +115,75 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 
 	}
 }
+
+// permissionMiddleware only lets requests through when the role of the authenticated user
+// is granted every one of the given permissions
+// it must run after authMiddleware
+func permissionMiddleware(permissions ...permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		for _, perm := range permissions {
+			if !hasPermission(authPayload.Role, perm) {
+				err := fmt.Errorf("role %s is not allowed to %s", authPayload.Role, perm)
+
+				// abort the request and send a json response to the client
+				ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// ResourceRequest stores the id of the resource of a route
+type ResourceRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// resourceLoader looks up the resource of a route along with the users owning it
+// it writes the error response itself if the resource cannot be looked up
+type resourceLoader func(ctx *gin.Context) (resource interface{}, owners []string, valid bool)
+
+// ownershipMiddleware only lets requests through when the authenticated user owns the resource
+// of the route or his/her role is granted the permission.
+// The resource is stored in the context so the handler does not look it up again
+// it must run after authMiddleware
+func ownershipMiddleware(name string, perm permission, load resourceLoader) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resource, owners, valid := load(ctx)
+		if !valid {
+			ctx.Abort()
+			return
+		}
+
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		if !hasPermission(authPayload.Role, perm) && !isOwner(authPayload, owners...) {
+			err := fmt.Errorf("%s doesn't belong to the authenticated user", name)
+
+			// abort the request and send a json response to the client
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		ctx.Set(authorizationResourceKey, resource)
+
+		ctx.Next()
+	}
+}
+
+// resourceID binds the id of the resource of the route, writing an error response if it is invalid
+func resourceID(ctx *gin.Context) (int64, bool) {
+	var uri ResourceRequest
+
+	err := ctx.ShouldBindUri(&uri)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return 0, false
+	}
+
+	return uri.ID, true
+}
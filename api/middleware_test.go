@@ -1,14 +1,20 @@
 package api
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
 	"simple_bank/token"
+	"simple_bank/utils"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -18,9 +24,10 @@ func addAuthorization(
 	tokenMaker token.Maker,
 	authorizationType string,
 	username string,
+	role string,
 	duration time.Duration,
 ) {
-	token, payload, err := tokenMaker.CreateToken(username, duration)
+	token, payload, err := tokenMaker.CreateToken(username, role, uuid.New(), token.TokenTypeAccessToken, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
@@ -33,21 +40,102 @@ func TestAuthMiddleware(t *testing.T) {
 	testCases := []struct {
 		name          string
 		setUpAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		// testcase 1: Happy case
 		{
 			name: "OK",
 			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationBearerType, "user", time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "user", utils.CustomerRole, time.Minute)
 
 			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthChangeAt(gomock.Any(), gomock.Eq("user")).
+					Times(1).
+					Return(db.GetUserAuthChangeAtRow{PasswordChangeAt: time.Now().Add(-time.Hour), RoleChangeAt: time.Now().Add(-time.Hour)}, nil)
+			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 
 			},
 		},
 
+		// tokens issued before the password was changed are rejected
+		{
+			name: "PasswordChanged",
+			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "user", utils.CustomerRole, time.Minute)
+
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthChangeAt(gomock.Any(), gomock.Eq("user")).
+					Times(1).
+					Return(db.GetUserAuthChangeAtRow{PasswordChangeAt: time.Now().Add(time.Second)}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+			},
+		},
+
+		// tokens still carrying the role of the user before a promotion or demotion are rejected
+		{
+			name: "RoleChanged",
+			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "user", utils.BankerRole, time.Minute)
+
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthChangeAt(gomock.Any(), gomock.Eq("user")).
+					Times(1).
+					Return(db.GetUserAuthChangeAtRow{RoleChangeAt: time.Now().Add(time.Second)}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+			},
+		},
+
+		{
+			name: "UserNotFound",
+			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "user", utils.CustomerRole, time.Minute)
+
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthChangeAt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetUserAuthChangeAtRow{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+			},
+		},
+
+		{
+			name: "InternalError",
+			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "user", utils.CustomerRole, time.Minute)
+
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthChangeAt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetUserAuthChangeAtRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+
+			},
+		},
+
 		// testcase 2: No authorization header provided
 		{
 			name: "NoAuthorization",
@@ -55,6 +143,33 @@ func TestAuthMiddleware(t *testing.T) {
 				// empty
 
 			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthChangeAt(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+			},
+		},
+
+		// refresh tokens cannot be used as access tokens
+		{
+			name: "RefreshToken",
+			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				refreshToken, _, err := tokenMaker.CreateToken("user", utils.CustomerRole, uuid.Nil, token.TokenTypeRefreshToken, time.Minute)
+				require.NoError(t, err)
+
+				authorizationHeader := fmt.Sprintf("%s %s", authorizationBearerType, refreshToken)
+				request.Header.Set(authorizationHeaderKey, authorizationHeader)
+
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthChangeAt(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 
@@ -65,9 +180,14 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "UnSupportedAuthorization",
 			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, "unsupported", "user", time.Minute)
+				addAuthorization(t, request, tokenMaker, "unsupported", "user", utils.CustomerRole, time.Minute)
 
 			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthChangeAt(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 
@@ -78,9 +198,14 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "InvalidAuthorizationFormat",
 			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, "", "user", time.Minute)
+				addAuthorization(t, request, tokenMaker, "", "user", utils.CustomerRole, time.Minute)
 
 			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthChangeAt(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 
@@ -91,9 +216,14 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "ExpiredToken",
 			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationBearerType, "user", -time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, "user", utils.CustomerRole, -time.Minute)
 
 			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthChangeAt(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 
@@ -109,15 +239,21 @@ func TestAuthMiddleware(t *testing.T) {
 		t.Run(
 			tc.name,
 			func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				// the db store is only used to look up the last password and role changes
+				store := mockdb.NewMockStore(ctrl)
+				tc.buildStubs(store)
+
 				// create a new test server
-				// notice for parameter db.store we use nil since we don't need to access the db store
-				server := newTestServer(t, nil)
+				server := newTestServer(t, store)
 
 				// create a fake api route
 				authPath := "/auth"
 				server.router.GET(
 					authPath,
-					authMiddleware(server.tokenMaker),
+					authMiddleware(server.tokenMaker, server.store),
 					func(ctx *gin.Context) {
 						// send a status ok with and empty body
 						ctx.JSON(http.StatusOK, gin.H{})
@@ -145,3 +281,181 @@ func TestAuthMiddleware(t *testing.T) {
 
 	}
 }
+
+func TestPermissionMiddleware(t *testing.T) {
+	testCases := []struct {
+		name          string
+		role          string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Admin",
+			role: utils.AdminRole,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Banker",
+			role: utils.BankerRole,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "Customer",
+			role: utils.CustomerRole,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownRole",
+			role: "unknown",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+			// create a fake api route that only admins can use
+			permissionPath := "/permission"
+			server.router.GET(
+				permissionPath,
+				authMiddleware(server.tokenMaker, server.store),
+				permissionMiddleware(permissionManageUsers),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, permissionPath, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationBearerType, "user", tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestOwnershipMiddleware(t *testing.T) {
+	testCases := []struct {
+		name          string
+		username      string
+		role          string
+		perm          permission
+		valid         bool
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Owner",
+			username: "owner",
+			role:     utils.CustomerRole,
+			perm:     permissionReadAnyAccount,
+			valid:    true,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, `{"resource":"resource"}`, recorder.Body.String())
+			},
+		},
+		{
+			name:     "OtherOwner",
+			username: "other_owner",
+			role:     utils.CustomerRole,
+			perm:     permissionReadAnyAccount,
+			valid:    true,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "NotOwner",
+			username: "user",
+			role:     utils.CustomerRole,
+			perm:     permissionReadAnyAccount,
+			valid:    true,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "Permission",
+			username: "user",
+			role:     utils.BankerRole,
+			perm:     permissionReadAnyAccount,
+			valid:    true,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			// no role is granted permissionNone, not even admins
+			name:     "OwnerOnly",
+			username: "user",
+			role:     utils.AdminRole,
+			perm:     permissionNone,
+			valid:    true,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			// the loader wrote the error response
+			name:     "LoadFailed",
+			username: "owner",
+			role:     utils.CustomerRole,
+			perm:     permissionReadAnyAccount,
+			valid:    false,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+			// create a fake api route of a resource owned by two users
+			ownershipPath := "/ownership"
+			server.router.GET(
+				ownershipPath,
+				authMiddleware(server.tokenMaker, server.store),
+				ownershipMiddleware("resource", tc.perm, func(ctx *gin.Context) (interface{}, []string, bool) {
+					if !tc.valid {
+						ctx.JSON(http.StatusNotFound, gin.H{})
+						return nil, nil, false
+					}
+					return "resource", []string{"owner", "other_owner"}, true
+				}),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{"resource": ctx.MustGet(authorizationResourceKey)})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, ownershipPath, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationBearerType, tc.username, tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
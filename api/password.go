@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"simple_bank/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	resetPasswordSubject         = "Reset your Simple Bank password"
+	resetPasswordContentTemplate = `Hello %s,<br/>
+We received a request to reset your password.<br/>
+Please <a href="%s">click here</a> to choose a new one. The link expires in %s.<br/>
+If you did not ask for a new password you can ignore this email.<br/>`
+)
+
+// ChangePasswordRequest stores the change password requests
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required,min=6"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// changePassword updates the password of the authenticated user and ends all of his/her sessions
+// the access token of the request stops working too, so the user has to log in again
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req ChangePasswordRequest
+
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = utils.CheckPassword(req.OldPassword, user.HarshPassword)
+
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		Username:      user.Username,
+		HarshPassword: hashedPassword,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+
+}
+
+// ForgotPasswordRequest stores the forgot password requests
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// forgotPassword emails a single use reset code to the owner of the email address
+// the response is the same whether the address is registered or not,
+// so it can't be used to find out who has an account
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req ForgotPasswordRequest
+
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// the code is created and emailed after the response, so neither the response
+	// nor the time it takes tell a registered address from an unknown one
+	server.runTask(func() {
+		server.issueResetPassword(context.Background(), req.Email)
+	})
+
+	ctx.Status(http.StatusAccepted)
+
+}
+
+// issueResetPassword creates a new reset code for the owner of the email address, which
+// makes his/her earlier codes invalid, and emails it. Nothing is sent to an unknown address.
+// It runs after the response of forgotPassword, so the failures are only logged
+func (server *Server) issueResetPassword(ctx context.Context, email string) {
+	user, err := server.store.GetUserByEmail(ctx, email)
+
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("cannot get user of reset password email %s: %v", email, err)
+		}
+		return
+	}
+
+	secretCode, err := newSecretCode()
+
+	if err != nil {
+		log.Printf("cannot create reset password code of %s: %v", user.Username, err)
+		return
+	}
+
+	result, err := server.store.CreateResetPasswordTx(ctx, db.CreateResetPasswordTxParams{
+		CreateResetPasswordParams: db.CreateResetPasswordParams{
+			Username:   user.Username,
+			SecretCode: hashSecretCode(secretCode),
+			ExpiredAt:  time.Now().Add(server.config.ResetPasswordDuration),
+		},
+	})
+
+	if err != nil {
+		log.Printf("cannot create reset password of %s: %v", user.Username, err)
+		return
+	}
+
+	err = server.sendResetPasswordEmail(user, result.ResetPassword, secretCode)
+
+	if err != nil {
+		log.Printf("cannot send reset password email to %s: %v", user.Username, err)
+	}
+
+}
+
+// sendResetPasswordEmail emails the user a link to choose a new password
+// the link opens the reset password page of the frontend, which posts the new password with the code to resetPassword
+func (server *Server) sendResetPasswordEmail(user db.User, resetPassword db.ResetPassword, secretCode string) error {
+	resetURL := fmt.Sprintf(
+		"%s?reset_id=%d&secret_code=%s",
+		server.config.ResetPasswordURL,
+		resetPassword.ID,
+		secretCode,
+	)
+
+	content := fmt.Sprintf(resetPasswordContentTemplate, html.EscapeString(user.FullName), resetURL, server.config.ResetPasswordDuration)
+
+	return server.mailer.SendEmail(resetPasswordSubject, content, []string{user.Email})
+}
+
+// ResetPasswordRequest stores the reset password requests
+type ResetPasswordRequest struct {
+	ResetID     int64  `json:"reset_id" binding:"required,min=1"`
+	SecretCode  string `json:"secret_code" binding:"required,len=64,hexadecimal"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// resetPassword sets a new password with a code sent by forgotPassword
+// and ends all the sessions of the user
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req ResetPasswordRequest
+
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// the code can only be used once, before it expires
+	_, err = server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		ResetID:       req.ResetID,
+		SecretCode:    hashSecretCode(req.SecretCode),
+		HarshPassword: hashedPassword,
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err := errors.New("invalid, used or expired reset code")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+
+}
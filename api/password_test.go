@@ -0,0 +1,539 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/mail"
+	"simple_bank/token"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type eqChangePasswordTxParamsMatcher struct {
+	username string // the user whose password is changed
+	password string // the unhashed new password
+}
+
+func (e eqChangePasswordTxParamsMatcher) Matches(x interface{}) bool {
+	actualArg, ok := x.(db.ChangePasswordTxParams)
+	if !ok {
+		return false
+	}
+
+	if actualArg.Username != e.username {
+		return false
+	}
+
+	return utils.CheckPassword(e.password, actualArg.HarshPassword) == nil
+}
+
+func (e eqChangePasswordTxParamsMatcher) String() string {
+	return fmt.Sprintf("matches username %v and password %v", e.username, e.password)
+}
+
+func EqChangePasswordTxParams(username string, password string) gomock.Matcher {
+	return eqChangePasswordTxParamsMatcher{username, password}
+}
+
+type eqResetPasswordTxParamsMatcher struct {
+	resetID    int64
+	secretCode string // the unhashed secret code sent by email
+	password   string // the unhashed new password
+}
+
+func (e eqResetPasswordTxParamsMatcher) Matches(x interface{}) bool {
+	actualArg, ok := x.(db.ResetPasswordTxParams)
+	if !ok {
+		return false
+	}
+
+	// only the hash of the secret code may reach the db
+	if actualArg.ResetID != e.resetID || actualArg.SecretCode != hashSecretCode(e.secretCode) {
+		return false
+	}
+
+	return utils.CheckPassword(e.password, actualArg.HarshPassword) == nil
+}
+
+func (e eqResetPasswordTxParamsMatcher) String() string {
+	return fmt.Sprintf("matches reset id %v, secret code %v and password %v", e.resetID, e.secretCode, e.password)
+}
+
+func EqResetPasswordTxParams(resetID int64, secretCode string, password string) gomock.Matcher {
+	return eqResetPasswordTxParamsMatcher{resetID, secretCode, password}
+}
+
+func TestChangePasswordAPI(t *testing.T) {
+	user, password := randomUser(t)
+	newPassword := utils.RandomString(8)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setUpAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"old_password": password,
+				"new_password": newPassword,
+			},
+			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), EqChangePasswordTxParams(user.Username, newPassword)).
+					Times(1).
+					Return(db.ChangePasswordTxResult{User: user, BlockedSessions: 2}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body, user)
+			},
+		},
+		{
+			name: "WrongOldPassword",
+			body: gin.H{
+				"old_password": "wrong-password",
+				"new_password": newPassword,
+			},
+			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{
+				"old_password": password,
+				"new_password": newPassword,
+			},
+			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "TooShortPassword",
+			body: gin.H{
+				"old_password": password,
+				"new_password": "123",
+			},
+			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"old_password": password,
+				"new_password": newPassword,
+			},
+			setUpAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationBearerType, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ChangePasswordTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/users/password"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setUpAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestForgotPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		sendErr       error
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder, mailer *mail.FakeSender)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"email": user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+
+				store.EXPECT().
+					CreateResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateResetPasswordTxParams) (db.CreateResetPasswordTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Len(t, arg.SecretCode, 64)
+						require.True(t, arg.ExpiredAt.After(time.Now()))
+
+						return db.CreateResetPasswordTxResult{
+							ResetPassword: db.ResetPassword{ID: 1, Username: arg.Username, SecretCode: arg.SecretCode},
+						}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, mailer *mail.FakeSender) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+
+				emails := mailer.Emails()
+				require.Len(t, emails, 1)
+				require.Equal(t, []string{user.Email}, emails[0].To)
+				require.Contains(t, emails[0].Content, "http://localhost:3000/reset-password?reset_id=1&secret_code=")
+			},
+		},
+		{
+			// unknown addresses get the same response so accounts can't be discovered
+			name: "UnknownEmail",
+			body: gin.H{
+				"email": user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+
+				store.EXPECT().
+					CreateResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, mailer *mail.FakeSender) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, mailer.Emails())
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{
+				"email": "invalid-email",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, mailer *mail.FakeSender) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			// the failures of registered addresses get the same response too
+			name: "CreateResetPasswordError",
+			body: gin.H{
+				"email": user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+
+				store.EXPECT().
+					CreateResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateResetPasswordTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, mailer *mail.FakeSender) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, mailer.Emails())
+			},
+		},
+		{
+			name: "SendEmailError",
+			body: gin.H{
+				"email": user.Email,
+			},
+			sendErr: errors.New("smtp server unavailable"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+
+				store.EXPECT().
+					CreateResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateResetPasswordTxResult{ResetPassword: db.ResetPassword{ID: 1, Username: user.Username}}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, mailer *mail.FakeSender) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, mailer.Emails())
+			},
+		},
+		{
+			// the lookup runs after the response, so its failures can't change it either
+			name: "InternalError",
+			body: gin.H{
+				"email": user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+
+				store.EXPECT().
+					CreateResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, mailer *mail.FakeSender) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, mailer.Emails())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.mailer.(*mail.FakeSender).SetError(tc.sendErr)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/users/forgot_password"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			// the reset email is sent in the background
+			server.tasks.Wait()
+			tc.checkResponse(recorder, server.mailer.(*mail.FakeSender))
+		})
+	}
+}
+
+func TestSendResetPasswordEmailEscapesFullName(t *testing.T) {
+	user, _ := randomUser(t)
+	user.FullName = `<a href="http://phishing.example">Claim your prize</a>`
+
+	server := newTestServer(t, nil)
+
+	err := server.sendResetPasswordEmail(user, db.ResetPassword{ID: 1}, "secret")
+	require.NoError(t, err)
+
+	emails := server.mailer.(*mail.FakeSender).Emails()
+	require.Len(t, emails, 1)
+	require.NotContains(t, emails[0].Content, user.FullName)
+	require.Contains(t, emails[0].Content, "&lt;a href=&#34;http://phishing.example&#34;&gt;")
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	newPassword := utils.RandomString(8)
+
+	secretCode, err := newSecretCode()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"reset_id":     1,
+				"secret_code":  secretCode,
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), EqResetPasswordTxParams(1, secretCode, newPassword)).
+					Times(1).
+					Return(db.ResetPasswordTxResult{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidOrUsedCode",
+			body: gin.H{
+				"reset_id":     1,
+				"secret_code":  secretCode,
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ResetPasswordTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MalformedCode",
+			body: gin.H{
+				"reset_id":     1,
+				"secret_code":  "not-a-code",
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "TooShortPassword",
+			body: gin.H{
+				"reset_id":     1,
+				"secret_code":  secretCode,
+				"new_password": "123",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"reset_id":     1,
+				"secret_code":  secretCode,
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ResetPasswordTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/users/reset_password"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
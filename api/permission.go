@@ -0,0 +1,54 @@
+package api
+
+import (
+	"simple_bank/token"
+	"simple_bank/utils"
+)
+
+// permission names an action that reaches beyond the resources a user owns
+type permission string
+
+const (
+	permissionReadAnyAccount   permission = "accounts:read_any"
+	permissionManageAccounts   permission = "accounts:manage"
+	permissionManageUsers      permission = "users:manage"
+	permissionReverseTransfers permission = "transfers:reverse"
+	permissionHandleCash       permission = "cash:handle"
+	permissionManageFees       permission = "fees:manage"
+
+	// permissionNone is granted to no role, the routes requiring it are only open to the owners
+	permissionNone permission = ""
+)
+
+// rolePermissions maps every role to the permissions it is granted
+// customers are only granted access to the resources they own
+var rolePermissions = map[string][]permission{
+	utils.CustomerRole: {},
+	utils.BankerRole:   {permissionReadAnyAccount, permissionManageAccounts, permissionReverseTransfers, permissionHandleCash},
+	utils.AdminRole:    {permissionReadAnyAccount, permissionManageAccounts, permissionManageUsers, permissionReverseTransfers, permissionHandleCash, permissionManageFees},
+}
+
+// hasPermission returns true if the role is granted the permission
+func hasPermission(role string, perm permission) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// canReadAccount returns true if the authenticated user may read an account of the given owner
+func canReadAccount(authPayload *token.Payload, owner string) bool {
+	return authPayload.Username == owner || hasPermission(authPayload.Role, permissionReadAnyAccount)
+}
+
+// isOwner returns true if the authenticated user is one of the given owners, whatever his/her role
+func isOwner(authPayload *token.Payload, owners ...string) bool {
+	for _, owner := range owners {
+		if authPayload.Username == owner {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,347 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	db "simple_bank/db/sqlc"
+	"simple_bank/scheduler"
+	"simple_bank/token"
+	"simple_bank/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// error codes returned alongside business rule failures of a scheduled transfer
+const (
+	errCodeScheduledTransferNotActive = "scheduled_transfer_not_active"
+)
+
+// CreateScheduledTransferRequest stores the create scheduled transfer requests
+// a transfer without recurrence runs once at start_at,
+// a recurring transfer first runs at start_at or, if it is not given, at the first time of its recurrence
+type CreateScheduledTransferRequest struct {
+	FromAccountID int64     `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64     `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64     `json:"amount" binding:"required,min=1"`
+	Currency      string    `json:"currency" binding:"required,currency"`
+	Recurrence    string    `json:"recurrence"` // cron expression such as "0 9 1 * *", in UTC
+	StartAt       time.Time `json:"start_at"`
+}
+
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req CreateScheduledTransferRequest
+
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.FromAccountID == req.ToAccountID {
+		err := errors.New("from and to accounts must differ")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	nextRunAt, err := firstScheduledRun(req.Recurrence, req.StartAt)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	// a logged in user can only schedule transfers from an account he/she owns
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if fromAccount.Owner != authPayload.Username {
+		err := errors.New("from account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if !server.requireVerifiedEmail(ctx, authPayload.Username) {
+		return
+	}
+
+	// no exchange rate is known ahead of time, so scheduled transfers never convert currencies
+	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	scheduledTransfer, err := server.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Recurrence:    req.Recurrence,
+		NextRunAt:     nextRunAt,
+	})
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "foreign_key_violation":
+				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+
+}
+
+// firstScheduledRun returns when a new scheduled transfer runs for the first time
+func firstScheduledRun(recurrence string, startAt time.Time) (time.Time, error) {
+	if recurrence != "" {
+		schedule, err := scheduler.ParseCron(recurrence)
+
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if startAt.IsZero() {
+			return schedule.Next(time.Now())
+		}
+	}
+
+	if startAt.IsZero() {
+		return startAt, errors.New("start_at is required for a transfer without recurrence")
+	}
+
+	if !startAt.After(time.Now()) {
+		return startAt, errors.New("start_at must be in the future")
+	}
+
+	return startAt, nil
+}
+
+// ListScheduledTransfersRequest stores the list scheduled transfers requests
+type ListScheduledTransfersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=1,max=50"`
+}
+
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req ListScheduledTransfersRequest
+
+	err := ctx.ShouldBindQuery(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// a logged in user can only list the transfers he/she scheduled
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	scheduledTransfers, err := server.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfers)
+
+}
+
+// getScheduledTransfer returns the scheduled transfer of the uri
+// the scheduled transfer was looked up and authorized by scheduledTransferMiddleware
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	scheduledTransfer := ctx.MustGet(authorizationResourceKey).(db.ScheduledTransfer)
+
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+
+}
+
+// UpdateScheduledTransferRequest stores the update scheduled transfer requests
+// only the given fields are updated, an empty recurrence turns the transfer into a one-off.
+// A new recurrence without next_run_at first runs at the first time of the recurrence
+type UpdateScheduledTransferRequest struct {
+	Amount     *int64     `json:"amount" binding:"omitempty,min=1"`
+	Recurrence *string    `json:"recurrence"`
+	NextRunAt  *time.Time `json:"next_run_at"`
+}
+
+// updateScheduledTransfer changes the amount or the runs of the scheduled transfer of the uri
+// the scheduled transfer was looked up and authorized by scheduledTransferMiddleware
+func (server *Server) updateScheduledTransfer(ctx *gin.Context) {
+	var req UpdateScheduledTransferRequest
+
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.Amount == nil && req.Recurrence == nil && req.NextRunAt == nil {
+		err := errors.New("at least one of amount, recurrence or next_run_at must be given")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduledTransfer := ctx.MustGet(authorizationResourceKey).(db.ScheduledTransfer)
+
+	arg := db.UpdateScheduledTransferParams{
+		ID: scheduledTransfer.ID,
+	}
+
+	if req.Amount != nil {
+		arg.Amount = sql.NullInt64{Int64: *req.Amount, Valid: true}
+	}
+
+	if req.Recurrence != nil {
+		arg.Recurrence = sql.NullString{String: *req.Recurrence, Valid: true}
+
+		if *req.Recurrence != "" && req.NextRunAt == nil {
+			nextRunAt, err := firstScheduledRun(*req.Recurrence, time.Time{})
+
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, errorResponse(err))
+				return
+			}
+
+			arg.NextRunAt = sql.NullTime{Time: nextRunAt, Valid: true}
+		}
+	}
+
+	if req.NextRunAt != nil {
+		recurrence := ""
+		if req.Recurrence != nil {
+			recurrence = *req.Recurrence
+		}
+
+		// only the recurrence given in the request is checked here,
+		// the stored one was already checked when it was saved
+		nextRunAt, err := firstScheduledRun(recurrence, *req.NextRunAt)
+
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		arg.NextRunAt = sql.NullTime{Time: nextRunAt, Valid: true}
+	}
+
+	if scheduledTransfer.Status != utils.ScheduledTransferStatusActive {
+		err := errors.New("only an active scheduled transfer can be updated")
+		ctx.JSON(http.StatusConflict, codedErrorResponse(errCodeScheduledTransferNotActive, err))
+		return
+	}
+
+	// the update only applies if the transfer is still active
+	scheduledTransfer, err = server.store.UpdateScheduledTransfer(ctx, arg)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err := errors.New("scheduled transfer is no longer active")
+			ctx.JSON(http.StatusConflict, codedErrorResponse(errCodeScheduledTransferNotActive, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+
+}
+
+// cancelScheduledTransfer stops all future runs of a scheduled transfer
+// its past runs are kept
+// the scheduled transfer was looked up and authorized by scheduledTransferMiddleware
+func (server *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	scheduledTransfer := ctx.MustGet(authorizationResourceKey).(db.ScheduledTransfer)
+
+	// the cancel only applies if the transfer is still active
+	_, err := server.store.CancelScheduledTransfer(ctx, scheduledTransfer.ID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err := errors.New("only an active scheduled transfer can be cancelled")
+			ctx.JSON(http.StatusConflict, codedErrorResponse(errCodeScheduledTransferNotActive, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+
+}
+
+// ListScheduledTransferRunsRequest stores the list scheduled transfer runs requests
+type ListScheduledTransferRunsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=1,max=50"`
+}
+
+// listScheduledTransferRuns returns the runs of the scheduled transfer of the uri
+// the scheduled transfer was looked up and authorized by scheduledTransferMiddleware
+func (server *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	var req ListScheduledTransferRunsRequest
+
+	err := ctx.ShouldBindQuery(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduledTransfer := ctx.MustGet(authorizationResourceKey).(db.ScheduledTransfer)
+
+	runs, err := server.store.ListScheduledTransferRuns(ctx, db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		Limit:               req.PageSize,
+		Offset:              (req.PageID - 1) * req.PageSize,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, runs)
+
+}
+
+// scheduledTransferMiddleware looks up the scheduled transfer of the uri,
+// which its owner and the roles granted perm may access
+func (server *Server) scheduledTransferMiddleware(perm permission) gin.HandlerFunc {
+	return ownershipMiddleware("scheduled transfer", perm, func(ctx *gin.Context) (interface{}, []string, bool) {
+		id, valid := resourceID(ctx)
+		if !valid {
+			return nil, nil, false
+		}
+
+		scheduledTransfer, err := server.store.GetScheduledTransfer(ctx, id)
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return nil, nil, false
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return nil, nil, false
+		}
+
+		return scheduledTransfer, []string{scheduledTransfer.Owner}, true
+	})
+}
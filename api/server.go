@@ -1,10 +1,15 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	db "simple_bank/db/sqlc"
+	"simple_bank/fx"
+	"simple_bank/mail"
+	"simple_bank/scheduler"
 	"simple_bank/token"
 	"simple_bank/utils"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -13,18 +18,34 @@ import (
 
 // Server serves all http requests for our banking service
 type Server struct {
-	config     utils.Config
-	store      db.Store    // Allows has to interact with our db
-	tokenMaker token.Maker // allows us to interact with our token maker interface
-	router     *gin.Engine // Helps send http requests to the correct handler for processing
+	config       utils.Config
+	store        db.Store             // Allows has to interact with our db
+	tokenMaker   token.Maker          // allows us to interact with our token maker interface
+	rateProvider fx.RateProvider      // looks up exchange rates for cross currency transfers
+	mailer       mail.EmailSender     // sends the verification and password reset emails
+	scheduler    *scheduler.Scheduler // executes the scheduled transfers in the background
+	router       *gin.Engine          // Helps send http requests to the correct handler for processing
+	tasks        sync.WaitGroup       // the background work started by requests, like sending the reset emails
 
 }
 
 // NewServer creates a new http server instance and setups all api routing for our service
 // on that server
 func NewServer(config utils.Config, store db.Store) (*Server, error) {
-	// create a new token maker object using the paseto token maker
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmeticKey)
+	// the keys committed in app.env are public, they must be replaced outside development
+	err := checkDevTokenKeys(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// a key that expires at once would let every retry move the money again
+	if config.IdempotencyKeyTTL <= 0 {
+		return nil, fmt.Errorf("idempotency key ttl must be positive, got %s", config.IdempotencyKeyTTL)
+	}
+
+	// create a new token maker object using the configured token maker
+	tokenMaker, err := newTokenMaker(config)
 
 	if err != nil {
 		// return a nil object and and error description
@@ -32,11 +53,38 @@ func NewServer(config utils.Config, store db.Store) (*Server, error) {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	// create a new rate provider from the configured exchange rates
+	rateProvider, err := fx.NewStaticRateProvider(config.FXRates)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate provider: %w", err)
+	}
+
+	// create a new mailer sending through the configured SMTP server
+	mailer := mail.NewSMTPSender(
+		config.SMTPHost,
+		config.SMTPPort,
+		config.SMTPUsername,
+		config.SMTPPassword,
+		config.EmailSenderName,
+		config.EmailSenderAddress,
+	)
+
+	// create a new scheduler executing the due scheduled transfers
+	transferScheduler, err := scheduler.NewScheduler(store, config)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot create scheduler: %w", err)
+	}
+
 	// Creates a new server
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:       config,
+		store:        store,
+		tokenMaker:   tokenMaker,
+		rateProvider: rateProvider,
+		mailer:       mailer,
+		scheduler:    transferScheduler,
 	}
 
 	// register the currencyValidator() with gin
@@ -44,6 +92,9 @@ func NewServer(config utils.Config, store db.Store) (*Server, error) {
 	// then convert the validator to validator.Validate
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("role", validRole)
+		v.RegisterValidation("day_count_convention", validDayCountConvention)
+		v.RegisterValidation("limit_tier", validLimitTier)
 	}
 
 	server.setUpRouter()
@@ -56,6 +107,9 @@ func (server *Server) setUpRouter() {
 	// Creates a new router
 	router := gin.Default()
 
+	// every mutating call is written to the audit log, including the ones rejected by a middleware
+	router.Use(server.auditMiddleware())
+
 	// Add routes to the router
 	// create a POST route
 	// pass a path /accounts in our case
@@ -63,29 +117,221 @@ func (server *Server) setUpRouter() {
 	// all other middle params are middleware
 	router.POST("/users", server.createUser)
 	router.POST("/users/login", server.loginUser)
+	router.POST("/users/logout", server.logoutUser)
+	router.GET("/users/verify_email", server.verifyEmail)
+	router.POST("/users/forgot_password", server.forgotPassword)
+	router.POST("/users/reset_password", server.resetPassword)
 	router.POST("/tokens/renew_access", server.renewAccessToken)
+	router.GET("/tokens/public_keys", server.listPublicKeys)
 
 	// below routes need to be authorized
 	// therefore we add our  middleware here
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.store))
 
 	// now instead of router, we use the authRoutes
+	// the routes of a resource first check that the authenticated user owns it,
+	// unless his/her role is granted the permission to access the resources of others
 
+	authRoutes.POST("/users/password", server.changePassword)
+	authRoutes.PATCH("/users/:username", userMiddleware(permissionManageUsers), server.updateUser)
 	authRoutes.POST("/accounts", server.createAcccount)
-	authRoutes.GET("/accounts/:id", server.getAccount)
+	authRoutes.GET("/accounts/:id", server.accountMiddleware(permissionReadAnyAccount), server.getAccount)
 	authRoutes.GET("/accounts", server.listAccounts)
+	authRoutes.GET("/accounts/:id/entries", server.accountMiddleware(permissionReadAnyAccount), server.listEntries)
+	authRoutes.GET("/accounts/:id/balance", server.accountMiddleware(permissionReadAnyAccount), server.getAccountBalance)
+	authRoutes.POST("/accounts/:id/freeze", server.accountMiddleware(permissionManageAccounts), server.freezeAccount)
+	authRoutes.POST("/accounts/:id/unfreeze", permissionMiddleware(permissionManageAccounts), server.accountMiddleware(permissionManageAccounts), server.unfreezeAccount)
+	authRoutes.POST("/accounts/:id/close", server.accountMiddleware(permissionManageAccounts), server.closeAccount)
 	authRoutes.POST("/transfers", server.createTransfer)
+	authRoutes.GET("/transfers", server.listTransfers)
+	authRoutes.GET("/transfers/:id", server.transferMiddleware(permissionReadAnyAccount), server.getTransfer)
+	authRoutes.GET("/sessions", server.listSessions)
+	authRoutes.DELETE("/sessions/:id", server.revokeSession)
+	authRoutes.POST("/sessions/logout_others", server.logoutOtherSessions)
+	authRoutes.POST("/scheduled-transfers", server.createScheduledTransfer)
+	authRoutes.GET("/scheduled-transfers", server.listScheduledTransfers)
+	authRoutes.GET("/scheduled-transfers/:id", server.scheduledTransferMiddleware(permissionReadAnyAccount), server.getScheduledTransfer)
+	authRoutes.PATCH("/scheduled-transfers/:id", server.scheduledTransferMiddleware(permissionNone), server.updateScheduledTransfer)
+	authRoutes.DELETE("/scheduled-transfers/:id", server.scheduledTransferMiddleware(permissionNone), server.cancelScheduledTransfer)
+	authRoutes.GET("/scheduled-transfers/:id/runs", server.scheduledTransferMiddleware(permissionReadAnyAccount), server.listScheduledTransferRuns)
+	authRoutes.GET("/accounts/:id/holds", server.accountMiddleware(permissionReadAnyAccount), server.listHolds)
+	authRoutes.POST("/holds", server.placeHold)
+	authRoutes.GET("/holds/:id", server.holdMiddleware(permissionReadAnyAccount, false), server.getHold)
+	authRoutes.POST("/holds/:id/capture", server.holdMiddleware(permissionManageAccounts, true), server.captureHold)
+	authRoutes.POST("/holds/:id/void", server.holdMiddleware(permissionManageAccounts, true), server.voidHold)
+	authRoutes.GET("/accounts/:id/interest-rate", server.accountMiddleware(permissionReadAnyAccount), server.getInterestRate)
+	authRoutes.GET("/accounts/:id/interest-accruals", server.accountMiddleware(permissionReadAnyAccount), server.listInterestAccruals)
+	authRoutes.GET("/limits", server.listTransferLimits)
+
+	// below routes can only be used by bankers and admins, every route checks its own permission
+	bankerRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.store))
+
+	bankerRoutes.POST("/transfers/:id/reverse", permissionMiddleware(permissionReverseTransfers), server.reverseTransfer)
+	bankerRoutes.POST("/accounts/:id/deposits", permissionMiddleware(permissionHandleCash), server.createDeposit)
+	bankerRoutes.POST("/accounts/:id/withdrawals", permissionMiddleware(permissionHandleCash), server.createWithdrawal)
+	bankerRoutes.PUT("/accounts/:id/interest-rate", permissionMiddleware(permissionManageAccounts), server.setInterestRate)
+	bankerRoutes.PUT("/accounts/:id/overdraft-limit", permissionMiddleware(permissionManageAccounts), server.accountMiddleware(permissionManageAccounts), server.setOverdraftLimit)
+	bankerRoutes.POST("/fee-schedules", permissionMiddleware(permissionManageFees), server.createFeeSchedule)
+	bankerRoutes.GET("/fee-schedules", permissionMiddleware(permissionManageFees), server.listFeeSchedules)
+	bankerRoutes.GET("/fee-schedules/:id", permissionMiddleware(permissionManageFees), server.getFeeSchedule)
+
+	// below routes can only be used by admins
+	adminRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.store), permissionMiddleware(permissionManageUsers))
+
+	adminRoutes.GET("/users/:username", server.getUser)
+	adminRoutes.PATCH("/users/:username/role", server.updateUserRole)
+	adminRoutes.PATCH("/users/:username/limit-tier", server.updateUserLimitTier)
+	adminRoutes.PUT("/users/:username/limits", server.setTransferLimit)
+	adminRoutes.GET("/ledger/verify", server.verifyLedger)
+	adminRoutes.GET("/audit-logs", server.listAuditLogs)
 
 	// Set this router object to server.router
 	server.router = router
 
 }
 
+// Different token makers that can be picked through the TOKEN_MAKER config
+const (
+	tokenMakerPaseto       = "paseto"        // PASETO v2.local, symmetric key
+	tokenMakerPasetoPublic = "paseto_public" // PASETO v2.public, Ed25519 key pair
+	tokenMakerJWT          = "jwt"           // HS256 JWT, symmetric key
+	tokenMakerJWTEdDSA     = "jwt_eddsa"     // EdDSA JWT, Ed25519 key pair
+)
+
+// newTokenMaker creates the token maker selected by the config
+// PASETO v2.local is used when no maker is configured
+func newTokenMaker(config utils.Config) (token.Maker, error) {
+	switch config.TokenMaker {
+	case "", tokenMakerPaseto:
+		keys, activeID, err := tokenKeys(config)
+
+		if err != nil {
+			return nil, err
+		}
+
+		keyring, err := token.NewKeyring(keys, activeID, config.TokenKeyGracePeriod)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return token.NewPasetoKeyringMaker(keyring)
+	case tokenMakerJWT:
+		return token.NewJWTMaker(config.TokenSymmeticKey)
+	case tokenMakerPasetoPublic, tokenMakerJWTEdDSA:
+		privateKey, err := token.ParseEd25519Seed(config.TokenPrivateKey)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if config.TokenMaker == tokenMakerPasetoPublic {
+			return token.NewPasetoPublicMaker(privateKey)
+		}
+		return token.NewJWTEdDSAMaker(privateKey)
+	default:
+		return nil, fmt.Errorf("unsupported token maker %s", config.TokenMaker)
+	}
+}
+
+// checkDevTokenKeys refuses the placeholder keys of app.env outside the development environment
+// only the keys used by the configured token maker are checked
+func checkDevTokenKeys(config utils.Config) error {
+	if config.Environment == utils.DevelopmentEnvironment {
+		return nil
+	}
+
+	switch config.TokenMaker {
+	case tokenMakerPasetoPublic, tokenMakerJWTEdDSA:
+		if config.TokenPrivateKey == utils.DevTokenPrivateKey {
+			return fmt.Errorf("the development token private key cannot be used in the %q environment", config.Environment)
+		}
+	case tokenMakerJWT:
+		if config.TokenSymmeticKey == utils.DevTokenSymmetricKey {
+			return fmt.Errorf("the development token symmetric key cannot be used in the %q environment", config.Environment)
+		}
+	default:
+		keys, _, err := tokenKeys(config)
+
+		if err != nil {
+			// the invalid keys are reported by newTokenMaker
+			return nil
+		}
+
+		for _, key := range keys {
+			if string(key.Key) == utils.DevTokenSymmetricKey {
+				return fmt.Errorf("the development token symmetric key cannot be used in the %q environment", config.Environment)
+			}
+		}
+	}
+
+	return nil
+}
+
+// tokenKeys returns the symmetric keys of the config and the id of the one to sign with
+// the single TOKEN_SYMMETRIC_KEY is used when no key ids are configured
+func tokenKeys(config utils.Config) ([]token.SymmetricKey, string, error) {
+	if config.TokenSymmetricKeys == "" {
+		return []token.SymmetricKey{{ID: token.DefaultKeyID, Key: []byte(config.TokenSymmeticKey)}}, token.DefaultKeyID, nil
+	}
+
+	keys, err := token.ParseSymmetricKeys(config.TokenSymmetricKeys)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return keys, config.TokenActiveKeyID, nil
+}
+
+// UpdateTokenKeys replaces the symmetric token keys with the ones of the config
+// so keys can be rotated without restarting the server
+func (server *Server) UpdateTokenKeys(config utils.Config) error {
+	maker, ok := server.tokenMaker.(*token.Pasetomaker)
+
+	if !ok {
+		return fmt.Errorf("token maker %s does not support key rotation", server.config.TokenMaker)
+	}
+
+	err := checkDevTokenKeys(config)
+
+	if err != nil {
+		return err
+	}
+
+	keys, activeID, err := tokenKeys(config)
+
+	if err != nil {
+		return err
+	}
+
+	return maker.Keyring().Update(keys, activeID, config.TokenKeyGracePeriod)
+}
+
+// runTask runs a task in the background, the request that starts it does not wait for its result
+func (server *Server) runTask(task func()) {
+	server.tasks.Add(1)
+
+	go func() {
+		defer server.tasks.Done()
+		task()
+	}()
+}
+
 // Start runs an http request on a specific address
+// and executes the scheduled transfers in the background
 func (server *Server) Start(address string) error {
+	go server.scheduler.Run(context.Background())
+
 	return server.router.Run(address)
 }
 
 func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
+
+// codedErrorResponse adds a machine readable code to the error response
+// so clients can tell business rule failures apart without parsing the message
+func codedErrorResponse(code string, err error) gin.H {
+	return gin.H{"error": err.Error(), "code": code}
+}
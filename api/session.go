@@ -0,0 +1,221 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// sessionResponse leaves out the refresh token so it can never leak through the session list
+type sessionResponse struct {
+	ID        uuid.UUID `json:"id"`
+	UserAgent string    `json:"user_agent"`
+	ClientIp  string    `json:"client_ip"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newSessionResponse(session db.Session) sessionResponse {
+	return sessionResponse{
+		ID:        session.ID,
+		UserAgent: session.UserAgent,
+		ClientIp:  session.ClientIp,
+		ExpiresAt: session.ExpiresAt,
+		CreatedAt: session.CreatedAt,
+	}
+}
+
+func (server *Server) listSessions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	sessions, err := server.store.ListSessions(ctx, authPayload.Username)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]sessionResponse, len(sessions))
+
+	for i, session := range sessions {
+		rsp[i] = newSessionResponse(session)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+
+}
+
+// RevokeSessionRequest stores the revoke session requests
+type RevokeSessionRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+func (server *Server) revokeSession(ctx *gin.Context) {
+	var req RevokeSessionRequest
+
+	err := ctx.ShouldBindUri(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// the session is only blocked if it belongs to the authenticated user
+	// so a session of another user is reported as not found
+	session, err := server.store.BlockSession(ctx, db.BlockSessionParams{
+		ID:       uuid.MustParse(req.ID),
+		Username: authPayload.Username,
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// also block the rotated sessions the revoked one descends from,
+	// access tokens already issued for it stay valid until they expire
+	_, err = server.store.BlockSessionFamily(ctx, session.FamilyID)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+
+}
+
+// LogoutUserRequest stores the logout requests
+// the refresh token identifies the session to end
+type LogoutUserRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// logoutUser blocks the session of the given refresh token so it can no longer renew access tokens
+// access tokens already issued stay valid until they expire
+func (server *Server) logoutUser(ctx *gin.Context) {
+	var req LogoutUserRequest
+
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken, token.TokenTypeRefreshToken)
+
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	session, err := server.store.BlockSession(ctx, db.BlockSessionParams{
+		ID:       refreshPayload.ID,
+		Username: refreshPayload.Username,
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// the refresh token may be an older one of a rotated family,
+	// so block the whole family to make sure the login is ended
+	_, err = server.store.BlockSessionFamily(ctx, session.FamilyID)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+
+}
+
+type logoutOtherSessionsResponse struct {
+	RevokedSessions int64 `json:"revoked_sessions"`
+}
+
+// logoutOtherSessions blocks every session of the authenticated user except the family
+// of the given refresh token, logging the user out of all other devices
+// access tokens already issued to the other devices stay valid until they expire
+func (server *Server) logoutOtherSessions(ctx *gin.Context) {
+	var req LogoutUserRequest
+
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken, token.TokenTypeRefreshToken)
+
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if refreshPayload.Username != authPayload.Username {
+		err := errors.New("refresh token doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	// the session of the refresh token must still be active, otherwise there is
+	// no family to keep and nothing would be blocked without telling the user
+	session, err := server.store.GetSession(ctx, refreshPayload.ID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if session.Username != authPayload.Username {
+		err := errors.New("incorrect session user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if session.IsBlocked {
+		err := errors.New("blocked session")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	revoked, err := server.store.BlockOtherSessions(ctx, db.BlockOtherSessionsParams{
+		Username: authPayload.Username,
+		FamilyID: session.FamilyID,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, logoutOtherSessionsResponse{RevokedSessions: revoked})
+
+}
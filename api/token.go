@@ -2,11 +2,15 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type renewAccessTokenRequest struct {
@@ -14,8 +18,11 @@ type renewAccessTokenRequest struct {
 }
 
 type renewAccessTokenResponse struct {
-	AccessToken          string    `json:"access_token"`
-	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+	SessionID             uuid.UUID `json:"session_id"`
+	AccessToken           string    `json:"access_token"`
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
 }
 
 func (server *Server) renewAccessToken(ctx *gin.Context) {
@@ -25,7 +32,7 @@ func (server *Server) renewAccessToken(ctx *gin.Context) {
 		return
 	}
 
-	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
+	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken, token.TokenTypeRefreshToken)
 	if err != nil {
 		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
 		return
@@ -41,12 +48,6 @@ func (server *Server) renewAccessToken(ctx *gin.Context) {
 		return
 	}
 
-	if session.IsBlocked {
-		err := fmt.Errorf("blocked session")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
-		return
-	}
-
 	if session.Username != refreshPayload.Username {
 		err := fmt.Errorf("incorrect session user")
 		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
@@ -59,14 +60,51 @@ func (server *Server) renewAccessToken(ctx *gin.Context) {
 		return
 	}
 
+	// a refresh token is only ever exchanged once, so seeing a retired one again
+	// means it was stolen: block every session descending from the same login
+	if session.RotatedAt.Valid {
+		server.blockReusedSession(ctx, session)
+		return
+	}
+
+	if session.IsBlocked {
+		err := fmt.Errorf("blocked session")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
 	if time.Now().After(session.ExpiresAt) {
 		err := fmt.Errorf("expired session")
 		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
 		return
 	}
 
+	// look the user up again so a role change applies from the next renewal
+	user, err := server.store.GetUser(ctx, session.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// every renewal also rotates the refresh token
+	refreshToken, newRefreshPayload, err := server.tokenMaker.CreateToken(
+		user.Username,
+		user.Role,
+		uuid.Nil,
+		token.TokenTypeRefreshToken,
+		server.config.RefreshTokenDuration,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// the new access token belongs to the rotated session
 	accessToken, accessPayload, err := server.tokenMaker.CreateToken(
-		refreshPayload.Username,
+		user.Username,
+		user.Role,
+		newRefreshPayload.ID,
+		token.TokenTypeAccessToken,
 		server.config.AccessTokenDuration,
 	)
 	if err != nil {
@@ -74,9 +112,63 @@ func (server *Server) renewAccessToken(ctx *gin.Context) {
 		return
 	}
 
+	result, err := server.store.RotateSessionTx(ctx, db.RotateSessionTxParams{
+		SessionID: session.ID,
+		NewSession: db.CreateSessionParams{
+			ID:           newRefreshPayload.ID,
+			Username:     user.Username,
+			RefreshToken: refreshToken,
+			UserAgent:    ctx.Request.UserAgent(),
+			ClientIp:     ctx.ClientIP(),
+			IsBlocked:    false,
+			ExpiresAt:    newRefreshPayload.ExpiredAt,
+		},
+	})
+	if err != nil {
+		// a concurrent renewal with the same refresh token got there first
+		if errors.Is(err, db.ErrSessionReused) {
+			server.blockReusedSession(ctx, session)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
 	rsp := renewAccessTokenResponse{
-		AccessToken:          accessToken,
-		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+		SessionID:             result.NewSession.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: newRefreshPayload.ExpiredAt,
 	}
 	ctx.JSON(http.StatusOK, rsp)
 }
+
+// blockReusedSession blocks the whole family of a session whose refresh token was presented twice
+func (server *Server) blockReusedSession(ctx *gin.Context, session db.Session) {
+	_, err := server.store.BlockSessionFamily(ctx, session.FamilyID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = fmt.Errorf("refresh token reuse detected, session revoked")
+	ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+}
+
+type listPublicKeysResponse struct {
+	Keys []token.PublicKey `json:"keys"`
+}
+
+// listPublicKeys exports the public keys other services need to verify our access tokens
+// it is only available when tokens are signed with a key pair
+func (server *Server) listPublicKeys(ctx *gin.Context) {
+	maker, ok := server.tokenMaker.(token.PublicKeyMaker)
+	if !ok {
+		err := errors.New("tokens are not signed with a public key")
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listPublicKeysResponse{Keys: maker.PublicKeys()})
+}
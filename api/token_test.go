@@ -0,0 +1,505 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		tokenType     token.TokenType
+		updateSession func(session *db.Session)
+		buildStubs    func(store *mockdb.MockStore, session db.Session)
+		checkResponse func(recoder *httptest.ResponseRecorder, session db.Session)
+	}{
+		{
+			name:          "OK",
+			tokenType:     token.TokenTypeRefreshToken,
+			updateSession: func(session *db.Session) {},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.RotateSessionTxParams) (db.RotateSessionTxResult, error) {
+						require.Equal(t, session.ID, arg.SessionID)
+						require.Equal(t, user.Username, arg.NewSession.Username)
+
+						newSession := session
+						newSession.ID = arg.NewSession.ID
+						newSession.RefreshToken = arg.NewSession.RefreshToken
+
+						return db.RotateSessionTxResult{OldSession: session, NewSession: newSession}, nil
+					})
+
+				store.EXPECT().
+					BlockSessionFamily(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, session db.Session) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp renewAccessTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+				require.NotEqual(t, session.RefreshToken, rsp.RefreshToken)
+				require.NotEqual(t, session.ID, rsp.SessionID)
+			},
+		},
+		{
+			name:      "RotatedRefreshToken",
+			tokenType: token.TokenTypeRefreshToken,
+			updateSession: func(session *db.Session) {
+				session.RotatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+
+				store.EXPECT().
+					BlockSessionFamily(gomock.Any(), gomock.Eq(session.FamilyID)).
+					Times(1).
+					Return(int64(2), nil)
+
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, session db.Session) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:          "ConcurrentRotation",
+			tokenType:     token.TokenTypeRefreshToken,
+			updateSession: func(session *db.Session) {},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RotateSessionTxResult{}, db.ErrSessionReused)
+
+				store.EXPECT().
+					BlockSessionFamily(gomock.Any(), gomock.Eq(session.FamilyID)).
+					Times(1).
+					Return(int64(2), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, session db.Session) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "BlockedSession",
+			tokenType: token.TokenTypeRefreshToken,
+			updateSession: func(session *db.Session) {
+				session.IsBlocked = true
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, session db.Session) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "MismatchedRefreshToken",
+			tokenType: token.TokenTypeRefreshToken,
+			updateSession: func(session *db.Session) {
+				session.RefreshToken = "other"
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, session db.Session) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:          "AccessToken",
+			tokenType:     token.TokenTypeAccessToken,
+			updateSession: func(session *db.Session) {},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, session db.Session) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:          "SessionNotFound",
+			tokenType:     token.TokenTypeRefreshToken,
+			updateSession: func(session *db.Session) {},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, session db.Session) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:          "InternalError",
+			tokenType:     token.TokenTypeRefreshToken,
+			updateSession: func(session *db.Session) {},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RotateSessionTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, session db.Session) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			// the token must be signed by the server's own token maker
+			refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, uuid.Nil, tc.tokenType, time.Hour)
+			require.NoError(t, err)
+
+			session := randomSession(user.Username)
+			session.ID = refreshPayload.ID
+			session.FamilyID = refreshPayload.ID
+			session.RefreshToken = refreshToken
+			tc.updateSession(&session)
+
+			tc.buildStubs(store, session)
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder, session)
+		})
+	}
+}
+
+func TestListPublicKeysAPI(t *testing.T) {
+	privateKey := hex.EncodeToString([]byte(utils.RandomString(32)))
+
+	testCases := []struct {
+		name          string
+		tokenMaker    string
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "PasetoPublic",
+			tokenMaker: tokenMakerPasetoPublic,
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchPublicKey(t, recorder.Body, token.AlgorithmPasetoV2Public)
+			},
+		},
+		{
+			name:       "JWTEdDSA",
+			tokenMaker: tokenMakerJWTEdDSA,
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchPublicKey(t, recorder.Body, token.AlgorithmEdDSA)
+			},
+		},
+		{
+			name:       "SymmetricPaseto",
+			tokenMaker: tokenMakerPaseto,
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "SymmetricJWT",
+			tokenMaker: tokenMakerJWT,
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			config := newTestConfig()
+			config.TokenMaker = tc.tokenMaker
+			config.TokenPrivateKey = privateKey
+
+			server, err := NewServer(config, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/tokens/public_keys", nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestNewTokenMaker(t *testing.T) {
+	config := newTestConfig()
+
+	config.TokenMaker = "unknown"
+	_, err := newTokenMaker(config)
+	require.Error(t, err)
+
+	// key pair makers need a valid private key
+	config.TokenMaker = tokenMakerPasetoPublic
+	config.TokenPrivateKey = "invalid"
+	_, err = newTokenMaker(config)
+	require.Error(t, err)
+
+	// an empty config falls back to the symmetric paseto maker
+	config.TokenMaker = ""
+	maker, err := newTokenMaker(config)
+	require.NoError(t, err)
+	require.IsType(t, &token.Pasetomaker{}, maker)
+}
+
+func TestNewServerDevTokenKeys(t *testing.T) {
+	testCases := []struct {
+		name        string
+		tokenMaker  string
+		updateKeys  func(config *utils.Config)
+		environment string
+		wantErr     bool
+	}{
+		{
+			name:       "PrivateKeyPasetoPublic",
+			tokenMaker: tokenMakerPasetoPublic,
+			updateKeys: func(config *utils.Config) {
+				config.TokenPrivateKey = utils.DevTokenPrivateKey
+			},
+			wantErr: true,
+		},
+		{
+			name:       "PrivateKeyJWTEdDSA",
+			tokenMaker: tokenMakerJWTEdDSA,
+			updateKeys: func(config *utils.Config) {
+				config.TokenPrivateKey = utils.DevTokenPrivateKey
+			},
+			wantErr: true,
+		},
+		{
+			// the symmetric maker never signs with the private key
+			name:       "PrivateKeyPaseto",
+			tokenMaker: tokenMakerPaseto,
+			updateKeys: func(config *utils.Config) {
+				config.TokenPrivateKey = utils.DevTokenPrivateKey
+			},
+		},
+		{
+			name:       "SymmetricKeyPaseto",
+			tokenMaker: tokenMakerPaseto,
+			updateKeys: func(config *utils.Config) {
+				config.TokenSymmeticKey = utils.DevTokenSymmetricKey
+			},
+			wantErr: true,
+		},
+		{
+			name:       "SymmetricKeyKeyring",
+			tokenMaker: tokenMakerPaseto,
+			updateKeys: func(config *utils.Config) {
+				config.TokenSymmetricKeys = "k1=" + utils.RandomString(32) + ",k2=" + utils.DevTokenSymmetricKey
+				config.TokenActiveKeyID = "k1"
+			},
+			wantErr: true,
+		},
+		{
+			name:       "SymmetricKeyJWT",
+			tokenMaker: tokenMakerJWT,
+			updateKeys: func(config *utils.Config) {
+				config.TokenSymmeticKey = utils.DevTokenSymmetricKey
+			},
+			wantErr: true,
+		},
+		{
+			// the key pair makers never sign with the symmetric key
+			name:       "SymmetricKeyPasetoPublic",
+			tokenMaker: tokenMakerPasetoPublic,
+			updateKeys: func(config *utils.Config) {
+				config.TokenSymmeticKey = utils.DevTokenSymmetricKey
+			},
+		},
+		{
+			name:        "Development",
+			tokenMaker:  tokenMakerPasetoPublic,
+			environment: utils.DevelopmentEnvironment,
+			updateKeys: func(config *utils.Config) {
+				config.TokenSymmeticKey = utils.DevTokenSymmetricKey
+				config.TokenPrivateKey = utils.DevTokenPrivateKey
+			},
+		},
+	}
+
+	privateKey := hex.EncodeToString([]byte(utils.RandomString(32)))
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			config := newTestConfig()
+			config.TokenMaker = tc.tokenMaker
+			config.TokenPrivateKey = privateKey
+			config.Environment = tc.environment
+			tc.updateKeys(&config)
+
+			_, err := NewServer(config, nil)
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func requireBodyMatchPublicKey(t *testing.T, body *bytes.Buffer, algorithm string) {
+	var rsp listPublicKeysResponse
+	err := json.Unmarshal(body.Bytes(), &rsp)
+	require.NoError(t, err)
+
+	require.Len(t, rsp.Keys, 1)
+	require.Equal(t, algorithm, rsp.Keys[0].Algorithm)
+	require.Len(t, rsp.Keys[0].Key, ed25519.PublicKeySize)
+}
+
+func TestUpdateTokenKeys(t *testing.T) {
+	key1 := utils.RandomString(32)
+	key2 := utils.RandomString(32)
+
+	config := newTestConfig()
+	config.TokenSymmetricKeys = "k1=" + key1
+	config.TokenActiveKeyID = "k1"
+	config.TokenKeyGracePeriod = time.Hour
+
+	server, err := NewServer(config, nil)
+	require.NoError(t, err)
+
+	token1, _, err := server.tokenMaker.CreateToken(utils.RandomOwner(), utils.CustomerRole, uuid.New(), token.TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+
+	// rotate to k2 and retire k1
+	config.TokenSymmetricKeys = "k2=" + key2 + ",k1=" + key1 + "@" + time.Now().Format(time.RFC3339)
+	config.TokenActiveKeyID = "k2"
+
+	err = server.UpdateTokenKeys(config)
+	require.NoError(t, err)
+
+	// tokens of k1 are still accepted during the grace period
+	_, err = server.tokenMaker.VerifyToken(token1, token.TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// an invalid config leaves the keys untouched
+	config.TokenActiveKeyID = "k3"
+	err = server.UpdateTokenKeys(config)
+	require.Error(t, err)
+
+	_, err = server.tokenMaker.VerifyToken(token1, token.TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// the placeholder key of app.env can't be rotated in either
+	config.TokenSymmetricKeys = "k2=" + utils.DevTokenSymmetricKey + ",k1=" + key1
+	config.TokenActiveKeyID = "k2"
+	err = server.UpdateTokenKeys(config)
+	require.Error(t, err)
+
+	_, err = server.tokenMaker.VerifyToken(token1, token.TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// makers without a keyring cannot rotate their keys
+	config = newTestConfig()
+	config.TokenMaker = tokenMakerJWT
+
+	server, err = NewServer(config, nil)
+	require.NoError(t, err)
+
+	err = server.UpdateTokenKeys(config)
+	require.Error(t, err)
+}
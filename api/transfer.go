@@ -4,19 +4,46 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	db "simple_bank/db/sqlc"
+	"simple_bank/fx"
 	"simple_bank/token"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// error codes returned alongside business rule failures of a transfer
+const (
+	errCodeInsufficientFunds       = "insufficient_funds"
+	errCodeTransferNotReversible   = "transfer_not_reversible"
+	errCodeTransferAlreadyReversed = "transfer_already_reversed"
+	errCodeFeeExceedsMax           = "fee_exceeds_max"
+	errCodeTransactionLimit        = "transaction_limit_exceeded"
+	errCodeDailyLimit              = "daily_limit_exceeded"
+	errCodeMonthlyLimit            = "monthly_limit_exceeded"
+	errCodeCashAccount             = "cash_account"
+	errCodeFeeAccount              = "fee_account"
+	errCodeInterestAccount         = "interest_account"
+)
+
+// directions a transfer can be filtered by, relative to the authenticated user
+const (
+	directionAll      = "all"
+	directionIncoming = "incoming"
+	directionOutgoing = "outgoing"
+)
+
 // TransferRequest stores the create account requests
 type TransferRequest struct {
 	FromAccountID int64  `json:"from_account_id" binding:"required"`
 	ToAccountID   int64  `json:"to_account_id" binding:"required"`
 	Amount        int64  `json:"amount" binding:"required,min=1"`
 	Currency      string `json:"currency" binding:"required,currency"`
+	// MaxFee is the highest fee the sender accepts, in the currency of the from account
+	// the transfer is rejected rather than charged more, any fee is accepted when it is omitted
+	MaxFee *int64 `json:"max_fee" binding:"omitempty,min=0"`
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
@@ -46,7 +73,13 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		return
 	}
 
-	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
+	// money can only be sent once the user has proven he/she owns the email address
+	if !server.requireVerifiedEmail(ctx, authPayload.Username) {
+		return
+	}
+
+	// the receiving account may hold a different currency, the amount is then converted
+	toAccount, valid := server.existingAccount(ctx, req.ToAccountID)
 	if !valid {
 		return
 	}
@@ -57,9 +90,99 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		Amount:        req.Amount,
 	}
 
-	result, err := server.store.TransferTx(ctx, arg)
+	if req.MaxFee != nil {
+		arg.MaxFee = sql.NullInt64{Int64: *req.MaxFee, Valid: true}
+	}
+
+	if toAccount.Currency != req.Currency {
+		rate, err := server.rateProvider.GetRate(ctx, req.Currency, toAccount.Currency)
+
+		if err != nil {
+			if errors.Is(err, fx.ErrRateNotFound) {
+				err := fmt.Errorf("account: %d currency mismatch: %w", req.ToAccountID, err)
+				ctx.JSON(http.StatusBadRequest, errorResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		arg.ToAmount, err = rate.Convert(req.Amount)
+
+		if err != nil {
+			err := fmt.Errorf("cannot convert from %s to %s: %w", req.Currency, toAccount.Currency, err)
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		// the stored rate is the one the amount was converted with
+		arg.ExchangeRate = rate.String()
+	}
+
+	// an optional idempotency key lets clients safely retry the same transfer
+	idempotencyKey := ctx.GetHeader(idempotencyKeyHeaderKey)
+
+	var result db.TransferTxResult
+
+	if idempotencyKey == "" {
+		result, err = server.store.TransferTx(ctx, arg)
+	} else {
+		key, valid := server.newIdempotencyKey(ctx, authPayload.Username, idempotencyKey, req)
+		if !valid {
+			return
+		}
+
+		// the key is claimed and the result stored in the transaction of the transfer,
+		// so a failed transfer leaves the key free and a committed one can always be replayed
+		result, err = server.store.IdempotentTransferTx(ctx, db.IdempotentTransferTxParams{
+			TransferTxParams: arg,
+			IdempotencyKey:   key,
+			ResponseStatus:   http.StatusOK,
+		})
+
+		if errors.Is(err, db.ErrIdempotencyKeyUsed) {
+			server.replayIdempotentResponse(ctx, key)
+			return
+		}
+	}
 
 	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeInsufficientFunds, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrAccountNotActive) {
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeAccountNotActive, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrFeeExceedsMax) {
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeFeeExceedsMax, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrTransactionLimitExceeded) {
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeTransactionLimit, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrDailyLimitExceeded) {
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeDailyLimit, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrMonthlyLimitExceeded) {
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeMonthlyLimit, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrCurrencyMismatch) || errors.Is(err, db.ErrCashAccount) || errors.Is(err, db.ErrFeeAccount) ||
+			errors.Is(err, db.ErrInterestAccount) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 
 		return
@@ -71,6 +194,27 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 
 // validAccount validates the from and to accounts currencies
 func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, valid := server.existingAccount(ctx, accountID)
+	if !valid {
+		return account, false
+	}
+
+	if account.Currency != currency {
+
+		err := fmt.Errorf("account: %d currency mismatch: %v vs %v", accountID, account.Currency, currency)
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+
+		return account, false
+
+	}
+
+	return account, true
+
+}
+
+// existingAccount queries the account from the db, writing an error response if it cannot be found
+func (server *Server) existingAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
 	// query the acccount from the db
 	account, err := server.store.GetAccount(ctx, accountID)
 
@@ -83,16 +227,198 @@ func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency s
 		return account, false
 	}
 
-	if account.Currency != currency {
+	return account, true
 
-		err := fmt.Errorf("account: %d currency mismatch: %v vs %v", accountID, account.Currency, currency)
+}
+
+// ListTransfersRequest stores the list transfers requests
+// all filters are optional, a zero value means the filter is not applied
+type ListTransfersRequest struct {
+	PageID         int32     `form:"page_id" binding:"required,min=1"`
+	PageSize       int32     `form:"page_size" binding:"required,min=1,max=50"`
+	Direction      string    `form:"direction" binding:"omitempty,oneof=all incoming outgoing"`
+	CounterpartyID int64     `form:"counterparty_id" binding:"omitempty,min=1"`
+	MinAmount      int64     `form:"min_amount" binding:"omitempty,min=1"`
+	MaxAmount      int64     `form:"max_amount" binding:"omitempty,min=1"`
+	From           time.Time `form:"from"`
+	To             time.Time `form:"to"`
+}
 
+func (server *Server) listTransfers(ctx *gin.Context) {
+	var req ListTransfersRequest
+
+	err := ctx.ShouldBindQuery(&req)
+
+	if err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
 
-		return account, false
+	if req.MinAmount > 0 && req.MaxAmount > 0 && req.MinAmount > req.MaxAmount {
+		err := errors.New("min amount must not be greater than max amount")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
 
+	if !req.From.IsZero() && !req.To.IsZero() && req.From.After(req.To) {
+		err := errors.New("from date must be before to date")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
 	}
 
-	return account, true
+	if req.Direction == "" {
+		req.Direction = directionAll
+	}
+
+	// a logged in user can only list transfers touching accounts he/she owns
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.ListUserTransfersParams{
+		Direction:      req.Direction,
+		Owner:          authPayload.Username,
+		CounterpartyID: sql.NullInt64{Int64: req.CounterpartyID, Valid: req.CounterpartyID > 0},
+		MinAmount:      sql.NullInt64{Int64: req.MinAmount, Valid: req.MinAmount > 0},
+		MaxAmount:      sql.NullInt64{Int64: req.MaxAmount, Valid: req.MaxAmount > 0},
+		FromTime:       sql.NullTime{Time: req.From, Valid: !req.From.IsZero()},
+		ToTime:         sql.NullTime{Time: req.To, Valid: !req.To.IsZero()},
+		Limit:          req.PageSize,
+		Offset:         (req.PageID - 1) * req.PageSize,
+	}
+
+	transfers, err := server.store.ListUserTransfers(ctx, arg)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfers)
+
+}
+
+// GetTransferRequest stores the get transfer requests
+type GetTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getTransfer returns the transfer of the uri
+// the transfer was looked up and authorized by transferMiddleware
+func (server *Server) getTransfer(ctx *gin.Context) {
+	transfer := ctx.MustGet(authorizationResourceKey).(db.Transfer)
+
+	ctx.JSON(http.StatusOK, transfer)
+
+}
+
+// transferMiddleware looks up the transfer of the uri, which the owners of both its accounts
+// and the roles granted perm may access
+func (server *Server) transferMiddleware(perm permission) gin.HandlerFunc {
+	return ownershipMiddleware("transfer", perm, func(ctx *gin.Context) (interface{}, []string, bool) {
+		id, valid := resourceID(ctx)
+		if !valid {
+			return nil, nil, false
+		}
+
+		transfer, err := server.store.GetTransfer(ctx, id)
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return nil, nil, false
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return nil, nil, false
+		}
+
+		fromAccount, valid := server.existingAccount(ctx, transfer.FromAccountID)
+		if !valid {
+			return nil, nil, false
+		}
+
+		toAccount, valid := server.existingAccount(ctx, transfer.ToAccountID)
+		if !valid {
+			return nil, nil, false
+		}
+
+		return transfer, []string{fromAccount.Owner, toAccount.Owner}, true
+	})
+}
+
+// ReverseTransferRequest stores the reverse transfer requests
+// the whole amount left to reverse is refunded unless a smaller amount is given
+type ReverseTransferRequest struct {
+	Amount int64 `json:"amount" binding:"omitempty,min=1"`
+}
+
+// reverseTransfer refunds the sender of a mistaken transfer with a reversal transfer
+// the amount is in the currency of the sender
+func (server *Server) reverseTransfer(ctx *gin.Context) {
+	var uri GetTransferRequest
+
+	err := ctx.ShouldBindUri(&uri)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req ReverseTransferRequest
+
+	// the body is optional
+	err = ctx.ShouldBindJSON(&req)
+
+	if err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, err := server.store.GetTransfer(ctx, uri.ID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if req.Amount == 0 {
+		req.Amount = transfer.Amount - transfer.ReversedAmount
+	}
+
+	result, err := server.store.ReverseTransferTx(ctx, db.ReverseTransferTxParams{
+		TransferID: transfer.ID,
+		Amount:     req.Amount,
+	})
+
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		case errors.Is(err, db.ErrTransferNotReversible):
+			ctx.JSON(http.StatusConflict, codedErrorResponse(errCodeTransferNotReversible, err))
+		case errors.Is(err, db.ErrTransferAlreadyReversed):
+			ctx.JSON(http.StatusConflict, codedErrorResponse(errCodeTransferAlreadyReversed, err))
+		case errors.Is(err, db.ErrInvalidReversalAmount):
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		case errors.Is(err, db.ErrInsufficientFunds):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeInsufficientFunds, err))
+		case errors.Is(err, db.ErrAccountNotActive):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeAccountNotActive, err))
+		// e.g. an interest capitalization, which only the interest account may pay
+		case errors.Is(err, db.ErrInterestAccount):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeInterestAccount, err))
+		case errors.Is(err, db.ErrCashAccount):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeCashAccount, err))
+		case errors.Is(err, db.ErrFeeAccount):
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(errCodeFeeAccount, err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
 
 }
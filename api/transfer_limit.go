@@ -0,0 +1,221 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// transferLimitResponse is the transfer limits of a user in a currency along with what is left of them
+// the daily remaining is never above the monthly remaining, since both limits apply to the next transfer
+type transferLimitResponse struct {
+	Currency            string `json:"currency"`
+	LimitTier           string `json:"limit_tier"`
+	PerTransactionLimit int64  `json:"per_transaction_limit"`
+	DailyLimit          int64  `json:"daily_limit"`
+	MonthlyLimit        int64  `json:"monthly_limit"`
+	DailyTotal          int64  `json:"daily_total"`
+	MonthlyTotal        int64  `json:"monthly_total"`
+	DailyRemaining      int64  `json:"daily_remaining"`
+	MonthlyRemaining    int64  `json:"monthly_remaining"`
+}
+
+// newTransferLimitResponse converts the transfer limits of a user in a currency to transferLimitResponse
+func newTransferLimitResponse(limit db.ListTransferLimitsRow) transferLimitResponse {
+	monthlyRemaining := limit.MonthlyLimit - limit.MonthlyTotal
+	if monthlyRemaining < 0 {
+		monthlyRemaining = 0
+	}
+
+	dailyRemaining := limit.DailyLimit - limit.DailyTotal
+	if dailyRemaining < 0 {
+		dailyRemaining = 0
+	}
+	if dailyRemaining > monthlyRemaining {
+		dailyRemaining = monthlyRemaining
+	}
+
+	return transferLimitResponse{
+		Currency:            limit.Currency,
+		LimitTier:           limit.LimitTier,
+		PerTransactionLimit: limit.PerTransactionLimit,
+		DailyLimit:          limit.DailyLimit,
+		MonthlyLimit:        limit.MonthlyLimit,
+		DailyTotal:          limit.DailyTotal,
+		MonthlyTotal:        limit.MonthlyTotal,
+		DailyRemaining:      dailyRemaining,
+		MonthlyRemaining:    monthlyRemaining,
+	}
+
+}
+
+// ListTransferLimitsRequest stores the list transfer limits requests
+// the limits of the authenticated user are listed unless another user is given
+type ListTransferLimitsRequest struct {
+	Username string `form:"username" binding:"omitempty,alphanum"`
+}
+
+// listTransferLimits returns the transfer limits of a user in every currency and what is left of them
+func (server *Server) listTransferLimits(ctx *gin.Context) {
+	var req ListTransferLimitsRequest
+
+	err := ctx.ShouldBindQuery(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	username := req.Username
+
+	if username == "" {
+		username = authPayload.Username
+	}
+
+	if !canReadAccount(authPayload, username) {
+		err := errors.New("cannot read the transfer limits of another user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	limits, err := server.store.ListTransferLimits(ctx, username)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]transferLimitResponse, 0, len(limits))
+
+	for _, limit := range limits {
+		rsp = append(rsp, newTransferLimitResponse(limit))
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+
+}
+
+// SetTransferLimitRequest stores the set transfer limit requests
+// a limit that is not set falls back to the limit of the user tier
+type SetTransferLimitRequest struct {
+	Currency            string `json:"currency" binding:"required,currency"`
+	PerTransactionLimit *int64 `json:"per_transaction_limit" binding:"omitempty,min=1"`
+	DailyLimit          *int64 `json:"daily_limit" binding:"omitempty,min=1"`
+	MonthlyLimit        *int64 `json:"monthly_limit" binding:"omitempty,min=1"`
+}
+
+// setTransferLimit overrides the transfer limits of the user of the uri in a currency
+func (server *Server) setTransferLimit(ctx *gin.Context) {
+	var uri GetUserRequest
+
+	err := ctx.ShouldBindUri(&uri)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req SetTransferLimitRequest
+
+	err = ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.UpsertUserTransferLimitParams{
+		Username: uri.Username,
+		Currency: req.Currency,
+	}
+
+	if req.PerTransactionLimit != nil {
+		arg.PerTransactionLimit = sql.NullInt64{Int64: *req.PerTransactionLimit, Valid: true}
+	}
+
+	if req.DailyLimit != nil {
+		arg.DailyLimit = sql.NullInt64{Int64: *req.DailyLimit, Valid: true}
+	}
+
+	if req.MonthlyLimit != nil {
+		arg.MonthlyLimit = sql.NullInt64{Int64: *req.MonthlyLimit, Valid: true}
+	}
+
+	_, err = server.store.UpsertUserTransferLimit(ctx, arg)
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "foreign_key_violation":
+				// the user does not exist
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	limit, err := server.store.GetTransferLimit(ctx, db.GetTransferLimitParams{
+		Username: uri.Username,
+		Currency: req.Currency,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTransferLimitResponse(db.ListTransferLimitsRow(limit)))
+
+}
+
+// UpdateUserLimitTierRequest stores the update user limit tier requests
+type UpdateUserLimitTierRequest struct {
+	LimitTier string `json:"limit_tier" binding:"required,limit_tier"`
+}
+
+// updateUserLimitTier moves the user of the uri to another transfer limit tier
+func (server *Server) updateUserLimitTier(ctx *gin.Context) {
+	var uri GetUserRequest
+
+	err := ctx.ShouldBindUri(&uri)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req UpdateUserLimitTierRequest
+
+	err = ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.UpdateUserLimitTier(ctx, db.UpdateUserLimitTierParams{
+		Username:  uri.Username,
+		LimitTier: req.LimitTier,
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+
+}
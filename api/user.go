@@ -2,8 +2,11 @@ package api
 
 import (
 	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	db "simple_bank/db/sqlc"
+	"simple_bank/token"
 	"simple_bank/utils"
 	"time"
 
@@ -25,6 +28,9 @@ type userResponse struct {
 	Username         string    `json:"username"`
 	FullName         string    `json:"full_name"`
 	Email            string    `json:"email"`
+	Role             string    `json:"role"`
+	IsEmailVerified  bool      `json:"is_email_verified"`
+	LimitTier        string    `json:"limit_tier"`
 	PasswordChangeAt time.Time `json:"password_change_at"`
 	CreatedAt        time.Time `json:"created_at"`
 }
@@ -35,6 +41,9 @@ func newUserResponse(user db.User) userResponse {
 		Username:         user.Username,
 		FullName:         user.FullName,
 		Email:            user.Email,
+		Role:             user.Role,
+		IsEmailVerified:  user.IsEmailVerified,
+		LimitTier:        user.LimitTier,
 		PasswordChangeAt: user.PasswordChangeAt,
 		CreatedAt:        user.CreatedAt,
 	}
@@ -60,14 +69,25 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	arg := db.CreateUserParams{
-		Username:      req.Username,
-		HarshPassword: hashedPassword,
-		FullName:      req.FullName,
-		Email:         req.Email,
+	secretCode, err := newSecretCode()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	user, err := server.store.CreateUser(ctx, arg)
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:      req.Username,
+			HarshPassword: hashedPassword,
+			FullName:      req.FullName,
+			Email:         req.Email,
+		},
+		VerifyEmailSecretCode: hashSecretCode(secretCode),
+		VerifyEmailExpiredAt:  time.Now().Add(server.config.VerifyEmailDuration),
+	}
+
+	result, err := server.store.CreateUserTx(ctx, arg)
 
 	if err != nil {
 		// convert this error to postgres error
@@ -83,7 +103,14 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	userRes := newUserResponse(user)
+	// the email is only sent once the user is committed, so it never links to a code that was rolled back
+	err = server.sendVerifyEmail(result.User, result.VerifyEmail, secretCode)
+
+	if err != nil {
+		log.Printf("cannot send verification email to %s: %v", result.User.Username, err)
+	}
+
+	userRes := newUserResponse(result.User)
 
 	ctx.JSON(http.StatusOK, userRes)
 
@@ -132,28 +159,35 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	// if we got here - verify password
 	err = utils.CheckPassword(req.Password, user.HarshPassword)
 
-	if err == sql.ErrNoRows {
+	if err != nil {
 		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
 
 		return
 
 	}
 
-	// if we got here - generate a new access token for the user
-	accessToken, accessPayload, err := server.tokenMaker.CreateToken(
+	// if we got here - generate a new refresh token for the user, its id is the id of the new session
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(
 		user.Username,
-		server.config.AccessTokenDuration,
+		user.Role,
+		uuid.Nil,
+		token.TokenTypeRefreshToken,
+		server.config.RefreshTokenDuration,
 	)
-
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(
+	// the access token records the session it belongs to
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(
 		user.Username,
-		server.config.RefreshTokenDuration,
+		user.Role,
+		refreshPayload.ID,
+		token.TokenTypeAccessToken,
+		server.config.AccessTokenDuration,
 	)
+
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -167,12 +201,16 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		ClientIp:     ctx.ClientIP(),
 		IsBlocked:    false,
 		ExpiresAt:    refreshPayload.ExpiredAt,
+		FamilyID:     refreshPayload.ID, // a login starts a new family of rotated sessions
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	// the login is made without a session, so the audit log records the session it started
+	ctx.Set(auditSessionIDKey, session.ID)
+
 	rsp := loginUserResponse{
 		SessionID:             session.ID,
 		AccessToken:           accessToken,
@@ -184,3 +222,191 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, rsp)
 
 }
+
+// GetUserRequest stores the get user requests
+type GetUserRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+func (server *Server) getUser(ctx *gin.Context) {
+	var req GetUserRequest
+
+	err := ctx.ShouldBindUri(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, req.Username)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+
+}
+
+// UpdateUserRoleRequest stores the update user role requests
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,role"`
+}
+
+// updateUserRole changes the role of a user
+// the access tokens issued to the user before the change are rejected, so the new role applies from the next renewal
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	var uri GetUserRequest
+
+	err := ctx.ShouldBindUri(&uri)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req UpdateUserRoleRequest
+
+	err = ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// an admin cannot take away his/her own admin role and lock everyone out
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if uri.Username == authPayload.Username && req.Role != authPayload.Role {
+		err := errors.New("cannot change the role of the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		Username: uri.Username,
+		Role:     req.Role,
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+
+}
+
+// UpdateUserRequest stores the update user requests
+// only the fields that are set are updated
+type UpdateUserRequest struct {
+	FullName *string `json:"full_name" binding:"omitempty,min=1"`
+	Email    *string `json:"email" binding:"omitempty,email"`
+}
+
+// updateUser updates the profile of a user, a user can update his/her own profile
+// and admins can update any profile
+// a new email has to be verified again, so a verification email is sent to it
+// the user of the uri was authorized by userMiddleware
+func (server *Server) updateUser(ctx *gin.Context) {
+	var req UpdateUserRequest
+
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.FullName == nil && req.Email == nil {
+		err := errors.New("at least one of full_name or email must be given")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	username := ctx.MustGet(authorizationResourceKey).(string)
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	secretCode, err := newSecretCode()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.UpdateUserTxParams{
+		UpdateUserParams: db.UpdateUserParams{
+			Username: username,
+		},
+		ChangedBy:             authPayload.Username,
+		VerifyEmailSecretCode: hashSecretCode(secretCode),
+		VerifyEmailExpiredAt:  time.Now().Add(server.config.VerifyEmailDuration),
+	}
+
+	if req.FullName != nil {
+		arg.FullName = sql.NullString{String: *req.FullName, Valid: true}
+	}
+
+	if req.Email != nil {
+		arg.Email = sql.NullString{String: *req.Email, Valid: true}
+	}
+
+	result, err := server.store.UpdateUserTx(ctx, arg)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		// the new email may already be used by another user
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				return
+
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// a verification code is only created when the email changed,
+	// and it is only emailed once the new email is committed
+	if result.VerifyEmail.ID != 0 {
+		err = server.sendVerifyNewEmail(result.User, result.VerifyEmail, secretCode)
+
+		if err != nil {
+			log.Printf("cannot send verification email to %s: %v", result.User.Username, err)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+
+}
+
+// userMiddleware authorizes the user of the uri, whom the user himself/herself and the roles granted perm may access
+// the username is stored as the resource of the route, the user is not looked up
+func userMiddleware(perm permission) gin.HandlerFunc {
+	return ownershipMiddleware("user", perm, func(ctx *gin.Context) (interface{}, []string, bool) {
+		var uri GetUserRequest
+
+		err := ctx.ShouldBindUri(&uri)
+
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return nil, nil, false
+		}
+
+		return uri.Username, []string{uri.Username}, true
+	})
+}
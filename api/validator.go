@@ -15,3 +15,33 @@ var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
 
 	return false
 }
+
+var validRole validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if role, ok := fieldLevel.Field().Interface().(string); ok {
+		// check if role is supported
+		return utils.IsRoleSupported(role)
+
+	}
+
+	return false
+}
+
+var validDayCountConvention validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if convention, ok := fieldLevel.Field().Interface().(string); ok {
+		// check if day count convention is supported
+		return utils.IsDayCountConventionSupported(convention)
+
+	}
+
+	return false
+}
+
+var validLimitTier validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if tier, ok := fieldLevel.Field().Interface().(string); ok {
+		// check if transfer limit tier is supported
+		return utils.IsLimitTierSupported(tier)
+
+	}
+
+	return false
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	db "simple_bank/db/sqlc"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	secretCodeSize             = 32
+	errCodeEmailNotVerified    = "email_not_verified"
+	verifyEmailSubject         = "Welcome to Simple Bank"
+	verifyEmailContentTemplate = `Hello %s,<br/>
+Thank you for registering with us!<br/>
+Please <a href="%s">click here</a> to verify your email address.<br/>`
+	verifyNewEmailSubject         = "Confirm your new Simple Bank email address"
+	verifyNewEmailContentTemplate = `Hello %s,<br/>
+The email address of your Simple Bank profile was changed to this one.<br/>
+Please <a href="%s">click here</a> to confirm it. You cannot move money until it is confirmed.<br/>
+If you did not make this change you can ignore this email.<br/>`
+)
+
+// newSecretCode generates a random verification code
+// the code only ever leaves the server by email, the db keeps its hash
+func newSecretCode() (string, error) {
+	data := make([]byte, secretCodeSize)
+
+	_, err := rand.Read(data)
+
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(data), nil
+}
+
+// hashSecretCode returns the hex encoded sha256 hash of a verification code
+func hashSecretCode(secretCode string) string {
+	sum := sha256.Sum256([]byte(secretCode))
+	return hex.EncodeToString(sum[:])
+}
+
+// sendVerifyEmail emails a new user a link to verify his/her email address
+func (server *Server) sendVerifyEmail(user db.User, verifyEmail db.VerifyEmail, secretCode string) error {
+	// anyone can register with someone else's email, so the name they chose must not become markup
+	content := fmt.Sprintf(verifyEmailContentTemplate, html.EscapeString(user.FullName), server.verifyEmailURL(verifyEmail, secretCode))
+
+	return server.mailer.SendEmail(verifyEmailSubject, content, []string{user.Email})
+}
+
+// sendVerifyNewEmail emails a user who changed his/her email a link to verify the new address
+func (server *Server) sendVerifyNewEmail(user db.User, verifyEmail db.VerifyEmail, secretCode string) error {
+	content := fmt.Sprintf(verifyNewEmailContentTemplate, html.EscapeString(user.FullName), server.verifyEmailURL(verifyEmail, secretCode))
+
+	return server.mailer.SendEmail(verifyNewEmailSubject, content, []string{user.Email})
+}
+
+// verifyEmailURL returns the link of the verifyEmail endpoint for the given code
+func (server *Server) verifyEmailURL(verifyEmail db.VerifyEmail, secretCode string) string {
+	return fmt.Sprintf(
+		"%s/users/verify_email?email_id=%d&secret_code=%s",
+		server.config.AppBaseURL,
+		verifyEmail.ID,
+		secretCode,
+	)
+}
+
+// VerifyEmailRequest stores the verify email requests
+type VerifyEmailRequest struct {
+	EmailID    int64  `form:"email_id" binding:"required,min=1"`
+	SecretCode string `form:"secret_code" binding:"required,len=64,hexadecimal"`
+}
+
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req VerifyEmailRequest
+
+	err := ctx.ShouldBindQuery(&req)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// the code can only be used once, before it expires
+	_, err = server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailID:    req.EmailID,
+		SecretCode: hashSecretCode(req.SecretCode),
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err := errors.New("invalid, used or expired verification code")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, verifyEmailResponse{IsVerified: true})
+
+}
+
+// requireVerifiedEmail returns true if the user has verified his/her email,
+// otherwise an error response has already been written
+func (server *Server) requireVerifiedEmail(ctx *gin.Context, username string) bool {
+	user, err := server.store.GetUser(ctx, username)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if !user.IsEmailVerified {
+		err := errors.New("email address must be verified first")
+		ctx.JSON(http.StatusForbidden, codedErrorResponse(errCodeEmailNotVerified, err))
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/mail"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	secretCode, err := newSecretCode()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		emailID       int64
+		secretCode    string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "OK",
+			emailID:    1,
+			secretCode: secretCode,
+			buildStubs: func(store *mockdb.MockStore) {
+				// only the hash of the code is looked up
+				arg := db.VerifyEmailTxParams{
+					EmailID:    1,
+					SecretCode: hashSecretCode(secretCode),
+				}
+
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.VerifyEmailTxResult{User: user}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"is_verified": true}`, recorder.Body.String())
+			},
+		},
+		{
+			name:       "InvalidOrUsedCode",
+			emailID:    1,
+			secretCode: secretCode,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmailTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:       "MalformedCode",
+			emailID:    1,
+			secretCode: "not-a-code",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:       "InvalidEmailID",
+			emailID:    0,
+			secretCode: secretCode,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:       "InternalError",
+			emailID:    1,
+			secretCode: secretCode,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmailTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/users/verify_email?email_id=%d&secret_code=%s", tc.emailID, tc.secretCode)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestSendVerifyEmailEscapesFullName(t *testing.T) {
+	user, _ := randomUser(t)
+	user.FullName = `<a href="http://phishing.example">Claim your prize</a>`
+
+	server := newTestServer(t, nil)
+
+	err := server.sendVerifyEmail(user, db.VerifyEmail{ID: 1}, "secret")
+	require.NoError(t, err)
+
+	emails := server.mailer.(*mail.FakeSender).Emails()
+	require.Len(t, emails, 1)
+	require.NotContains(t, emails[0].Content, user.FullName)
+	require.Contains(t, emails[0].Content, "&lt;a href=&#34;http://phishing.example&#34;&gt;")
+}
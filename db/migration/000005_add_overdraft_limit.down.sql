@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS accounts DROP COLUMN IF EXISTS "overdraft_limit";
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE IF EXISTS transfers
+  DROP COLUMN IF EXISTS "to_amount",
+  DROP COLUMN IF EXISTS "from_currency",
+  DROP COLUMN IF EXISTS "to_currency",
+  DROP COLUMN IF EXISTS "exchange_rate";
\ No newline at end of file
@@ -0,0 +1,22 @@
+ALTER TABLE "transfers"
+  ADD COLUMN "to_amount" bigint,
+  ADD COLUMN "from_currency" varchar,
+  ADD COLUMN "to_currency" varchar,
+  ADD COLUMN "exchange_rate" numeric(20, 10) NOT NULL DEFAULT 1;
+
+-- every transfer made so far was between accounts of the same currency
+UPDATE "transfers" t SET
+  "to_amount" = t."amount",
+  "from_currency" = fa."currency",
+  "to_currency" = ta."currency"
+FROM "accounts" fa, "accounts" ta
+WHERE fa."id" = t."from_account_id" AND ta."id" = t."to_account_id";
+
+ALTER TABLE "transfers"
+  ALTER COLUMN "to_amount" SET NOT NULL,
+  ALTER COLUMN "from_currency" SET NOT NULL,
+  ALTER COLUMN "to_currency" SET NOT NULL;
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'amount credited, in to_currency';
+
+COMMENT ON COLUMN "transfers"."exchange_rate" IS 'units of to_currency bought by one unit of from_currency';
@@ -0,0 +1,14 @@
+ALTER TABLE "sessions" ADD COLUMN "family_id" uuid;
+
+-- every existing session starts its own family
+UPDATE "sessions" SET "family_id" = "id";
+
+ALTER TABLE "sessions" ALTER COLUMN "family_id" SET NOT NULL;
+
+ALTER TABLE "sessions" ADD COLUMN "rotated_at" timestamptz;
+
+CREATE INDEX ON "sessions" ("family_id");
+
+COMMENT ON COLUMN "sessions"."family_id" IS 'id of the first session of a chain of rotated refresh tokens';
+
+COMMENT ON COLUMN "sessions"."rotated_at" IS 'set once the refresh token has been exchanged for a new one';
@@ -0,0 +1,3 @@
+ALTER TABLE IF EXISTS users DROP COLUMN IF EXISTS "is_email_verified";
+
+DROP TABLE IF EXISTS verify_emails;
\ No newline at end of file
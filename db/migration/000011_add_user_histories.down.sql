@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_histories;
\ No newline at end of file
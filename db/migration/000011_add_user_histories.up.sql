@@ -0,0 +1,17 @@
+CREATE TABLE "user_histories" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "changed_by" varchar NOT NULL,
+  "field" varchar NOT NULL,
+  "old_value" varchar NOT NULL,
+  "new_value" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "user_histories" ("username");
+
+COMMENT ON COLUMN "user_histories"."changed_by" IS 'the user who made the change, the user himself/herself or an admin';
+
+ALTER TABLE "user_histories" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "user_histories" ADD FOREIGN KEY ("changed_by") REFERENCES "users" ("username");
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS owner_currency_key;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency");
+
+ALTER TABLE IF EXISTS accounts DROP COLUMN IF EXISTS "status";
\ No newline at end of file
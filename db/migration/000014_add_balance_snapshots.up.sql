@@ -0,0 +1,18 @@
+CREATE TABLE "balance_snapshots" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "balance" bigint NOT NULL,
+  "taken_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "balance_snapshots" ("account_id", "taken_at");
+
+-- point-in-time balances sum the entries of an account created after its last snapshot
+CREATE INDEX ON "entries" ("account_id", "created_at");
+
+COMMENT ON COLUMN "balance_snapshots"."balance" IS 'sum of the entries of the account created up to taken_at';
+
+COMMENT ON COLUMN "balance_snapshots"."taken_at" IS 'the end of the day the snapshot was taken for';
+
+ALTER TABLE "balance_snapshots" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
@@ -0,0 +1,4 @@
+ALTER TABLE IF EXISTS transfers
+  DROP COLUMN IF EXISTS "reversal_of",
+  DROP COLUMN IF EXISTS "reversed_amount",
+  DROP COLUMN IF EXISTS "is_reversed";
\ No newline at end of file
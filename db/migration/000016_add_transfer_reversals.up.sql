@@ -0,0 +1,16 @@
+ALTER TABLE "transfers"
+  ADD COLUMN "reversal_of" bigint,
+  ADD COLUMN "reversed_amount" bigint NOT NULL DEFAULT 0,
+  ADD COLUMN "is_reversed" boolean NOT NULL DEFAULT false;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reversal_of") REFERENCES "transfers" ("id");
+
+ALTER TABLE "transfers" ADD CONSTRAINT "reversed_amount_within_amount" CHECK ("reversed_amount" BETWEEN 0 AND "amount");
+
+CREATE INDEX ON "transfers" ("reversal_of");
+
+COMMENT ON COLUMN "transfers"."reversal_of" IS 'transfer refunded by this reversal';
+
+COMMENT ON COLUMN "transfers"."reversed_amount" IS 'running total refunded by reversals, in from_currency';
+
+COMMENT ON COLUMN "transfers"."is_reversed" IS 'set once the whole amount is refunded';
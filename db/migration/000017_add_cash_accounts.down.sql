@@ -0,0 +1,9 @@
+ALTER TABLE IF EXISTS entries DROP COLUMN IF EXISTS "transaction_type";
+
+-- the daily snapshots are taken of every account, even the ones no money moved through
+DELETE FROM balance_snapshots WHERE account_id IN (SELECT id FROM accounts WHERE owner = 'simple_bank');
+
+-- fails once money moved through the cash accounts
+DELETE FROM accounts WHERE owner = 'simple_bank';
+
+DELETE FROM users WHERE username = 'simple_bank';
\ No newline at end of file
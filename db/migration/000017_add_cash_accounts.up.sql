@@ -0,0 +1,13 @@
+ALTER TABLE "entries" ADD COLUMN "transaction_type" varchar NOT NULL DEFAULT 'transfer';
+
+ALTER TABLE "entries" ADD CONSTRAINT "entries_transaction_type_check" CHECK ("transaction_type" IN ('transfer', 'reversal', 'deposit', 'withdrawal'));
+
+COMMENT ON COLUMN "entries"."transaction_type" IS 'kind of money movement that posted the entry';
+
+-- the bank owns one cash account per currency, deposits are paid out of it and withdrawals into it.
+-- Its user has no password, so no one can log in as it
+INSERT INTO "users" ("username", "harsh_password", "full_name", "email", "is_email_verified")
+VALUES ('simple_bank', '', 'Simple Bank', 'cash@simplebank.internal', true);
+
+INSERT INTO "accounts" ("owner", "balance", "currency")
+VALUES ('simple_bank', 0, 'KES'), ('simple_bank', 0, 'USD'), ('simple_bank', 0, 'EUR');
@@ -0,0 +1,17 @@
+ALTER TABLE IF EXISTS transfers DROP COLUMN IF EXISTS "fee_schedule_id", DROP COLUMN IF EXISTS "fee";
+
+DROP TABLE IF EXISTS fee_tiers;
+
+DROP TABLE IF EXISTS fee_schedules;
+
+ALTER TABLE IF EXISTS entries DROP CONSTRAINT IF EXISTS "entries_transaction_type_check";
+
+-- fails once fees were charged
+ALTER TABLE IF EXISTS entries ADD CONSTRAINT "entries_transaction_type_check" CHECK ("transaction_type" IN ('transfer', 'reversal', 'deposit', 'withdrawal'));
+
+-- the fee accounts get a daily snapshot like every other account
+DELETE FROM balance_snapshots WHERE account_id IN (SELECT id FROM accounts WHERE owner = 'simple_bank_fees');
+
+DELETE FROM accounts WHERE owner = 'simple_bank_fees';
+
+DELETE FROM users WHERE username = 'simple_bank_fees';
\ No newline at end of file
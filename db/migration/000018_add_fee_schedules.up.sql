@@ -0,0 +1,60 @@
+-- a schedule is never changed once created, a new version replaces it from its effective time,
+-- so the transfers keep pointing to the schedule their fee was computed with
+CREATE TABLE "fee_schedules" (
+  "id" bigserial PRIMARY KEY,
+  "currency" varchar NOT NULL,
+  "transfer_type" varchar NOT NULL,
+  "version" int NOT NULL,
+  "effective_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- flat and percentage fees are schedules with a single tier starting at 0
+CREATE TABLE "fee_tiers" (
+  "id" bigserial PRIMARY KEY,
+  "fee_schedule_id" bigint NOT NULL,
+  "min_amount" bigint NOT NULL DEFAULT 0,
+  "flat_fee" bigint NOT NULL DEFAULT 0,
+  "basis_points" int NOT NULL DEFAULT 0
+);
+
+ALTER TABLE "fee_schedules" ADD CONSTRAINT "fee_schedules_transfer_type_check" CHECK ("transfer_type" IN ('transfer', 'withdrawal'));
+
+ALTER TABLE "fee_tiers" ADD FOREIGN KEY ("fee_schedule_id") REFERENCES "fee_schedules" ("id");
+
+ALTER TABLE "fee_tiers" ADD CONSTRAINT "fee_tiers_amounts_check" CHECK ("min_amount" >= 0 AND "flat_fee" >= 0 AND "basis_points" BETWEEN 0 AND 10000);
+
+CREATE UNIQUE INDEX ON "fee_schedules" ("currency", "transfer_type", "version");
+
+CREATE UNIQUE INDEX ON "fee_tiers" ("fee_schedule_id", "min_amount");
+
+COMMENT ON COLUMN "fee_schedules"."transfer_type" IS 'entry type of the transfers charged by the schedule';
+
+COMMENT ON COLUMN "fee_schedules"."version" IS 'increases with every new schedule of the same currency and transfer type';
+
+COMMENT ON COLUMN "fee_tiers"."min_amount" IS 'smallest amount the tier applies to, up to the next tier';
+
+COMMENT ON COLUMN "fee_tiers"."basis_points" IS 'percentage of the amount charged, in hundredths of a percent';
+
+ALTER TABLE "transfers"
+  ADD COLUMN "fee" bigint NOT NULL DEFAULT 0,
+  ADD COLUMN "fee_schedule_id" bigint;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("fee_schedule_id") REFERENCES "fee_schedules" ("id");
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_fee_check" CHECK ("fee" >= 0);
+
+COMMENT ON COLUMN "transfers"."fee" IS 'charged to the sender on top of the amount, in from_currency';
+
+COMMENT ON COLUMN "transfers"."fee_schedule_id" IS 'schedule the fee was computed with';
+
+ALTER TABLE "entries" DROP CONSTRAINT "entries_transaction_type_check";
+
+ALTER TABLE "entries" ADD CONSTRAINT "entries_transaction_type_check" CHECK ("transaction_type" IN ('transfer', 'reversal', 'deposit', 'withdrawal', 'fee'));
+
+-- the fees are credited to a fee income account of the bank, one per currency
+INSERT INTO "users" ("username", "harsh_password", "full_name", "email", "is_email_verified")
+VALUES ('simple_bank_fees', '', 'Simple Bank Fees', 'fees@simplebank.internal', true);
+
+INSERT INTO "accounts" ("owner", "balance", "currency")
+VALUES ('simple_bank_fees', 0, 'KES'), ('simple_bank_fees', 0, 'USD'), ('simple_bank_fees', 0, 'EUR');
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS interest_accruals;
+
+DROP TABLE IF EXISTS interest_capitalizations;
+
+DROP TABLE IF EXISTS account_interest_rates;
+
+ALTER TABLE IF EXISTS entries DROP CONSTRAINT IF EXISTS "entries_transaction_type_check";
+
+-- fails once interest was paid
+ALTER TABLE IF EXISTS entries ADD CONSTRAINT "entries_transaction_type_check" CHECK ("transaction_type" IN ('transfer', 'reversal', 'deposit', 'withdrawal', 'fee'));
+
+-- the snapshots of the interest accounts reference them too
+DELETE FROM balance_snapshots WHERE account_id IN (SELECT id FROM accounts WHERE owner = 'simple_bank_interest');
+
+DELETE FROM accounts WHERE owner = 'simple_bank_interest';
+
+DELETE FROM users WHERE username = 'simple_bank_interest';
\ No newline at end of file
@@ -0,0 +1,73 @@
+CREATE TABLE "account_interest_rates" (
+  "account_id" bigint PRIMARY KEY,
+  "annual_rate_bps" int NOT NULL,
+  "day_count_convention" varchar NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "interest_accruals" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "accrual_date" date NOT NULL,
+  "balance" bigint NOT NULL,
+  "annual_rate_bps" int NOT NULL,
+  "day_count_convention" varchar NOT NULL,
+  "amount_micros" bigint NOT NULL,
+  "capitalization_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "interest_capitalizations" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "period_end" date NOT NULL,
+  "accrued_micros" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "carry_micros" bigint NOT NULL,
+  "transfer_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "account_interest_rates" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "account_interest_rates" ADD CONSTRAINT "account_interest_rates_check" CHECK (
+  "annual_rate_bps" BETWEEN 0 AND 10000 AND "day_count_convention" IN ('actual/365', 'actual/360', '30/360')
+);
+
+ALTER TABLE "interest_accruals" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "interest_accruals" ADD FOREIGN KEY ("capitalization_id") REFERENCES "interest_capitalizations" ("id");
+
+ALTER TABLE "interest_capitalizations" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "interest_capitalizations" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+-- a day is accrued and a period is capitalized at most once per account, so both jobs can be re-run
+CREATE UNIQUE INDEX ON "interest_accruals" ("account_id", "accrual_date");
+
+CREATE UNIQUE INDEX ON "interest_capitalizations" ("account_id", "period_end");
+
+COMMENT ON COLUMN "account_interest_rates"."annual_rate_bps" IS 'annual interest rate, in hundredths of a percent';
+
+COMMENT ON COLUMN "account_interest_rates"."day_count_convention" IS 'how the annual rate is split into daily rates';
+
+COMMENT ON COLUMN "interest_accruals"."balance" IS 'balance at the end of the accrual date';
+
+COMMENT ON COLUMN "interest_accruals"."amount_micros" IS 'interest earned on the day, in millionths of the currency minor unit';
+
+COMMENT ON COLUMN "interest_accruals"."capitalization_id" IS 'capitalization that paid the accrual out, unset until then';
+
+COMMENT ON COLUMN "interest_capitalizations"."accrued_micros" IS 'accruals of the period plus the carry of the previous capitalization';
+
+COMMENT ON COLUMN "interest_capitalizations"."carry_micros" IS 'fraction of a minor unit left over, carried to the next capitalization';
+
+ALTER TABLE "entries" DROP CONSTRAINT "entries_transaction_type_check";
+
+ALTER TABLE "entries" ADD CONSTRAINT "entries_transaction_type_check" CHECK ("transaction_type" IN ('transfer', 'reversal', 'deposit', 'withdrawal', 'fee', 'interest'));
+
+-- the interest is paid out of an interest expense account of the bank, one per currency
+INSERT INTO "users" ("username", "harsh_password", "full_name", "email", "is_email_verified")
+VALUES ('simple_bank_interest', '', 'Simple Bank Interest', 'interest@simplebank.internal', true);
+
+INSERT INTO "accounts" ("owner", "balance", "currency")
+VALUES ('simple_bank_interest', 0, 'KES'), ('simple_bank_interest', 0, 'USD'), ('simple_bank_interest', 0, 'EUR');
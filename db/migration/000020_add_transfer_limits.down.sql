@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_transfer_limits;
+
+DROP TABLE IF EXISTS transfer_limits;
+
+ALTER TABLE IF EXISTS users DROP COLUMN IF EXISTS "limit_tier";
\ No newline at end of file
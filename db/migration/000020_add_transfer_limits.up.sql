@@ -0,0 +1,45 @@
+ALTER TABLE "users" ADD COLUMN "limit_tier" varchar NOT NULL DEFAULT 'standard';
+
+ALTER TABLE "users" ADD CONSTRAINT "users_limit_tier_check" CHECK ("limit_tier" IN ('standard', 'premium'));
+
+-- the default limits of every tier, a currency without limits is not limited
+CREATE TABLE "transfer_limits" (
+  "tier" varchar NOT NULL,
+  "currency" varchar NOT NULL,
+  "per_transaction_limit" bigint NOT NULL,
+  "daily_limit" bigint NOT NULL,
+  "monthly_limit" bigint NOT NULL,
+  PRIMARY KEY ("tier", "currency")
+);
+
+-- the limits of a single user, a limit that is not set falls back to the limit of the user tier
+CREATE TABLE "user_transfer_limits" (
+  "username" varchar NOT NULL,
+  "currency" varchar NOT NULL,
+  "per_transaction_limit" bigint,
+  "daily_limit" bigint,
+  "monthly_limit" bigint,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("username", "currency")
+);
+
+ALTER TABLE "user_transfer_limits" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "transfer_limits" ADD CONSTRAINT "transfer_limits_tier_check" CHECK ("tier" IN ('standard', 'premium'));
+
+ALTER TABLE "transfer_limits" ADD CONSTRAINT "transfer_limits_amounts_check" CHECK ("per_transaction_limit" > 0 AND "daily_limit" > 0 AND "monthly_limit" > 0);
+
+ALTER TABLE "user_transfer_limits" ADD CONSTRAINT "user_transfer_limits_amounts_check" CHECK ("per_transaction_limit" > 0 AND "daily_limit" > 0 AND "monthly_limit" > 0);
+
+COMMENT ON COLUMN "transfer_limits"."daily_limit" IS 'total of the outbound transfers of a user in the currency per UTC day';
+
+COMMENT ON COLUMN "transfer_limits"."monthly_limit" IS 'total of the outbound transfers of a user in the currency per UTC month';
+
+INSERT INTO "transfer_limits" ("tier", "currency", "per_transaction_limit", "daily_limit", "monthly_limit")
+VALUES
+  ('standard', 'KES', 10000000, 20000000, 100000000),
+  ('standard', 'USD', 100000, 200000, 1000000),
+  ('standard', 'EUR', 100000, 200000, 1000000),
+  ('premium', 'KES', 50000000, 100000000, 500000000),
+  ('premium', 'USD', 500000, 1000000, 5000000),
+  ('premium', 'EUR', 500000, 1000000, 5000000);
@@ -0,0 +1,45 @@
+-- one row per mutating api call and per login, whether it succeeded or not
+CREATE TABLE "audit_logs" (
+  "id" bigserial PRIMARY KEY,
+  "actor" varchar NOT NULL DEFAULT '',
+  "actor_role" varchar NOT NULL DEFAULT '',
+  "session_id" uuid,
+  "client_ip" varchar NOT NULL,
+  "method" varchar NOT NULL,
+  "route" varchar NOT NULL,
+  "target_ids" jsonb NOT NULL DEFAULT '{}',
+  "request_summary" jsonb NOT NULL DEFAULT '{}',
+  "status" int NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "audit_logs" ("created_at");
+
+CREATE INDEX ON "audit_logs" ("actor", "created_at");
+
+CREATE INDEX ON "audit_logs" ("route", "created_at");
+
+COMMENT ON COLUMN "audit_logs"."actor" IS 'authenticated username, or the username given to a login, empty when unknown';
+
+COMMENT ON COLUMN "audit_logs"."session_id" IS 'session of the access token or of the refresh token the call was made with';
+
+COMMENT ON COLUMN "audit_logs"."route" IS 'route template of the call, like /accounts/:id';
+
+COMMENT ON COLUMN "audit_logs"."target_ids" IS 'uri parameters and ids of the request body the call acted on';
+
+COMMENT ON COLUMN "audit_logs"."request_summary" IS 'request body with secrets redacted';
+
+COMMENT ON COLUMN "audit_logs"."status" IS 'http status of the response';
+
+-- the audit logs are append only, even for the application database user
+CREATE FUNCTION "audit_logs_append_only"() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit logs cannot be changed or deleted';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_logs_no_update_delete" BEFORE UPDATE OR DELETE ON "audit_logs"
+FOR EACH ROW EXECUTE FUNCTION "audit_logs_append_only"();
+
+CREATE TRIGGER "audit_logs_no_truncate" BEFORE TRUNCATE ON "audit_logs"
+FOR EACH STATEMENT EXECUTE FUNCTION "audit_logs_append_only"();
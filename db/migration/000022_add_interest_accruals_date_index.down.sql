@@ -0,0 +1 @@
+DROP INDEX IF EXISTS interest_accruals_accrual_date_idx;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- the scheduler catches up the interest of the days it missed from the last accrued day
+CREATE INDEX ON "interest_accruals" ("accrual_date");
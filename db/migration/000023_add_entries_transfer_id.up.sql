@@ -0,0 +1,33 @@
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+COMMENT ON COLUMN "entries"."transfer_id" IS 'transfer that posted the entry, along with its fee';
+
+-- the entries posted so far are matched to their transfer by account, amount and creation time:
+-- they were created within the db transaction of the transfer, so they share its now().
+-- The entries matching no transfer keep no transfer id and are reported by the ledger verification
+UPDATE "entries" e
+SET "transfer_id" = t.id
+FROM "transfers" t
+WHERE e.created_at = t.created_at
+AND e.transaction_type <> 'fee'
+AND (
+  (e.account_id = t.from_account_id AND e.amount = -t.amount)
+  OR (e.account_id = t.to_account_id AND e.amount = t.to_amount)
+);
+
+UPDATE "entries" e
+SET "transfer_id" = t.id
+FROM "transfers" t
+WHERE e.created_at = t.created_at
+AND e.transaction_type = 'fee'
+AND t.fee > 0
+AND (
+  (e.account_id = t.from_account_id AND e.amount = -t.fee)
+  OR (e.amount = t.fee AND e.account_id IN (
+    SELECT id FROM accounts WHERE owner = 'simple_bank_fees' AND currency = t.from_currency
+  ))
+);
@@ -0,0 +1,3 @@
+ALTER TABLE "users" ADD COLUMN "role_change_at" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z';
+
+COMMENT ON COLUMN "users"."role_change_at" IS 'access tokens issued before the last role change still carry the old role';
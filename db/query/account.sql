@@ -11,6 +11,12 @@ INSERT INTO accounts (
 SELECT * FROM accounts
 WHERE id = $1 LIMIT 1;
 
+-- name: GetAccountByCurrency :one
+-- an owner has at most one open account per currency
+SELECT * FROM accounts
+WHERE owner = $1 AND currency = $2 AND status <> 'closed'
+LIMIT 1;
+
 -- name: GetAccountForUpdate :one
 SELECT * FROM accounts
 WHERE id = $1 LIMIT 1
@@ -35,6 +41,18 @@ SET balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
+-- name: UpdateAccountOverdraftLimit :one
+UPDATE accounts
+SET overdraft_limit = sqlc.arg(overdraft_limit)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
 -- name: DeleteAccount :exec
 DELETE FROM accounts
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+-- name: UpdateAccountStatus :one
+UPDATE accounts
+SET status = sqlc.arg(new_status)
+WHERE id = sqlc.arg(id)
+AND status = sqlc.arg(old_status)
+RETURNING *;
@@ -0,0 +1,32 @@
+-- name: CreateAuditLog :one
+INSERT INTO audit_logs (
+  actor,
+  actor_role,
+  session_id,
+  client_ip,
+  method,
+  route,
+  target_ids,
+  request_summary,
+  status
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING *;
+
+-- name: ListAuditLogs :many
+-- the most recent audit logs first, a filter left unset matches every log
+SELECT * FROM audit_logs
+WHERE
+    (sqlc.narg(actor)::varchar IS NULL OR actor = sqlc.narg(actor))
+    AND (sqlc.narg(session_id)::uuid IS NULL OR session_id = sqlc.narg(session_id))
+    AND (sqlc.narg(method)::varchar IS NULL OR method = sqlc.narg(method))
+    AND (sqlc.narg(route)::varchar IS NULL OR route = sqlc.narg(route))
+    AND (sqlc.narg(target_id)::varchar IS NULL OR EXISTS (
+      SELECT 1 FROM jsonb_each_text(target_ids) t WHERE t.value = sqlc.narg(target_id)
+    ))
+    AND (sqlc.narg(status)::int IS NULL OR status = sqlc.narg(status))
+    AND (sqlc.narg(from_time)::timestamptz IS NULL OR created_at >= sqlc.narg(from_time))
+    AND (sqlc.narg(to_time)::timestamptz IS NULL OR created_at <= sqlc.narg(to_time))
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
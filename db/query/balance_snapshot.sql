@@ -0,0 +1,39 @@
+-- name: CreateBalanceSnapshots :execrows
+-- every snapshot continues from the previous snapshot of its account,
+-- a day that was already snapshotted is left untouched
+INSERT INTO balance_snapshots (account_id, balance, taken_at)
+SELECT a.id, (COALESCE(prev.balance, 0) + COALESCE(SUM(e.amount), 0))::bigint, sqlc.arg(taken_at)::timestamptz
+FROM accounts a
+LEFT JOIN LATERAL (
+  SELECT balance, taken_at FROM balance_snapshots
+  WHERE account_id = a.id AND taken_at < sqlc.arg(taken_at)
+  ORDER BY taken_at DESC
+  LIMIT 1
+) prev ON true
+LEFT JOIN entries e ON e.account_id = a.id
+  AND e.created_at > COALESCE(prev.taken_at, '-infinity')
+  AND e.created_at <= sqlc.arg(taken_at)
+WHERE a.created_at <= sqlc.arg(taken_at)
+GROUP BY a.id, prev.balance
+ON CONFLICT (account_id, taken_at) DO NOTHING;
+
+-- name: GetAccountBalanceAt :one
+-- the balance of the last snapshot taken at or before the given time
+-- plus the entries created after that snapshot, up to the given time
+SELECT (
+  COALESCE(s.balance, 0) + COALESCE((
+    SELECT SUM(e.amount) FROM entries e
+    WHERE e.account_id = a.id
+    AND e.created_at > COALESCE(s.taken_at, '-infinity')
+    AND e.created_at <= sqlc.arg(at)
+  ), 0)
+)::bigint AS balance
+FROM accounts a
+LEFT JOIN LATERAL (
+  SELECT balance, taken_at FROM balance_snapshots
+  WHERE account_id = a.id AND taken_at <= sqlc.arg(at)
+  ORDER BY taken_at DESC
+  LIMIT 1
+) s ON true
+WHERE a.id = sqlc.arg(account_id);
+
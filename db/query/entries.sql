@@ -1,9 +1,11 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  transaction_type,
+  transfer_id
 ) VALUES (
-  $1, $2
+  $1, $2, $3, $4
 ) 
 RETURNING *;
 
@@ -16,4 +18,32 @@ SELECT * FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
-OFFSET $3;
\ No newline at end of file
+OFFSET $3;
+
+-- name: ListAccountStatement :many
+-- the running balance adds up the entries from the last balance snapshot taken before the statement,
+-- so only the entries since that snapshot are read and the account balance is never trusted
+WITH snapshot AS (
+  SELECT balance, taken_at FROM balance_snapshots
+  WHERE account_id = sqlc.arg(account_id) AND taken_at < sqlc.arg(from_time)
+  ORDER BY taken_at DESC
+  LIMIT 1
+)
+SELECT id, account_id, amount, created_at, transaction_type, running_balance
+FROM (
+  SELECT
+    e.id,
+    e.account_id,
+    e.amount,
+    e.created_at,
+    e.transaction_type,
+    (COALESCE((SELECT balance FROM snapshot), 0) + SUM(e.amount) OVER (ORDER BY e.id))::bigint AS running_balance
+  FROM entries e
+  WHERE e.account_id = sqlc.arg(account_id)
+  AND e.created_at > COALESCE((SELECT taken_at FROM snapshot), '-infinity')
+  AND e.created_at <= sqlc.arg(to_time)
+) AS statement
+WHERE created_at >= sqlc.arg(from_time)
+ORDER BY id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
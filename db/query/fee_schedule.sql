@@ -0,0 +1,52 @@
+-- name: CreateFeeSchedule :one
+-- the schedule gets the next version of its currency and transfer type
+INSERT INTO fee_schedules (
+  currency,
+  transfer_type,
+  version,
+  effective_at
+) VALUES (
+  sqlc.arg(currency),
+  sqlc.arg(transfer_type),
+  (
+    SELECT COALESCE(MAX(version), 0) + 1 FROM fee_schedules
+    WHERE currency = sqlc.arg(currency) AND transfer_type = sqlc.arg(transfer_type)
+  ),
+  sqlc.arg(effective_at)
+) RETURNING *;
+
+-- name: CreateFeeTier :one
+INSERT INTO fee_tiers (
+  fee_schedule_id,
+  min_amount,
+  flat_fee,
+  basis_points
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetFeeSchedule :one
+SELECT * FROM fee_schedules
+WHERE id = $1 LIMIT 1;
+
+-- name: GetCurrentFeeSchedule :one
+-- the latest version already in effect applies
+SELECT * FROM fee_schedules
+WHERE currency = $1
+AND transfer_type = $2
+AND effective_at <= now()
+ORDER BY version DESC
+LIMIT 1;
+
+-- name: ListFeeSchedules :many
+SELECT * FROM fee_schedules
+WHERE currency = $1
+AND transfer_type = $2
+ORDER BY version
+LIMIT $3
+OFFSET $4;
+
+-- name: ListFeeTiers :many
+SELECT * FROM fee_tiers
+WHERE fee_schedule_id = $1
+ORDER BY min_amount;
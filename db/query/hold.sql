@@ -0,0 +1,60 @@
+-- name: CreateHold :one
+INSERT INTO holds (
+  account_id,
+  to_account_id,
+  amount,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetHold :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1;
+
+-- name: GetHoldForUpdate :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListHolds :many
+SELECT * FROM holds
+WHERE account_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: GetAccountHeldAmount :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS held_amount FROM holds
+WHERE account_id = $1
+AND status = 'held'
+AND expires_at > now();
+
+-- name: CaptureHold :one
+UPDATE holds
+SET
+  status = 'captured',
+  captured_amount = sqlc.arg(captured_amount)
+WHERE id = sqlc.arg(id)
+AND status = 'held'
+RETURNING *;
+
+-- name: UpdateHoldTransfer :one
+UPDATE holds
+SET transfer_id = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: VoidHold :one
+UPDATE holds
+SET status = 'voided'
+WHERE id = $1
+AND status = 'held'
+AND expires_at > now()
+RETURNING *;
+
+-- name: ExpireHolds :execrows
+UPDATE holds
+SET status = 'expired'
+WHERE status = 'held'
+AND expires_at <= now();
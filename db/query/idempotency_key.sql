@@ -0,0 +1,34 @@
+-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys (
+  username,
+  key,
+  request_hash,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (username, key) DO UPDATE
+SET
+  request_hash = EXCLUDED.request_hash,
+  response_status = 0,
+  response_body = '{}',
+  expires_at = EXCLUDED.expires_at,
+  created_at = now()
+WHERE idempotency_keys.expires_at < now()
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE username = $1 AND key = $2 LIMIT 1;
+
+-- name: UpdateIdempotencyKeyResponse :one
+UPDATE idempotency_keys
+SET
+  response_status = $3,
+  response_body = $4
+WHERE username = $1 AND key = $2
+RETURNING *;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE username = $1 AND key = $2;
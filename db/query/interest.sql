@@ -0,0 +1,103 @@
+-- name: UpsertAccountInterestRate :one
+INSERT INTO account_interest_rates (
+  account_id,
+  annual_rate_bps,
+  day_count_convention
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (account_id) DO UPDATE SET
+  annual_rate_bps = EXCLUDED.annual_rate_bps,
+  day_count_convention = EXCLUDED.day_count_convention,
+  updated_at = now()
+RETURNING *;
+
+-- name: GetAccountInterestRate :one
+SELECT * FROM account_interest_rates
+WHERE account_id = $1 LIMIT 1;
+
+-- name: ListAccruingInterestRates :many
+-- the rates of the active accounts that already existed at the end of the day
+SELECT r.* FROM account_interest_rates r
+JOIN accounts a ON a.id = r.account_id
+WHERE a.status = 'active'
+AND a.created_at < sqlc.arg(day_end)
+AND r.annual_rate_bps > 0
+ORDER BY r.account_id;
+
+-- name: CreateInterestAccrual :execrows
+-- a day that was already accrued is left untouched
+INSERT INTO interest_accruals (
+  account_id,
+  accrual_date,
+  balance,
+  annual_rate_bps,
+  day_count_convention,
+  amount_micros
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (account_id, accrual_date) DO NOTHING;
+
+-- name: GetLastInterestAccrualDate :one
+-- returns no rows when no interest was ever accrued
+SELECT accrual_date FROM interest_accruals
+ORDER BY accrual_date DESC
+LIMIT 1;
+
+-- name: ListInterestAccruals :many
+SELECT * FROM interest_accruals
+WHERE account_id = $1
+ORDER BY accrual_date
+LIMIT $2
+OFFSET $3;
+
+-- name: ListAccountsToCapitalize :many
+SELECT DISTINCT ia.account_id FROM interest_accruals ia
+JOIN accounts a ON a.id = ia.account_id
+WHERE ia.capitalization_id IS NULL
+AND ia.accrual_date <= sqlc.arg(period_end)
+AND a.status = 'active'
+ORDER BY ia.account_id;
+
+-- name: GetUncapitalizedInterest :one
+SELECT COALESCE(SUM(amount_micros), 0)::bigint AS amount_micros FROM interest_accruals
+WHERE account_id = sqlc.arg(account_id)
+AND capitalization_id IS NULL
+AND accrual_date <= sqlc.arg(period_end);
+
+-- name: GetInterestCarry :one
+-- the fraction of a minor unit left over by the last capitalization of the account
+SELECT COALESCE((
+  SELECT carry_micros FROM interest_capitalizations
+  WHERE account_id = $1
+  ORDER BY period_end DESC
+  LIMIT 1
+), 0)::bigint AS carry_micros;
+
+-- name: CreateInterestCapitalization :one
+-- returns no rows when the period was already capitalized
+INSERT INTO interest_capitalizations (
+  account_id,
+  period_end,
+  accrued_micros,
+  amount,
+  carry_micros
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+ON CONFLICT (account_id, period_end) DO NOTHING
+RETURNING *;
+
+-- name: CapitalizeInterestAccruals :execrows
+UPDATE interest_accruals
+SET capitalization_id = sqlc.arg(capitalization_id)
+WHERE account_id = sqlc.arg(account_id)
+AND capitalization_id IS NULL
+AND accrual_date <= sqlc.arg(period_end);
+
+-- name: UpdateInterestCapitalizationTransfer :one
+UPDATE interest_capitalizations
+SET transfer_id = $2
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,43 @@
+-- name: ListAccountBalanceMismatches :many
+SELECT a.id, a.owner, a.currency, a.balance, COALESCE(SUM(e.amount), 0)::bigint AS entries_total
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+GROUP BY a.id
+HAVING a.balance <> COALESCE(SUM(e.amount), 0)
+ORDER BY a.id;
+
+-- name: ListOrphanEntries :many
+-- every entry is posted by a transfer, the ones without a transfer moved money on their own
+SELECT e.* FROM entries e
+LEFT JOIN transfers t ON t.id = e.transfer_id
+WHERE t.id IS NULL
+ORDER BY e.id;
+
+-- name: ListUnbalancedTransfers :many
+-- the fee entries posted along with a transfer are not part of its amount
+SELECT id, from_account_id, to_account_id, amount, to_amount, from_currency, to_currency, debit_entries, credit_entries
+FROM (
+  SELECT
+    t.id,
+    t.from_account_id,
+    t.to_account_id,
+    t.amount,
+    t.to_amount,
+    t.from_currency,
+    t.to_currency,
+    (
+      SELECT COUNT(*) FROM entries e
+      WHERE e.transfer_id = t.id AND e.account_id = t.from_account_id AND e.amount = -t.amount
+      AND e.transaction_type <> 'fee'
+    ) AS debit_entries,
+    (
+      SELECT COUNT(*) FROM entries e
+      WHERE e.transfer_id = t.id AND e.account_id = t.to_account_id AND e.amount = t.to_amount
+      AND e.transaction_type <> 'fee'
+    ) AS credit_entries
+  FROM transfers t
+) AS checked
+WHERE debit_entries <> 1
+OR credit_entries <> 1
+OR (from_currency = to_currency AND to_amount <> amount)
+ORDER BY id;
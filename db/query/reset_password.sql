@@ -0,0 +1,23 @@
+-- name: CreateResetPassword :one
+INSERT INTO reset_passwords (
+  username,
+  secret_code,
+  expired_at
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: UpdateResetPassword :one
+UPDATE reset_passwords
+SET is_used = true
+WHERE id = sqlc.arg(id)
+AND secret_code = sqlc.arg(secret_code)
+AND is_used = false
+AND expired_at > now()
+RETURNING *;
+
+-- name: UseUserResetPasswords :execrows
+UPDATE reset_passwords
+SET is_used = true
+WHERE username = $1
+AND is_used = false;
@@ -0,0 +1,75 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+  owner,
+  from_account_id,
+  to_account_id,
+  amount,
+  recurrence,
+  next_run_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: ListScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+SET
+  amount = COALESCE(sqlc.narg(amount), amount),
+  recurrence = COALESCE(sqlc.narg(recurrence), recurrence),
+  next_run_at = COALESCE(sqlc.narg(next_run_at), next_run_at),
+  attempts = 0
+WHERE id = sqlc.arg(id)
+AND status = 'active'
+RETURNING *;
+
+-- name: CancelScheduledTransfer :one
+UPDATE scheduled_transfers
+SET status = 'cancelled'
+WHERE id = $1
+AND status = 'active'
+RETURNING *;
+
+-- name: ClaimDueScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE status = 'active'
+AND next_run_at <= sqlc.arg(now)
+ORDER BY next_run_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: RescheduleScheduledTransfer :one
+UPDATE scheduled_transfers
+SET
+  status = sqlc.arg(status),
+  next_run_at = sqlc.arg(next_run_at),
+  attempts = sqlc.arg(attempts)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+  scheduled_transfer_id,
+  transfer_id,
+  attempt,
+  succeeded,
+  error
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListScheduledTransferRuns :many
+SELECT * FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
@@ -6,11 +6,55 @@ INSERT INTO sessions (
   user_agent,
   client_ip,
   is_blocked,
-  expires_at
+  expires_at,
+  family_id
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7
+  $1, $2, $3, $4, $5, $6, $7, $8
 ) RETURNING *;
 
 -- name: GetSession :one
 SELECT * FROM sessions
-WHERE id = $1 LIMIT 1;
\ No newline at end of file
+WHERE id = $1 LIMIT 1;
+
+-- name: ListSessions :many
+SELECT * FROM sessions
+WHERE username = $1
+AND is_blocked = false
+AND rotated_at IS NULL
+AND expires_at > now()
+ORDER BY created_at DESC;
+
+-- name: BlockSession :one
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1
+AND username = $2
+RETURNING *;
+
+-- name: BlockOtherSessions :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE username = $1
+AND family_id <> $2
+AND is_blocked = false
+AND rotated_at IS NULL;
+
+-- name: BlockSessionFamily :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE family_id = $1
+AND is_blocked = false;
+
+-- name: RotateSession :one
+UPDATE sessions
+SET rotated_at = now()
+WHERE id = $1
+AND rotated_at IS NULL
+AND is_blocked = false
+RETURNING *;
+
+-- name: BlockUserSessions :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE username = $1
+AND is_blocked = false;
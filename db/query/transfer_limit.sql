@@ -0,0 +1,84 @@
+-- name: GetTransferLimit :one
+-- the limits of a user in a currency, the limits of the user tier unless they are overridden,
+-- along with the outbound transfers of the user since the start of the UTC day and month
+SELECT
+  tl.currency,
+  u.limit_tier,
+  COALESCE(utl.per_transaction_limit, tl.per_transaction_limit)::bigint AS per_transaction_limit,
+  COALESCE(utl.daily_limit, tl.daily_limit)::bigint AS daily_limit,
+  COALESCE(utl.monthly_limit, tl.monthly_limit)::bigint AS monthly_limit,
+  COALESCE((
+    SELECT SUM(-e.amount) FROM entries e
+    JOIN accounts a ON a.id = e.account_id
+    WHERE a.owner = u.username
+    AND a.currency = tl.currency
+    AND e.transaction_type = 'transfer'
+    AND e.amount < 0
+    AND e.created_at >= date_trunc('day', now() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+  ), 0)::bigint AS daily_total,
+  COALESCE((
+    SELECT SUM(-e.amount) FROM entries e
+    JOIN accounts a ON a.id = e.account_id
+    WHERE a.owner = u.username
+    AND a.currency = tl.currency
+    AND e.transaction_type = 'transfer'
+    AND e.amount < 0
+    AND e.created_at >= date_trunc('month', now() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+  ), 0)::bigint AS monthly_total
+FROM users u
+JOIN transfer_limits tl ON tl.tier = u.limit_tier
+LEFT JOIN user_transfer_limits utl ON utl.username = u.username AND utl.currency = tl.currency
+WHERE u.username = sqlc.arg(username)
+AND tl.currency = sqlc.arg(currency)
+LIMIT 1;
+
+-- name: ListTransferLimits :many
+-- the limits of a user in every currency, along with the outbound transfers of the user
+-- since the start of the UTC day and month
+SELECT
+  tl.currency,
+  u.limit_tier,
+  COALESCE(utl.per_transaction_limit, tl.per_transaction_limit)::bigint AS per_transaction_limit,
+  COALESCE(utl.daily_limit, tl.daily_limit)::bigint AS daily_limit,
+  COALESCE(utl.monthly_limit, tl.monthly_limit)::bigint AS monthly_limit,
+  COALESCE((
+    SELECT SUM(-e.amount) FROM entries e
+    JOIN accounts a ON a.id = e.account_id
+    WHERE a.owner = u.username
+    AND a.currency = tl.currency
+    AND e.transaction_type = 'transfer'
+    AND e.amount < 0
+    AND e.created_at >= date_trunc('day', now() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+  ), 0)::bigint AS daily_total,
+  COALESCE((
+    SELECT SUM(-e.amount) FROM entries e
+    JOIN accounts a ON a.id = e.account_id
+    WHERE a.owner = u.username
+    AND a.currency = tl.currency
+    AND e.transaction_type = 'transfer'
+    AND e.amount < 0
+    AND e.created_at >= date_trunc('month', now() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+  ), 0)::bigint AS monthly_total
+FROM users u
+JOIN transfer_limits tl ON tl.tier = u.limit_tier
+LEFT JOIN user_transfer_limits utl ON utl.username = u.username AND utl.currency = tl.currency
+WHERE u.username = sqlc.arg(username)
+ORDER BY tl.currency;
+
+-- name: UpsertUserTransferLimit :one
+-- a limit left unset falls back to the limit of the user tier
+INSERT INTO user_transfer_limits (
+  username,
+  currency,
+  per_transaction_limit,
+  daily_limit,
+  monthly_limit
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+ON CONFLICT (username, currency) DO UPDATE SET
+  per_transaction_limit = EXCLUDED.per_transaction_limit,
+  daily_limit = EXCLUDED.daily_limit,
+  monthly_limit = EXCLUDED.monthly_limit,
+  updated_at = now()
+RETURNING *;
@@ -2,9 +2,16 @@
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  to_amount,
+  from_currency,
+  to_currency,
+  exchange_rate,
+  reversal_of,
+  fee,
+  fee_schedule_id
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 ) 
 RETURNING *;
 
@@ -12,11 +19,45 @@ RETURNING *;
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 WHERE 
-    from_account_id = $1 OR
-    to_account_id = $2
+    from_account_id = sqlc.arg(account_id) OR
+    to_account_id = sqlc.arg(account_id)
 ORDER BY id
-LIMIT $3
-OFFSET $4;
\ No newline at end of file
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: ListUserTransfers :many
+SELECT t.* FROM transfers t
+JOIN accounts fa ON fa.id = t.from_account_id
+JOIN accounts ta ON ta.id = t.to_account_id
+WHERE
+    (
+      (sqlc.arg(direction)::text IN ('all', 'outgoing') AND fa.owner = sqlc.arg(owner)) OR
+      (sqlc.arg(direction)::text IN ('all', 'incoming') AND ta.owner = sqlc.arg(owner))
+    )
+    AND (sqlc.narg(counterparty_id)::bigint IS NULL OR
+      t.from_account_id = sqlc.narg(counterparty_id) OR
+      t.to_account_id = sqlc.narg(counterparty_id))
+    AND (sqlc.narg(min_amount)::bigint IS NULL OR t.amount >= sqlc.narg(min_amount))
+    AND (sqlc.narg(max_amount)::bigint IS NULL OR t.amount <= sqlc.narg(max_amount))
+    AND (sqlc.narg(from_time)::timestamptz IS NULL OR t.created_at >= sqlc.narg(from_time))
+    AND (sqlc.narg(to_time)::timestamptz IS NULL OR t.created_at <= sqlc.narg(to_time))
+ORDER BY t.id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: AddTransferReversedAmount :one
+-- the transfer is marked as reversed once its whole amount is refunded
+UPDATE transfers
+SET
+  reversed_amount = reversed_amount + sqlc.arg(amount),
+  is_reversed = reversed_amount + sqlc.arg(amount) = amount
+WHERE id = sqlc.arg(id)
+RETURNING *;
\ No newline at end of file
@@ -10,4 +10,58 @@ INSERT INTO users (
 
 -- name: GetUser :one
 SELECT * FROM users
-WHERE username = $1 LIMIT 1;
\ No newline at end of file
+WHERE username = $1 LIMIT 1;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = sqlc.arg(role),
+  role_change_at = CASE WHEN role = sqlc.arg(role) THEN role_change_at ELSE now() END
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET is_email_verified = true
+WHERE username = sqlc.arg(username)
+AND email = sqlc.arg(email)
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET harsh_password = sqlc.arg(harsh_password),
+  password_change_at = sqlc.arg(password_change_at)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: GetUserAuthChangeAt :one
+-- the access tokens issued before the last password or role change are no longer valid
+SELECT password_change_at, role_change_at FROM users
+WHERE username = $1 LIMIT 1;
+
+-- name: GetUserForUpdate :one
+SELECT * FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UpdateUser :one
+UPDATE users
+SET
+  full_name = COALESCE(sqlc.narg(full_name), full_name),
+  email = COALESCE(sqlc.narg(email), email),
+  is_email_verified = CASE
+    WHEN sqlc.narg(email)::varchar IS NULL OR sqlc.narg(email) = email THEN is_email_verified
+    ELSE false
+  END
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: UpdateUserLimitTier :one
+UPDATE users
+SET limit_tier = sqlc.arg(limit_tier)
+WHERE username = sqlc.arg(username)
+RETURNING *;
@@ -0,0 +1,17 @@
+-- name: CreateUserHistory :one
+INSERT INTO user_histories (
+  username,
+  changed_by,
+  field,
+  old_value,
+  new_value
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListUserHistories :many
+SELECT * FROM user_histories
+WHERE username = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
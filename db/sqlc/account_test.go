@@ -11,11 +11,16 @@ import (
 )
 
 func CreateRandomAccount(t *testing.T) Account {
+	return CreateRandomAccountWithCurrency(t, utils.RandomCurrency())
+}
+
+// CreateRandomAccountWithCurrency creates a random account holding the given currency
+func CreateRandomAccountWithCurrency(t *testing.T, currency string) Account {
 	user := CreateRandomUser(t)
 	arg := CreateAccountParams{
 		Owner:    user.Username,
 		Balance:  utils.RandomMoney(),
-		Currency: utils.RandomCurrency(),
+		Currency: currency,
 	}
 
 	account, err := testQueries.CreateAccount(context.Background(), arg)
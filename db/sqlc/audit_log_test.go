@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomAuditLog(t *testing.T, actor string, targetID int64) AuditLog {
+	arg := CreateAuditLogParams{
+		Actor:          actor,
+		ActorRole:      utils.CustomerRole,
+		SessionID:      uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		ClientIp:       "127.0.0.1",
+		Method:         http.MethodPost,
+		Route:          "/accounts/:id/close",
+		TargetIds:      json.RawMessage(fmt.Sprintf(`{"id": "%d"}`, targetID)),
+		RequestSummary: json.RawMessage(`{}`),
+		Status:         http.StatusOK,
+	}
+
+	auditLog, err := testQueries.CreateAuditLog(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, auditLog.ID)
+	require.Equal(t, arg.Actor, auditLog.Actor)
+	require.Equal(t, arg.ActorRole, auditLog.ActorRole)
+	require.Equal(t, arg.SessionID, auditLog.SessionID)
+	require.Equal(t, arg.ClientIp, auditLog.ClientIp)
+	require.Equal(t, arg.Method, auditLog.Method)
+	require.Equal(t, arg.Route, auditLog.Route)
+	require.JSONEq(t, string(arg.TargetIds), string(auditLog.TargetIds))
+	require.Equal(t, arg.Status, auditLog.Status)
+	require.WithinDuration(t, time.Now(), auditLog.CreatedAt, time.Second)
+
+	return auditLog
+}
+
+func TestListAuditLogs(t *testing.T) {
+	user := CreateRandomUser(t)
+	targetID := utils.RandomInt(1, 1000000)
+
+	auditLog1 := CreateRandomAuditLog(t, user.Username, targetID)
+	auditLog2 := CreateRandomAuditLog(t, user.Username, targetID+1)
+
+	auditLogs, err := testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Actor:  sql.NullString{String: user.Username, Valid: true},
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, auditLogs, 2)
+
+	// the most recent calls come first
+	require.Equal(t, auditLog2.ID, auditLogs[0].ID)
+	require.Equal(t, auditLog1.ID, auditLogs[1].ID)
+
+	auditLogs, err = testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Actor:     sql.NullString{String: user.Username, Valid: true},
+		SessionID: auditLog1.SessionID,
+		TargetID:  sql.NullString{String: fmt.Sprint(targetID), Valid: true},
+		Status:    sql.NullInt32{Int32: http.StatusOK, Valid: true},
+		FromTime:  sql.NullTime{Time: auditLog1.CreatedAt.Add(-time.Minute), Valid: true},
+		Limit:     5,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, auditLogs, 1)
+	require.Equal(t, auditLog1.ID, auditLogs[0].ID)
+}
+
+func TestAuditLogAppendOnly(t *testing.T) {
+	user := CreateRandomUser(t)
+	auditLog := CreateRandomAuditLog(t, user.Username, utils.RandomInt(1, 1000000))
+
+	_, err := testDB.ExecContext(context.Background(), "UPDATE audit_logs SET status = 500 WHERE id = $1", auditLog.ID)
+	require.Error(t, err)
+
+	_, err = testDB.ExecContext(context.Background(), "DELETE FROM audit_logs WHERE id = $1", auditLog.ID)
+	require.Error(t, err)
+}
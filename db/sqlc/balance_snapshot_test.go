@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountBalanceAt(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 0)
+	account2 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 0)
+
+	_, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 100,
+	})
+	require.NoError(t, err)
+
+	transfer := func(amount int64) time.Time {
+		result, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        amount,
+		})
+		require.NoError(t, err)
+		return result.Transfer.CreatedAt
+	}
+
+	balanceAt := func(account Account, at time.Time) int64 {
+		balance, err := testQueries.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+			AccountID: account.ID,
+			At:        at,
+		})
+		require.NoError(t, err)
+		return balance
+	}
+
+	firstAt := transfer(10)
+	secondAt := transfer(20)
+
+	require.Zero(t, balanceAt(account2, firstAt.Add(-time.Microsecond)))
+	require.Equal(t, int64(10), balanceAt(account2, firstAt))
+	require.Equal(t, int64(30), balanceAt(account2, secondAt))
+
+	// snapshots continue from each other and only count the entries up to their time
+	_, err = testQueries.CreateBalanceSnapshots(context.Background(), firstAt)
+	require.NoError(t, err)
+
+	_, err = testQueries.CreateBalanceSnapshots(context.Background(), secondAt)
+	require.NoError(t, err)
+
+	thirdAt := transfer(40)
+
+	require.Zero(t, balanceAt(account2, firstAt.Add(-time.Microsecond)))
+	require.Equal(t, int64(10), balanceAt(account2, firstAt))
+	require.Equal(t, int64(30), balanceAt(account2, secondAt))
+	require.Equal(t, int64(70), balanceAt(account2, thirdAt))
+	require.Equal(t, int64(-70), balanceAt(account1, thirdAt))
+
+	// a day is only snapshotted once
+	snapshots, err := testQueries.CreateBalanceSnapshots(context.Background(), secondAt)
+	require.NoError(t, err)
+	require.Zero(t, snapshots)
+}
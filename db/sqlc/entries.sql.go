@@ -7,37 +7,50 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  transaction_type,
+  transfer_id
 ) VALUES (
-  $1, $2
+  $1, $2, $3, $4
 ) 
-RETURNING id, account_id, amount, created_at
+RETURNING id, account_id, amount, created_at, transaction_type, transfer_id
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID       int64         `json:"account_id"`
+	Amount          int64         `json:"amount"`
+	TransactionType string        `json:"transaction_type"`
+	TransferID      sql.NullInt64 `json:"transfer_id"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.TransactionType,
+		arg.TransferID,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransactionType,
+		&i.TransferID,
 	)
 	return i, err
 }
 
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, transaction_type, transfer_id FROM entries
 WHERE id = $1 LIMIT 1
 `
 
@@ -49,12 +62,96 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransactionType,
+		&i.TransferID,
 	)
 	return i, err
 }
 
+const listAccountStatement = `-- name: ListAccountStatement :many
+WITH snapshot AS (
+  SELECT balance, taken_at FROM balance_snapshots
+  WHERE account_id = $1 AND taken_at < $2
+  ORDER BY taken_at DESC
+  LIMIT 1
+)
+SELECT id, account_id, amount, created_at, transaction_type, running_balance
+FROM (
+  SELECT
+    e.id,
+    e.account_id,
+    e.amount,
+    e.created_at,
+    e.transaction_type,
+    (COALESCE((SELECT balance FROM snapshot), 0) + SUM(e.amount) OVER (ORDER BY e.id))::bigint AS running_balance
+  FROM entries e
+  WHERE e.account_id = $1
+  AND e.created_at > COALESCE((SELECT taken_at FROM snapshot), '-infinity')
+  AND e.created_at <= $3
+) AS statement
+WHERE created_at >= $2
+ORDER BY id
+LIMIT $4
+OFFSET $5
+`
+
+type ListAccountStatementParams struct {
+	AccountID int64     `json:"account_id"`
+	FromTime  time.Time `json:"from_time"`
+	ToTime    time.Time `json:"to_time"`
+	Limit     int32     `json:"limit"`
+	Offset    int32     `json:"offset"`
+}
+
+type ListAccountStatementRow struct {
+	ID              int64     `json:"id"`
+	AccountID       int64     `json:"account_id"`
+	Amount          int64     `json:"amount"`
+	CreatedAt       time.Time `json:"created_at"`
+	TransactionType string    `json:"transaction_type"`
+	RunningBalance  int64     `json:"running_balance"`
+}
+
+// the running balance adds up the entries from the last balance snapshot taken before the statement,
+// so only the entries since that snapshot are read and the account balance is never trusted
+func (q *Queries) ListAccountStatement(ctx context.Context, arg ListAccountStatementParams) ([]ListAccountStatementRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountStatement,
+		arg.AccountID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountStatementRow{}
+	for rows.Next() {
+		var i ListAccountStatementRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransactionType,
+			&i.RunningBalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, transaction_type, transfer_id FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
@@ -81,6 +178,8 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.TransactionType,
+			&i.TransferID,
 		); err != nil {
 			return nil, err
 		}
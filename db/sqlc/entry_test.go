@@ -11,8 +11,9 @@ import (
 
 func CreateRandomEntry(t *testing.T, account Account) Entry {
 	arg := CreateEntryParams{
-		AccountID: account.ID,
-		Amount:    utils.RandomMoney(),
+		AccountID:       account.ID,
+		Amount:          utils.RandomMoney(),
+		TransactionType: utils.EntryTypeTransfer,
 	}
 
 	entry, err := testQueries.CreateEntry(context.Background(), arg)
@@ -21,6 +22,7 @@ func CreateRandomEntry(t *testing.T, account Account) Entry {
 
 	require.Equal(t, arg.AccountID, entry.AccountID)
 	require.Equal(t, arg.Amount, entry.Amount)
+	require.Equal(t, arg.TransactionType, entry.TransactionType)
 
 	require.NotZero(t, entry.ID)
 	require.NotZero(t, entry.CreatedAt)
@@ -67,3 +69,71 @@ func TestListEntries(t *testing.T) {
 		require.Equal(t, arg.AccountID, entry.AccountID)
 	}
 }
+
+func TestListAccountStatement(t *testing.T) {
+	account := CreateRandomAccount(t)
+
+	var total int64
+	for i := 0; i < 10; i++ {
+		total += CreateRandomEntry(t, account).Amount
+	}
+
+	// a balance changed outside of the entries does not move the running balance
+	fundAccount(t, account, total+1000)
+
+	arg := ListAccountStatementParams{
+		AccountID: account.ID,
+		FromTime:  time.Now().Add(-time.Minute),
+		ToTime:    time.Now().Add(time.Minute),
+		Limit:     10,
+		Offset:    0,
+	}
+
+	statement, err := testQueries.ListAccountStatement(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, statement, 10)
+
+	// the account has no snapshot yet, so the running balance starts from zero
+	require.Equal(t, statement[0].Amount, statement[0].RunningBalance)
+	require.Equal(t, total, statement[len(statement)-1].RunningBalance)
+
+	for i := 1; i < len(statement); i++ {
+		require.Equal(t, arg.AccountID, statement[i].AccountID)
+		require.Equal(t, statement[i-1].RunningBalance+statement[i].Amount, statement[i].RunningBalance)
+	}
+
+	// a later page continues the running balance of the previous one
+	arg.Limit = 5
+	arg.Offset = 5
+
+	page, err := testQueries.ListAccountStatement(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, statement[5:], page)
+}
+
+func TestListAccountStatementFromSnapshot(t *testing.T) {
+	account := CreateRandomAccount(t)
+
+	before := CreateRandomEntry(t, account)
+
+	// the running balance of a later statement starts from the snapshot
+	_, err := testQueries.CreateBalanceSnapshots(context.Background(), before.CreatedAt)
+	require.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+	fromTime := time.Now()
+
+	after := CreateRandomEntry(t, account)
+
+	statement, err := testQueries.ListAccountStatement(context.Background(), ListAccountStatementParams{
+		AccountID: account.ID,
+		FromTime:  fromTime,
+		ToTime:    time.Now().Add(time.Minute),
+		Limit:     10,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, statement, 1)
+	require.Equal(t, after.ID, statement[0].ID)
+	require.Equal(t, before.Amount+after.Amount, statement[0].RunningBalance)
+}
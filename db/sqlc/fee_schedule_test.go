@@ -0,0 +1,213 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"simple_bank/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateFee(t *testing.T) {
+	// a flat fee of 50 up to 999, 1% from 1000 and 25 plus 0.5% from 100000
+	tiers := []FeeTier{
+		{MinAmount: 0, FlatFee: 50},
+		{MinAmount: 1000, BasisPoints: 100},
+		{MinAmount: 100000, FlatFee: 25, BasisPoints: 50},
+	}
+
+	testCases := []struct {
+		name   string
+		tiers  []FeeTier
+		amount int64
+		fee    int64
+	}{
+		{name: "NoTiers", tiers: []FeeTier{}, amount: 500, fee: 0},
+		{name: "BelowEveryTier", tiers: tiers[1:], amount: 999, fee: 0},
+		{name: "Flat", tiers: tiers, amount: 999, fee: 50},
+		{name: "Percentage", tiers: tiers, amount: 1000, fee: 10},
+		{name: "RoundedDown", tiers: tiers, amount: 1049, fee: 10},
+		{name: "RoundedUp", tiers: tiers, amount: 1050, fee: 11},
+		{name: "FlatAndPercentage", tiers: tiers, amount: 100000, fee: 525},
+		{name: "NoOverflow", tiers: tiers, amount: 1 << 60, fee: 5764607523034260},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.fee, calculateFee(tc.tiers, tc.amount))
+		})
+	}
+}
+
+func createRandomFeeSchedule(t *testing.T, q *Queries, currency string, effectiveAt time.Time, tiers ...CreateFeeTierParams) FeeSchedule {
+	feeSchedule, err := q.CreateFeeSchedule(context.Background(), CreateFeeScheduleParams{
+		Currency:     currency,
+		TransferType: utils.EntryTypeTransfer,
+		EffectiveAt:  effectiveAt,
+	})
+	require.NoError(t, err)
+
+	for _, tier := range tiers {
+		tier.FeeScheduleID = feeSchedule.ID
+
+		_, err := q.CreateFeeTier(context.Background(), tier)
+		require.NoError(t, err)
+	}
+
+	return feeSchedule
+}
+
+func TestCreateFeeScheduleTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	// a currency of its own keeps the versions of this test apart from the others
+	currency := strings.ToUpper(utils.RandomString(6))
+
+	arg := CreateFeeScheduleTxParams{
+		CreateFeeScheduleParams: CreateFeeScheduleParams{
+			Currency:     currency,
+			TransferType: utils.EntryTypeWithdrawal,
+			EffectiveAt:  time.Now().Add(-time.Hour).Truncate(time.Microsecond),
+		},
+		Tiers: []CreateFeeTierParams{
+			{MinAmount: 0, FlatFee: 10},
+			{MinAmount: 1000, BasisPoints: 150},
+		},
+	}
+
+	result, err := store.CreateFeeScheduleTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.NotZero(t, result.FeeSchedule.ID)
+	require.Equal(t, currency, result.FeeSchedule.Currency)
+	require.Equal(t, utils.EntryTypeWithdrawal, result.FeeSchedule.TransferType)
+	require.Equal(t, int32(1), result.FeeSchedule.Version)
+	require.WithinDuration(t, arg.EffectiveAt, result.FeeSchedule.EffectiveAt, time.Microsecond)
+
+	require.Len(t, result.Tiers, 2)
+	for i, tier := range result.Tiers {
+		require.Equal(t, result.FeeSchedule.ID, tier.FeeScheduleID)
+		require.Equal(t, arg.Tiers[i].MinAmount, tier.MinAmount)
+		require.Equal(t, arg.Tiers[i].FlatFee, tier.FlatFee)
+		require.Equal(t, arg.Tiers[i].BasisPoints, tier.BasisPoints)
+	}
+
+	// a new version only applies once it is in effect
+	arg.EffectiveAt = time.Now().Add(time.Hour)
+
+	next, err := store.CreateFeeScheduleTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), next.FeeSchedule.Version)
+
+	current, err := testQueries.GetCurrentFeeSchedule(context.Background(), GetCurrentFeeScheduleParams{
+		Currency:     currency,
+		TransferType: utils.EntryTypeWithdrawal,
+	})
+	require.NoError(t, err)
+	require.Equal(t, result.FeeSchedule.ID, current.ID)
+
+	feeSchedules, err := testQueries.ListFeeSchedules(context.Background(), ListFeeSchedulesParams{
+		Currency:     currency,
+		TransferType: utils.EntryTypeWithdrawal,
+		Limit:        5,
+		Offset:       0,
+	})
+	require.NoError(t, err)
+	require.Len(t, feeSchedules, 2)
+
+	// two tiers of the same schedule cannot start at the same amount
+	arg.Tiers = append(arg.Tiers, CreateFeeTierParams{MinAmount: 0, FlatFee: 20})
+
+	_, err = store.CreateFeeScheduleTx(context.Background(), arg)
+	require.Error(t, err)
+}
+
+func TestTransferTxFee(t *testing.T) {
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 2000)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	feeAccount, err := testQueries.GetAccountByCurrency(context.Background(), GetAccountByCurrencyParams{
+		Owner:    utils.FeeAccountOwner,
+		Currency: utils.USD,
+	})
+	require.NoError(t, err)
+
+	// the schedule is rolled back with the transaction, so it never charges the transfers of the other tests
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	q := New(tx)
+
+	feeSchedule := createRandomFeeSchedule(t, q, utils.USD, time.Now().Add(-time.Hour),
+		CreateFeeTierParams{MinAmount: 0, FlatFee: 5},
+		CreateFeeTierParams{MinAmount: 1000, FlatFee: 5, BasisPoints: 100},
+	)
+
+	result, err := transfer(context.Background(), q, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1000,
+		MaxFee:        sql.NullInt64{Int64: 15, Valid: true},
+	})
+	require.NoError(t, err)
+
+	// the fee is charged on top of the amount
+	require.Equal(t, int64(15), result.Transfer.Fee)
+	require.Equal(t, sql.NullInt64{Int64: feeSchedule.ID, Valid: true}, result.Transfer.FeeScheduleID)
+	require.Equal(t, account1.Balance-1015, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+1000, result.ToAccount.Balance)
+
+	require.NotNil(t, result.FeeEntry)
+	require.Equal(t, account1.ID, result.FeeEntry.AccountID)
+	require.Equal(t, int64(-15), result.FeeEntry.Amount)
+	require.Equal(t, utils.EntryTypeFee, result.FeeEntry.TransactionType)
+
+	require.NotNil(t, result.FeeIncomeEntry)
+	require.Equal(t, feeAccount.ID, result.FeeIncomeEntry.AccountID)
+	require.Equal(t, int64(15), result.FeeIncomeEntry.Amount)
+
+	updatedFeeAccount, err := q.GetAccount(context.Background(), feeAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(15), updatedFeeAccount.Balance-feeAccount.Balance)
+
+	// the sender can refuse a fee above what he/she expected
+	_, err = transfer(context.Background(), q, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		MaxFee:        sql.NullInt64{Int64: 4, Valid: true},
+	})
+	require.ErrorIs(t, err, ErrFeeExceedsMax)
+}
+
+func TestTransferTxFeeAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.EUR), 100)
+
+	feeAccount, err := testQueries.GetAccountByCurrency(context.Background(), GetAccountByCurrencyParams{
+		Owner:    utils.FeeAccountOwner,
+		Currency: utils.EUR,
+	})
+	require.NoError(t, err)
+
+	// the fee accounts only receive fees
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   feeAccount.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrFeeAccount)
+
+	_, err = store.WithdrawTx(context.Background(), CashTxParams{
+		AccountID: feeAccount.ID,
+		Amount:    10,
+	})
+	require.ErrorIs(t, err, ErrFeeAccount)
+}
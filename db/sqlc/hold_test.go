@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func placeHold(t *testing.T, store Store, account, toAccount Account, amount int64) Hold {
+	result, err := store.PlaceHoldTx(context.Background(), PlaceHoldTxParams{
+		CreateHoldParams: CreateHoldParams{
+			AccountID:   account.ID,
+			ToAccountID: toAccount.ID,
+			Amount:      amount,
+			ExpiresAt:   time.Now().Add(time.Hour),
+		},
+	})
+	require.NoError(t, err)
+
+	hold := result.Hold
+	require.NotZero(t, hold.ID)
+	require.Equal(t, account.ID, hold.AccountID)
+	require.Equal(t, toAccount.ID, hold.ToAccountID)
+	require.Equal(t, amount, hold.Amount)
+	require.Equal(t, utils.HoldStatusHeld, hold.Status)
+	require.Zero(t, hold.CapturedAmount)
+	require.False(t, hold.TransferID.Valid)
+
+	return hold
+}
+
+func TestPlaceHoldTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	placeHold(t, store, account1, account2, 60)
+
+	heldAmount, err := testQueries.GetAccountHeldAmount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(60), heldAmount)
+
+	// the held funds can neither be held again nor transferred
+	_, err = store.PlaceHoldTx(context.Background(), PlaceHoldTxParams{
+		CreateHoldParams: CreateHoldParams{
+			AccountID:   account1.ID,
+			ToAccountID: account2.ID,
+			Amount:      50,
+			ExpiresAt:   time.Now().Add(time.Hour),
+		},
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        50,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// the rest of the balance is still available
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        40,
+	})
+	require.NoError(t, err)
+}
+
+func TestCaptureHoldTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	hold := placeHold(t, store, account1, account2, 100)
+
+	_, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID: hold.ID,
+		Amount: hold.Amount + 1,
+	})
+	require.ErrorIs(t, err, ErrCaptureExceedsHold)
+
+	// only part of the hold is captured, the rest is released
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID: hold.ID,
+		Amount: 30,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, utils.HoldStatusCaptured, result.Hold.Status)
+	require.Equal(t, int64(30), result.Hold.CapturedAmount)
+	require.Equal(t, sql.NullInt64{Int64: result.Transfer.Transfer.ID, Valid: true}, result.Hold.TransferID)
+	require.Equal(t, account1.Balance-30, result.Transfer.FromAccount.Balance)
+	require.Equal(t, account2.Balance+30, result.Transfer.ToAccount.Balance)
+
+	heldAmount, err := testQueries.GetAccountHeldAmount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, heldAmount)
+
+	// a hold is only captured once
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID: hold.ID,
+		Amount: 30,
+	})
+	require.ErrorIs(t, err, ErrHoldNotActive)
+}
+
+func TestCaptureHoldTxLimits(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 1000)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	setTransferLimit(t, account1, 0, 150, 0)
+
+	// holds only reserve funds, so they can be placed above the limits
+	hold1 := placeHold(t, store, account1, account2, 100)
+	hold2 := placeHold(t, store, account1, account2, 100)
+
+	_, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID: hold1.ID,
+		Amount: hold1.Amount,
+	})
+	require.NoError(t, err)
+
+	// the captures count against the daily limit of the payer
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID: hold2.ID,
+		Amount: hold2.Amount,
+	})
+	require.ErrorIs(t, err, ErrDailyLimitExceeded)
+
+	// the failed capture is rolled back, so the hold can still be captured within the limit
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID: hold2.ID,
+		Amount: 50,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(50), result.Hold.CapturedAmount)
+	require.Equal(t, account1.Balance-150, result.Transfer.FromAccount.Balance)
+}
+
+func TestVoidHold(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	hold := placeHold(t, store, account1, account2, 100)
+
+	voided, err := testQueries.VoidHold(context.Background(), hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, utils.HoldStatusVoided, voided.Status)
+
+	heldAmount, err := testQueries.GetAccountHeldAmount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, heldAmount)
+
+	// a voided hold can neither be voided again nor captured
+	_, err = testQueries.VoidHold(context.Background(), hold.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID: hold.ID,
+		Amount: hold.Amount,
+	})
+	require.ErrorIs(t, err, ErrHoldNotActive)
+}
+
+func TestExpireHolds(t *testing.T) {
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	hold, err := testQueries.CreateHold(context.Background(), CreateHoldParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      100,
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	// an expired hold no longer counts against the funds, even before it is marked as expired
+	heldAmount, err := testQueries.GetAccountHeldAmount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, heldAmount)
+
+	expired, err := testQueries.ExpireHolds(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, expired, int64(1))
+
+	hold, err = testQueries.GetHold(context.Background(), hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, utils.HoldStatusExpired, hold.Status)
+}
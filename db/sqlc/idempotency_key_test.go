@@ -0,0 +1,210 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomIdempotencyKey(t *testing.T, user User, expiresAt time.Time) IdempotencyKey {
+	arg := CreateIdempotencyKeyParams{
+		Username:    user.Username,
+		Key:         utils.RandomString(16),
+		RequestHash: utils.RandomString(32),
+		ExpiresAt:   expiresAt,
+	}
+
+	key, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, key)
+
+	require.Equal(t, arg.Username, key.Username)
+	require.Equal(t, arg.Key, key.Key)
+	require.Equal(t, arg.RequestHash, key.RequestHash)
+	require.Zero(t, key.ResponseStatus)
+	require.WithinDuration(t, arg.ExpiresAt, key.ExpiresAt, time.Second)
+	require.NotZero(t, key.CreatedAt)
+
+	return key
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	user := CreateRandomUser(t)
+	key1 := CreateRandomIdempotencyKey(t, user, time.Now().Add(time.Minute))
+
+	// a live key cannot be claimed twice
+	_, err := testQueries.CreateIdempotencyKey(context.Background(), CreateIdempotencyKeyParams{
+		Username:    key1.Username,
+		Key:         key1.Key,
+		RequestHash: utils.RandomString(32),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestReclaimExpiredIdempotencyKey(t *testing.T) {
+	user := CreateRandomUser(t)
+	key1 := CreateRandomIdempotencyKey(t, user, time.Now().Add(-time.Minute))
+
+	arg := CreateIdempotencyKeyParams{
+		Username:    key1.Username,
+		Key:         key1.Key,
+		RequestHash: utils.RandomString(32),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+
+	key2, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.RequestHash, key2.RequestHash)
+	require.WithinDuration(t, arg.ExpiresAt, key2.ExpiresAt, time.Second)
+}
+
+func TestUpdateIdempotencyKeyResponse(t *testing.T) {
+	user := CreateRandomUser(t)
+	key1 := CreateRandomIdempotencyKey(t, user, time.Now().Add(time.Minute))
+
+	arg := UpdateIdempotencyKeyResponseParams{
+		Username:       key1.Username,
+		Key:            key1.Key,
+		ResponseStatus: 200,
+		ResponseBody:   json.RawMessage(`{"ok": true}`),
+	}
+
+	_, err := testQueries.UpdateIdempotencyKeyResponse(context.Background(), arg)
+	require.NoError(t, err)
+
+	key2, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: key1.Username,
+		Key:      key1.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, arg.ResponseStatus, key2.ResponseStatus)
+	require.JSONEq(t, string(arg.ResponseBody), string(key2.ResponseBody))
+}
+
+func TestDeleteIdempotencyKey(t *testing.T) {
+	user := CreateRandomUser(t)
+	key1 := CreateRandomIdempotencyKey(t, user, time.Now().Add(time.Minute))
+
+	err := testQueries.DeleteIdempotencyKey(context.Background(), DeleteIdempotencyKeyParams{
+		Username: key1.Username,
+		Key:      key1.Key,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: key1.Username,
+		Key:      key1.Key,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestIdempotentTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	arg := IdempotentTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+		},
+		IdempotencyKey: CreateIdempotencyKeyParams{
+			Username:    account1.Owner,
+			Key:         utils.RandomString(16),
+			RequestHash: utils.RandomString(32),
+			ExpiresAt:   time.Now().Add(time.Minute),
+		},
+		ResponseStatus: 200,
+	}
+
+	// concurrent retries of the same request only move the money once
+	n := 5
+	errs := make(chan error)
+	results := make(chan TransferTxResult)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := store.IdempotentTransferTx(context.Background(), arg)
+
+			errs <- err
+			results <- result
+		}()
+	}
+
+	var result TransferTxResult
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+		got := <-results
+
+		if err == nil {
+			require.Zero(t, result.Transfer.ID)
+			result = got
+			continue
+		}
+		require.ErrorIs(t, err, ErrIdempotencyKeyUsed)
+	}
+	require.NotZero(t, result.Transfer.ID)
+
+	// the response is stored along with the transfer
+	key, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: arg.IdempotencyKey.Username,
+		Key:      arg.IdempotencyKey.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, arg.ResponseStatus, key.ResponseStatus)
+
+	body, err := json.Marshal(result)
+	require.NoError(t, err)
+	require.JSONEq(t, string(body), string(key.ResponseBody))
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-arg.Amount, updatedAccount1.Balance)
+}
+
+func TestIdempotentTransferTxFailureFreesKey(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := CreateRandomAccountWithCurrency(t, utils.USD)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	arg := IdempotentTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        account1.Balance + 1,
+		},
+		IdempotencyKey: CreateIdempotencyKeyParams{
+			Username:    account1.Owner,
+			Key:         utils.RandomString(16),
+			RequestHash: utils.RandomString(32),
+			ExpiresAt:   time.Now().Add(time.Minute),
+		},
+		ResponseStatus: 200,
+	}
+
+	_, err := store.IdempotentTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// the claim was rolled back with the transfer, so the request can be retried
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: arg.IdempotencyKey.Username,
+		Key:      arg.IdempotencyKey.Key,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	fundAccount(t, account1, arg.Amount)
+
+	result, err := store.IdempotentTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Amount, result.Transfer.Amount)
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccrueInterestTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	_, err := store.DepositTx(context.Background(), CashTxParams{
+		AccountID: account.ID,
+		Amount:    1000000,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.UpsertAccountInterestRate(context.Background(), UpsertAccountInterestRateParams{
+		AccountID:          account.ID,
+		AnnualRateBps:      3600,
+		DayCountConvention: utils.DayCount30360,
+	})
+	require.NoError(t, err)
+
+	// a day of its own in the future keeps the accruals of this test apart from the others
+	date := time.Date(2100+int(utils.RandomInt(0, 800)), time.March, 15, 0, 0, 0, 0, time.UTC)
+
+	result, err := store.AccrueInterestTx(context.Background(), AccrueInterestTxParams{Date: date})
+	require.NoError(t, err)
+	require.NotZero(t, result.Accruals)
+
+	accruals, err := testQueries.ListInterestAccruals(context.Background(), ListInterestAccrualsParams{
+		AccountID: account.ID,
+		Limit:     5,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, accruals, 1)
+
+	// 36% a year over 360 days is 0.1% a day
+	accrual := accruals[0]
+	require.True(t, date.Equal(accrual.AccrualDate))
+	require.Equal(t, int64(1000000), accrual.Balance)
+	require.Equal(t, int32(3600), accrual.AnnualRateBps)
+	require.Equal(t, utils.DayCount30360, accrual.DayCountConvention)
+	require.Equal(t, int64(1000*utils.MicrosPerUnit), accrual.AmountMicros)
+	require.False(t, accrual.CapitalizationID.Valid)
+
+	// the scheduler catches up from the last accrued day
+	lastAccrued, err := testQueries.GetLastInterestAccrualDate(context.Background())
+	require.NoError(t, err)
+	require.False(t, lastAccrued.Before(date))
+
+	// re-running the day accrues nothing more
+	result, err = store.AccrueInterestTx(context.Background(), AccrueInterestTxParams{Date: date})
+	require.NoError(t, err)
+	require.Zero(t, result.Accruals)
+
+	accruals, err = testQueries.ListInterestAccruals(context.Background(), ListInterestAccrualsParams{
+		AccountID: account.ID,
+		Limit:     5,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, accruals, 1)
+}
+
+func insertInterestAccrual(t *testing.T, account Account, date time.Time, amountMicros int64) {
+	accruals, err := testQueries.CreateInterestAccrual(context.Background(), CreateInterestAccrualParams{
+		AccountID:          account.ID,
+		AccrualDate:        date,
+		Balance:            account.Balance,
+		AnnualRateBps:      100,
+		DayCountConvention: utils.DayCountActual365,
+		AmountMicros:       amountMicros,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), accruals)
+}
+
+func TestCapitalizeInterestTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := CreateRandomAccountWithCurrency(t, utils.EUR)
+
+	interestAccount, err := testQueries.GetAccountByCurrency(context.Background(), GetAccountByCurrencyParams{
+		Owner:    utils.InterestAccountOwner,
+		Currency: utils.EUR,
+	})
+	require.NoError(t, err)
+
+	insertInterestAccrual(t, account, time.Date(2023, time.January, 10, 0, 0, 0, 0, time.UTC), 600000)
+	insertInterestAccrual(t, account, time.Date(2023, time.January, 11, 0, 0, 0, 0, time.UTC), 600000)
+
+	january := time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC)
+
+	result, err := store.CapitalizeInterestTx(context.Background(), CapitalizeInterestTxParams{
+		AccountID: account.ID,
+		PeriodEnd: january,
+	})
+	require.NoError(t, err)
+
+	// a whole minor unit is paid and the rest is carried over
+	require.Equal(t, int64(1200000), result.Capitalization.AccruedMicros)
+	require.Equal(t, int64(1), result.Capitalization.Amount)
+	require.Equal(t, int64(200000), result.Capitalization.CarryMicros)
+
+	require.NotNil(t, result.Transfer)
+	require.Equal(t, sql.NullInt64{Int64: result.Transfer.Transfer.ID, Valid: true}, result.Capitalization.TransferID)
+	require.Equal(t, interestAccount.ID, result.Transfer.FromAccount.ID)
+	require.Equal(t, account.ID, result.Transfer.ToAccount.ID)
+	require.Equal(t, account.Balance+1, result.Transfer.ToAccount.Balance)
+	require.Equal(t, utils.EntryTypeInterest, result.Transfer.FromEntry.TransactionType)
+	require.Equal(t, utils.EntryTypeInterest, result.Transfer.ToEntry.TransactionType)
+
+	accruals, err := testQueries.ListInterestAccruals(context.Background(), ListInterestAccrualsParams{
+		AccountID: account.ID,
+		Limit:     5,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, accruals, 2)
+	for _, accrual := range accruals {
+		require.Equal(t, sql.NullInt64{Int64: result.Capitalization.ID, Valid: true}, accrual.CapitalizationID)
+	}
+
+	// re-running the period pays nothing more
+	_, err = store.CapitalizeInterestTx(context.Background(), CapitalizeInterestTxParams{
+		AccountID: account.ID,
+		PeriodEnd: january,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// the carry is added to the interest of the next period
+	insertInterestAccrual(t, account, time.Date(2023, time.February, 10, 0, 0, 0, 0, time.UTC), 900000)
+
+	result, err = store.CapitalizeInterestTx(context.Background(), CapitalizeInterestTxParams{
+		AccountID: account.ID,
+		PeriodEnd: time.Date(2023, time.February, 28, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1100000), result.Capitalization.AccruedMicros)
+	require.Equal(t, int64(1), result.Capitalization.Amount)
+	require.Equal(t, int64(100000), result.Capitalization.CarryMicros)
+
+	updatedAccount, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+2, updatedAccount.Balance)
+
+	// a period with less than a minor unit is recorded without a transfer
+	result, err = store.CapitalizeInterestTx(context.Background(), CapitalizeInterestTxParams{
+		AccountID: account.ID,
+		PeriodEnd: time.Date(2023, time.March, 31, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.Capitalization.Amount)
+	require.Equal(t, int64(100000), result.Capitalization.CarryMicros)
+	require.Nil(t, result.Transfer)
+	require.False(t, result.Capitalization.TransferID.Valid)
+}
+
+func TestTransferTxInterestAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.KES), 100)
+
+	interestAccount, err := testQueries.GetAccountByCurrency(context.Background(), GetAccountByCurrencyParams{
+		Owner:    utils.InterestAccountOwner,
+		Currency: utils.KES,
+	})
+	require.NoError(t, err)
+
+	// the interest accounts only pay interest
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   interestAccount.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrInterestAccount)
+
+	_, err = store.DepositTx(context.Background(), CashTxParams{
+		AccountID: interestAccount.ID,
+		Amount:    10,
+	})
+	require.ErrorIs(t, err, ErrInterestAccount)
+}
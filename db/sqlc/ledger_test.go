@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"simple_bank/utils"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyLedgerTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	// accounts start with the balance of their entries
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 0)
+	account2 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 0)
+
+	account1, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 100,
+	})
+	require.NoError(t, err)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// every entry of the transfer points to it
+	require.Equal(t, transfer.Transfer.ID, transfer.FromEntry.TransferID.Int64)
+	require.Equal(t, transfer.Transfer.ID, transfer.ToEntry.TransferID.Int64)
+
+	result, err := store.VerifyLedgerTx(context.Background())
+	require.NoError(t, err)
+	require.NotZero(t, result.CheckedAt)
+
+	// the transfer kept the books balanced
+	requireNoAccountMismatch(t, result, account1.ID, account2.ID)
+	requireNoTransferMismatch(t, result, transfer.Transfer.ID)
+
+	// a balance changed outside of a transfer no longer matches its entries
+	_, err = testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account2.ID,
+		Balance: 50,
+	})
+	require.NoError(t, err)
+
+	// and neither does a transfer recorded without its entries
+	loneTransfer, err := testQueries.CreateTransfer(context.Background(), CreateTransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        5,
+		ToAmount:      5,
+		FromCurrency:  utils.USD,
+		ToCurrency:    utils.USD,
+		ExchangeRate:  "1",
+	})
+	require.NoError(t, err)
+
+	// an entry moving money on its own belongs to no transfer
+	loneEntry := CreateRandomEntry(t, account1)
+
+	result, err = store.VerifyLedgerTx(context.Background())
+	require.NoError(t, err)
+	require.False(t, result.Balanced)
+
+	require.Contains(t, result.OrphanEntries, loneEntry)
+
+	for _, entry := range result.OrphanEntries {
+		require.NotEqual(t, transfer.FromEntry.ID, entry.ID)
+		require.NotEqual(t, transfer.ToEntry.ID, entry.ID)
+	}
+
+	requireNoAccountMismatch(t, result, account1.ID)
+	requireNoTransferMismatch(t, result, transfer.Transfer.ID)
+
+	require.Contains(t, result.AccountMismatches, ListAccountBalanceMismatchesRow{
+		ID:           account2.ID,
+		Owner:        account2.Owner,
+		Currency:     account2.Currency,
+		Balance:      50,
+		EntriesTotal: 10,
+	})
+
+	require.Contains(t, result.TransferMismatches, ListUnbalancedTransfersRow{
+		ID:            loneTransfer.ID,
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        5,
+		ToAmount:      5,
+		FromCurrency:  utils.USD,
+		ToCurrency:    utils.USD,
+	})
+}
+
+func requireNoAccountMismatch(t *testing.T, result VerifyLedgerTxResult, accountIDs ...int64) {
+	for _, mismatch := range result.AccountMismatches {
+		require.NotContains(t, accountIDs, mismatch.ID)
+	}
+}
+
+func requireNoTransferMismatch(t *testing.T, result VerifyLedgerTxResult, transferIDs ...int64) {
+	for _, mismatch := range result.TransferMismatches {
+		require.NotContains(t, transferIDs, mismatch.ID)
+	}
+}
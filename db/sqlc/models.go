@@ -5,6 +5,8 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -37,6 +39,49 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// how far below zero the balance may go
+	OverdraftLimit int64 `json:"overdraft_limit"`
+	// frozen and closed accounts can neither send nor receive money
+	Status string `json:"status"`
+}
+
+type AccountInterestRate struct {
+	AccountID int64 `json:"account_id"`
+	// annual interest rate, in hundredths of a percent
+	AnnualRateBps int32 `json:"annual_rate_bps"`
+	// how the annual rate is split into daily rates
+	DayCountConvention string    `json:"day_count_convention"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+type AuditLog struct {
+	ID int64 `json:"id"`
+	// authenticated username, or the username given to a login, empty when unknown
+	Actor     string `json:"actor"`
+	ActorRole string `json:"actor_role"`
+	// session of the access token or of the refresh token the call was made with
+	SessionID uuid.NullUUID `json:"session_id"`
+	ClientIp  string        `json:"client_ip"`
+	Method    string        `json:"method"`
+	// route template of the call, like /accounts/:id
+	Route string `json:"route"`
+	// uri parameters and ids of the request body the call acted on
+	TargetIds json.RawMessage `json:"target_ids"`
+	// request body with secrets redacted
+	RequestSummary json.RawMessage `json:"request_summary"`
+	// http status of the response
+	Status    int32     `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BalanceSnapshot struct {
+	ID        int64 `json:"id"`
+	AccountID int64 `json:"account_id"`
+	// sum of the entries of the account created up to taken_at
+	Balance int64 `json:"balance"`
+	// the end of the day the snapshot was taken for
+	TakenAt   time.Time `json:"taken_at"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Entry struct {
@@ -45,6 +90,123 @@ type Entry struct {
 	// can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// kind of money movement that posted the entry
+	TransactionType string `json:"transaction_type"`
+	// transfer that posted the entry, along with its fee
+	TransferID sql.NullInt64 `json:"transfer_id"`
+}
+
+type FeeSchedule struct {
+	ID       int64  `json:"id"`
+	Currency string `json:"currency"`
+	// entry type of the transfers charged by the schedule
+	TransferType string `json:"transfer_type"`
+	// increases with every new schedule of the same currency and transfer type
+	Version     int32     `json:"version"`
+	EffectiveAt time.Time `json:"effective_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type FeeTier struct {
+	ID            int64 `json:"id"`
+	FeeScheduleID int64 `json:"fee_schedule_id"`
+	// smallest amount the tier applies to, up to the next tier
+	MinAmount int64 `json:"min_amount"`
+	FlatFee   int64 `json:"flat_fee"`
+	// percentage of the amount charged, in hundredths of a percent
+	BasisPoints int32 `json:"basis_points"`
+}
+
+type Hold struct {
+	ID int64 `json:"id"`
+	// the account the funds are reserved on
+	AccountID int64 `json:"account_id"`
+	// the account the funds go to when the hold is captured
+	ToAccountID int64 `json:"to_account_id"`
+	// must be positive
+	Amount         int64  `json:"amount"`
+	CapturedAmount int64  `json:"captured_amount"`
+	Status         string `json:"status"`
+	// only set once the hold is captured
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type IdempotencyKey struct {
+	Username    string `json:"username"`
+	Key         string `json:"key"`
+	RequestHash string `json:"request_hash"`
+	// zero while the request is still in progress
+	ResponseStatus int32           `json:"response_status"`
+	ResponseBody   json.RawMessage `json:"response_body"`
+	ExpiresAt      time.Time       `json:"expires_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type InterestAccrual struct {
+	ID          int64     `json:"id"`
+	AccountID   int64     `json:"account_id"`
+	AccrualDate time.Time `json:"accrual_date"`
+	// balance at the end of the accrual date
+	Balance            int64  `json:"balance"`
+	AnnualRateBps      int32  `json:"annual_rate_bps"`
+	DayCountConvention string `json:"day_count_convention"`
+	// interest earned on the day, in millionths of the currency minor unit
+	AmountMicros int64 `json:"amount_micros"`
+	// capitalization that paid the accrual out, unset until then
+	CapitalizationID sql.NullInt64 `json:"capitalization_id"`
+	CreatedAt        time.Time     `json:"created_at"`
+}
+
+type InterestCapitalization struct {
+	ID        int64     `json:"id"`
+	AccountID int64     `json:"account_id"`
+	PeriodEnd time.Time `json:"period_end"`
+	// accruals of the period plus the carry of the previous capitalization
+	AccruedMicros int64 `json:"accrued_micros"`
+	Amount        int64 `json:"amount"`
+	// fraction of a minor unit left over, carried to the next capitalization
+	CarryMicros int64         `json:"carry_micros"`
+	TransferID  sql.NullInt64 `json:"transfer_id"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type ResetPassword struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha256 hash of the code sent by email
+	SecretCode string    `json:"secret_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	// must be positive
+	Amount int64 `json:"amount"`
+	// cron expression, empty for a one-off transfer
+	Recurrence string    `json:"recurrence"`
+	Status     string    `json:"status"`
+	NextRunAt  time.Time `json:"next_run_at"`
+	// failed attempts of the current run
+	Attempts  int32     `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64 `json:"id"`
+	ScheduledTransferID int64 `json:"scheduled_transfer_id"`
+	// only set when the run succeeded
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	Attempt    int32         `json:"attempt"`
+	Succeeded  bool          `json:"succeeded"`
+	Error      string        `json:"error"`
+	CreatedAt  time.Time     `json:"created_at"`
 }
 
 type Session struct {
@@ -56,6 +218,10 @@ type Session struct {
 	IsBlocked    bool      `json:"is_blocked"`
 	ExpiresAt    time.Time `json:"expires_at"`
 	CreatedAt    time.Time `json:"created_at"`
+	// id of the first session of a chain of rotated refresh tokens
+	FamilyID uuid.UUID `json:"family_id"`
+	// set once the refresh token has been exchanged for a new one
+	RotatedAt sql.NullTime `json:"rotated_at"`
 }
 
 type Transfer struct {
@@ -65,6 +231,32 @@ type Transfer struct {
 	// must be positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// amount credited, in to_currency
+	ToAmount     int64  `json:"to_amount"`
+	FromCurrency string `json:"from_currency"`
+	ToCurrency   string `json:"to_currency"`
+	// units of to_currency bought by one unit of from_currency
+	ExchangeRate string `json:"exchange_rate"`
+	// transfer refunded by this reversal
+	ReversalOf sql.NullInt64 `json:"reversal_of"`
+	// running total refunded by reversals, in from_currency
+	ReversedAmount int64 `json:"reversed_amount"`
+	// set once the whole amount is refunded
+	IsReversed bool `json:"is_reversed"`
+	// charged to the sender on top of the amount, in from_currency
+	Fee int64 `json:"fee"`
+	// schedule the fee was computed with
+	FeeScheduleID sql.NullInt64 `json:"fee_schedule_id"`
+}
+
+type TransferLimit struct {
+	Tier                string `json:"tier"`
+	Currency            string `json:"currency"`
+	PerTransactionLimit int64  `json:"per_transaction_limit"`
+	// total of the outbound transfers of a user in the currency per UTC day
+	DailyLimit int64 `json:"daily_limit"`
+	// total of the outbound transfers of a user in the currency per UTC month
+	MonthlyLimit int64 `json:"monthly_limit"`
 }
 
 type User struct {
@@ -74,4 +266,40 @@ type User struct {
 	Email            string    `json:"email"`
 	PasswordChangeAt time.Time `json:"password_change_at"`
 	CreatedAt        time.Time `json:"created_at"`
+	Role             string    `json:"role"`
+	IsEmailVerified  bool      `json:"is_email_verified"`
+	LimitTier        string    `json:"limit_tier"`
+	// access tokens issued before the last role change still carry the old role
+	RoleChangeAt time.Time `json:"role_change_at"`
+}
+
+type UserHistory struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// the user who made the change, the user himself/herself or an admin
+	ChangedBy string    `json:"changed_by"`
+	Field     string    `json:"field"`
+	OldValue  string    `json:"old_value"`
+	NewValue  string    `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserTransferLimit struct {
+	Username            string        `json:"username"`
+	Currency            string        `json:"currency"`
+	PerTransactionLimit sql.NullInt64 `json:"per_transaction_limit"`
+	DailyLimit          sql.NullInt64 `json:"daily_limit"`
+	MonthlyLimit        sql.NullInt64 `json:"monthly_limit"`
+	UpdatedAt           time.Time     `json:"updated_at"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	// sha256 hash of the code sent by email
+	SecretCode string    `json:"secret_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
 }
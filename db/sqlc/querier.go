@@ -6,28 +6,131 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	// the transfer is marked as reversed once its whole amount is refunded
+	AddTransferReversedAmount(ctx context.Context, arg AddTransferReversedAmountParams) (Transfer, error)
+	BlockOtherSessions(ctx context.Context, arg BlockOtherSessionsParams) (int64, error)
+	BlockSession(ctx context.Context, arg BlockSessionParams) (Session, error)
+	BlockSessionFamily(ctx context.Context, familyID uuid.UUID) (int64, error)
+	BlockUserSessions(ctx context.Context, username string) (int64, error)
+	CancelScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	CapitalizeInterestAccruals(ctx context.Context, arg CapitalizeInterestAccrualsParams) (int64, error)
+	CaptureHold(ctx context.Context, arg CaptureHoldParams) (Hold, error)
+	ClaimDueScheduledTransfer(ctx context.Context, now time.Time) (ScheduledTransfer, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error)
+	// every snapshot continues from the previous snapshot of its account,
+	// a day that was already snapshotted is left untouched
+	CreateBalanceSnapshots(ctx context.Context, takenAt time.Time) (int64, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	// the schedule gets the next version of its currency and transfer type
+	CreateFeeSchedule(ctx context.Context, arg CreateFeeScheduleParams) (FeeSchedule, error)
+	CreateFeeTier(ctx context.Context, arg CreateFeeTierParams) (FeeTier, error)
+	CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error)
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	// a day that was already accrued is left untouched
+	CreateInterestAccrual(ctx context.Context, arg CreateInterestAccrualParams) (int64, error)
+	// returns no rows when the period was already capitalized
+	CreateInterestCapitalization(ctx context.Context, arg CreateInterestCapitalizationParams) (InterestCapitalization, error)
+	CreateResetPassword(ctx context.Context, arg CreateResetPasswordParams) (ResetPassword, error)
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateUserHistory(ctx context.Context, arg CreateUserHistoryParams) (UserHistory, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteIdempotencyKey(ctx context.Context, arg DeleteIdempotencyKeyParams) error
+	ExpireHolds(ctx context.Context) (int64, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	// the balance of the last snapshot taken at or before the given time
+	// plus the entries created after that snapshot, up to the given time
+	GetAccountBalanceAt(ctx context.Context, arg GetAccountBalanceAtParams) (int64, error)
+	// an owner has at most one open account per currency
+	GetAccountByCurrency(ctx context.Context, arg GetAccountByCurrencyParams) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAccountHeldAmount(ctx context.Context, accountID int64) (int64, error)
+	GetAccountInterestRate(ctx context.Context, accountID int64) (AccountInterestRate, error)
+	// the latest version already in effect applies
+	GetCurrentFeeSchedule(ctx context.Context, arg GetCurrentFeeScheduleParams) (FeeSchedule, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetFeeSchedule(ctx context.Context, id int64) (FeeSchedule, error)
+	GetHold(ctx context.Context, id int64) (Hold, error)
+	GetHoldForUpdate(ctx context.Context, id int64) (Hold, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	// the fraction of a minor unit left over by the last capitalization of the account
+	GetInterestCarry(ctx context.Context, accountID int64) (int64, error)
+	// returns no rows when no interest was ever accrued
+	GetLastInterestAccrualDate(ctx context.Context) (time.Time, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
 	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
+	// the limits of a user in a currency, the limits of the user tier unless they are overridden,
+	// along with the outbound transfers of the user since the start of the UTC day and month
+	GetTransferLimit(ctx context.Context, arg GetTransferLimitParams) (GetTransferLimitRow, error)
+	GetUncapitalizedInterest(ctx context.Context, arg GetUncapitalizedInterestParams) (int64, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	// the access tokens issued before the last password or role change are no longer valid
+	GetUserAuthChangeAt(ctx context.Context, username string) (GetUserAuthChangeAtRow, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserForUpdate(ctx context.Context, username string) (User, error)
+	ListAccountBalanceMismatches(ctx context.Context) ([]ListAccountBalanceMismatchesRow, error)
+	// the running balance adds up the entries from the last balance snapshot taken before the statement,
+	// so only the entries since that snapshot are read and the account balance is never trusted
+	ListAccountStatement(ctx context.Context, arg ListAccountStatementParams) ([]ListAccountStatementRow, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAccountsToCapitalize(ctx context.Context, periodEnd time.Time) ([]int64, error)
+	// the rates of the active accounts that already existed at the end of the day
+	ListAccruingInterestRates(ctx context.Context, dayEnd time.Time) ([]AccountInterestRate, error)
+	// the most recent audit logs first, a filter left unset matches every log
+	ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListFeeSchedules(ctx context.Context, arg ListFeeSchedulesParams) ([]FeeSchedule, error)
+	ListFeeTiers(ctx context.Context, feeScheduleID int64) ([]FeeTier, error)
+	ListHolds(ctx context.Context, arg ListHoldsParams) ([]Hold, error)
+	ListInterestAccruals(ctx context.Context, arg ListInterestAccrualsParams) ([]InterestAccrual, error)
+	// every entry is posted by a transfer, the ones without a transfer moved money on their own
+	ListOrphanEntries(ctx context.Context) ([]Entry, error)
+	ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error)
+	ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error)
+	ListSessions(ctx context.Context, username string) ([]Session, error)
+	// the limits of a user in every currency, along with the outbound transfers of the user
+	// since the start of the UTC day and month
+	ListTransferLimits(ctx context.Context, username string) ([]ListTransferLimitsRow, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	// the fee entries posted along with a transfer are not part of its amount
+	ListUnbalancedTransfers(ctx context.Context) ([]ListUnbalancedTransfersRow, error)
+	ListUserHistories(ctx context.Context, arg ListUserHistoriesParams) ([]UserHistory, error)
+	ListUserTransfers(ctx context.Context, arg ListUserTransfersParams) ([]Transfer, error)
+	RescheduleScheduledTransfer(ctx context.Context, arg RescheduleScheduledTransferParams) (ScheduledTransfer, error)
+	RotateSession(ctx context.Context, id uuid.UUID) (Session, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error)
+	UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error)
+	UpdateHoldTransfer(ctx context.Context, arg UpdateHoldTransferParams) (Hold, error)
+	UpdateIdempotencyKeyResponse(ctx context.Context, arg UpdateIdempotencyKeyResponseParams) (IdempotencyKey, error)
+	UpdateInterestCapitalizationTransfer(ctx context.Context, arg UpdateInterestCapitalizationTransferParams) (InterestCapitalization, error)
+	UpdateResetPassword(ctx context.Context, arg UpdateResetPasswordParams) (ResetPassword, error)
+	UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error)
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserLimitTier(ctx context.Context, arg UpdateUserLimitTierParams) (User, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpdateVerifyEmail(ctx context.Context, arg UpdateVerifyEmailParams) (VerifyEmail, error)
+	UpsertAccountInterestRate(ctx context.Context, arg UpsertAccountInterestRateParams) (AccountInterestRate, error)
+	// a limit left unset falls back to the limit of the user tier
+	UpsertUserTransferLimit(ctx context.Context, arg UpsertUserTransferLimitParams) (UserTransferLimit, error)
+	UseUserResetPasswords(ctx context.Context, username string) (int64, error)
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
+	VoidHold(ctx context.Context, id int64) (Hold, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangePasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := CreateRandomUser(t)
+	for i := 0; i < 2; i++ {
+		CreateRandomSession(t, user)
+	}
+
+	hashedPassword, err := utils.HashPassword(utils.RandomString(8))
+	require.NoError(t, err)
+
+	result, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		Username:      user.Username,
+		HarshPassword: hashedPassword,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, hashedPassword, result.User.HarshPassword)
+	require.True(t, result.User.PasswordChangeAt.After(user.PasswordChangeAt))
+	require.Equal(t, int64(2), result.BlockedSessions)
+
+	authChangeAt, err := testQueries.GetUserAuthChangeAt(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.WithinDuration(t, result.User.PasswordChangeAt, authChangeAt.PasswordChangeAt, time.Microsecond)
+
+	sessions, err := testQueries.ListSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
+
+func TestCreateResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+
+	arg := CreateResetPasswordTxParams{
+		CreateResetPasswordParams: CreateResetPasswordParams{
+			Username:   user.Username,
+			SecretCode: utils.RandomString(64),
+			ExpiredAt:  time.Now().Add(time.Hour),
+		},
+	}
+
+	first, err := store.CreateResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, first.UsedResetPasswords)
+	require.False(t, first.ResetPassword.IsUsed)
+
+	arg.SecretCode = utils.RandomString(64)
+
+	second, err := store.CreateResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), second.UsedResetPasswords)
+	require.False(t, second.ResetPassword.IsUsed)
+
+	hashedPassword, err := utils.HashPassword(utils.RandomString(8))
+	require.NoError(t, err)
+
+	// the code of the first email no longer resets the password
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		ResetID:       first.ResetPassword.ID,
+		SecretCode:    first.ResetPassword.SecretCode,
+		HarshPassword: hashedPassword,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		ResetID:       second.ResetPassword.ID,
+		SecretCode:    second.ResetPassword.SecretCode,
+		HarshPassword: hashedPassword,
+	})
+	require.NoError(t, err)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := CreateRandomUser(t)
+	CreateRandomSession(t, user)
+
+	resetPassword, err := testQueries.CreateResetPassword(context.Background(), CreateResetPasswordParams{
+		Username:   user.Username,
+		SecretCode: utils.RandomString(64),
+		ExpiredAt:  time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.False(t, resetPassword.IsUsed)
+
+	hashedPassword, err := utils.HashPassword(utils.RandomString(8))
+	require.NoError(t, err)
+
+	// a wrong code is rejected
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		ResetID:       resetPassword.ID,
+		SecretCode:    utils.RandomString(64),
+		HarshPassword: hashedPassword,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	arg := ResetPasswordTxParams{
+		ResetID:       resetPassword.ID,
+		SecretCode:    resetPassword.SecretCode,
+		HarshPassword: hashedPassword,
+	}
+
+	result, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.ResetPassword.IsUsed)
+	require.Equal(t, user.Username, result.User.Username)
+	require.Equal(t, hashedPassword, result.User.HarshPassword)
+	require.Equal(t, int64(1), result.BlockedSessions)
+
+	// the code can only be used once
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestResetPasswordTxUsesOtherCodes(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+
+	resetPasswords := make([]ResetPassword, 2)
+	for i := range resetPasswords {
+		// not through CreateResetPasswordTx, so both codes stay valid until the reset
+		resetPassword, err := testQueries.CreateResetPassword(context.Background(), CreateResetPasswordParams{
+			Username:   user.Username,
+			SecretCode: utils.RandomString(64),
+			ExpiredAt:  time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+
+		resetPasswords[i] = resetPassword
+	}
+
+	hashedPassword, err := utils.HashPassword(utils.RandomString(8))
+	require.NoError(t, err)
+
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		ResetID:       resetPasswords[1].ID,
+		SecretCode:    resetPasswords[1].SecretCode,
+		HarshPassword: hashedPassword,
+	})
+	require.NoError(t, err)
+
+	// the other code can't change the new password
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		ResetID:       resetPasswords[0].ID,
+		SecretCode:    resetPasswords[0].SecretCode,
+		HarshPassword: user.HarshPassword,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestResetPasswordTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+
+	resetPassword, err := testQueries.CreateResetPassword(context.Background(), CreateResetPasswordParams{
+		Username:   user.Username,
+		SecretCode: utils.RandomString(64),
+		ExpiredAt:  time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		ResetID:       resetPassword.ID,
+		SecretCode:    resetPassword.SecretCode,
+		HarshPassword: user.HarshPassword,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
@@ -0,0 +1,270 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// longAgo is far enough in the past that only the transfers of the running test are due at it
+func longAgo() time.Time {
+	return time.Now().AddDate(-100, 0, 0).Truncate(time.Microsecond)
+}
+
+func createRandomScheduledTransfer(t *testing.T, fromAccount, toAccount Account, recurrence string, nextRunAt time.Time) ScheduledTransfer {
+	arg := CreateScheduledTransferParams{
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+		Recurrence:    recurrence,
+		NextRunAt:     nextRunAt,
+	}
+
+	scheduledTransfer, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.NotZero(t, scheduledTransfer.ID)
+	require.Equal(t, arg.Owner, scheduledTransfer.Owner)
+	require.Equal(t, arg.FromAccountID, scheduledTransfer.FromAccountID)
+	require.Equal(t, arg.ToAccountID, scheduledTransfer.ToAccountID)
+	require.Equal(t, arg.Amount, scheduledTransfer.Amount)
+	require.Equal(t, arg.Recurrence, scheduledTransfer.Recurrence)
+	require.Equal(t, utils.ScheduledTransferStatusActive, scheduledTransfer.Status)
+	require.WithinDuration(t, arg.NextRunAt, scheduledTransfer.NextRunAt, time.Microsecond)
+	require.Zero(t, scheduledTransfer.Attempts)
+	require.NotZero(t, scheduledTransfer.CreatedAt)
+
+	return scheduledTransfer
+}
+
+func TestUpdateScheduledTransfer(t *testing.T) {
+	account1 := CreateRandomAccountWithCurrency(t, utils.USD)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+	scheduledTransfer := createRandomScheduledTransfer(t, account1, account2, "", time.Now().Add(time.Hour))
+
+	updated, err := testQueries.UpdateScheduledTransfer(context.Background(), UpdateScheduledTransferParams{
+		ID:         scheduledTransfer.ID,
+		Amount:     sql.NullInt64{Int64: 20, Valid: true},
+		Recurrence: sql.NullString{String: "@daily", Valid: true},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(20), updated.Amount)
+	require.Equal(t, "@daily", updated.Recurrence)
+	require.WithinDuration(t, scheduledTransfer.NextRunAt, updated.NextRunAt, time.Microsecond)
+
+	cancelled, err := testQueries.CancelScheduledTransfer(context.Background(), scheduledTransfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, utils.ScheduledTransferStatusCancelled, cancelled.Status)
+
+	// a cancelled transfer can neither be updated nor cancelled again
+	_, err = testQueries.UpdateScheduledTransfer(context.Background(), UpdateScheduledTransferParams{
+		ID:     scheduledTransfer.ID,
+		Amount: sql.NullInt64{Int64: 30, Valid: true},
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = testQueries.CancelScheduledTransfer(context.Background(), scheduledTransfer.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestListScheduledTransfers(t *testing.T) {
+	account1 := CreateRandomAccountWithCurrency(t, utils.USD)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	for i := 0; i < 3; i++ {
+		createRandomScheduledTransfer(t, account1, account2, "@monthly", time.Now().Add(time.Hour))
+	}
+
+	scheduledTransfers, err := testQueries.ListScheduledTransfers(context.Background(), ListScheduledTransfersParams{
+		Owner:  account1.Owner,
+		Limit:  2,
+		Offset: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, scheduledTransfers, 2)
+
+	for _, scheduledTransfer := range scheduledTransfers {
+		require.Equal(t, account1.Owner, scheduledTransfer.Owner)
+	}
+}
+
+func TestExecuteScheduledTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	now := longAgo()
+	scheduledTransfer := createRandomScheduledTransfer(t, account1, account2, "", now)
+
+	result, err := store.ExecuteScheduledTransferTx(context.Background(), ExecuteScheduledTransferTxParams{
+		Now:         now,
+		MaxAttempts: 3,
+		RetryDelay:  time.Minute,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, scheduledTransfer.ID, result.ScheduledTransfer.ID)
+	require.Equal(t, utils.ScheduledTransferStatusCompleted, result.ScheduledTransfer.Status)
+
+	require.True(t, result.Run.Succeeded)
+	require.Equal(t, int32(1), result.Run.Attempt)
+	require.Empty(t, result.Run.Error)
+
+	require.NotNil(t, result.Transfer)
+	require.Equal(t, sql.NullInt64{Int64: result.Transfer.Transfer.ID, Valid: true}, result.Run.TransferID)
+	require.Equal(t, account1.Balance-scheduledTransfer.Amount, result.Transfer.FromAccount.Balance)
+	require.Equal(t, account2.Balance+scheduledTransfer.Amount, result.Transfer.ToAccount.Balance)
+
+	// a completed transfer is never run again
+	_, err = store.ExecuteScheduledTransferTx(context.Background(), ExecuteScheduledTransferTxParams{
+		Now:         now,
+		MaxAttempts: 3,
+		RetryDelay:  time.Minute,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestExecuteScheduledTransferTxRetry(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 0)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	now := longAgo()
+	scheduledTransfer := createRandomScheduledTransfer(t, account1, account2, "", now)
+
+	arg := ExecuteScheduledTransferTxParams{
+		Now:         now,
+		MaxAttempts: 2,
+		RetryDelay:  time.Minute,
+	}
+
+	result, err := store.ExecuteScheduledTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.False(t, result.Run.Succeeded)
+	require.Equal(t, int32(1), result.Run.Attempt)
+	require.Equal(t, ErrInsufficientFunds.Error(), result.Run.Error)
+	require.False(t, result.Run.TransferID.Valid)
+	require.Nil(t, result.Transfer)
+
+	// the failed attempt is retried after the retry delay
+	require.Equal(t, utils.ScheduledTransferStatusActive, result.ScheduledTransfer.Status)
+	require.Equal(t, int32(1), result.ScheduledTransfer.Attempts)
+	require.WithinDuration(t, now.Add(arg.RetryDelay), result.ScheduledTransfer.NextRunAt, time.Microsecond)
+
+	_, err = store.ExecuteScheduledTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	arg.Now = result.ScheduledTransfer.NextRunAt
+
+	result, err = store.ExecuteScheduledTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.False(t, result.Run.Succeeded)
+	require.Equal(t, int32(2), result.Run.Attempt)
+	require.Equal(t, utils.ScheduledTransferStatusFailed, result.ScheduledTransfer.Status)
+
+	// the failed transfers left no trace
+	account, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, account.Balance)
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		Limit:               5,
+		Offset:              0,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+}
+
+func TestExecuteScheduledTransferTxRecurring(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	now := longAgo()
+	scheduledTransfer := createRandomScheduledTransfer(t, account1, account2, "@daily", now)
+
+	nextRunAt := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+
+	result, err := store.ExecuteScheduledTransferTx(context.Background(), ExecuteScheduledTransferTxParams{
+		Now:         now,
+		MaxAttempts: 3,
+		RetryDelay:  time.Minute,
+		NextRunAt: func(recurrence string, after time.Time) (time.Time, error) {
+			require.Equal(t, scheduledTransfer.Recurrence, recurrence)
+			require.Equal(t, now, after)
+			return nextRunAt, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.True(t, result.Run.Succeeded)
+	require.Equal(t, utils.ScheduledTransferStatusActive, result.ScheduledTransfer.Status)
+	require.Zero(t, result.ScheduledTransfer.Attempts)
+	require.WithinDuration(t, nextRunAt, result.ScheduledTransfer.NextRunAt, time.Microsecond)
+
+	_, err = testQueries.CancelScheduledTransfer(context.Background(), scheduledTransfer.ID)
+	require.NoError(t, err)
+}
+
+func TestExecuteScheduledTransferTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	now := longAgo()
+	scheduledTransfer := createRandomScheduledTransfer(t, account1, account2, "", now)
+
+	// several schedulers look for due transfers at the same time
+	n := 5
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.ExecuteScheduledTransferTx(context.Background(), ExecuteScheduledTransferTxParams{
+				Now:         now,
+				MaxAttempts: 3,
+				RetryDelay:  time.Minute,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	}
+
+	require.Equal(t, 1, succeeded)
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		Limit:               5,
+		Offset:              0,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+
+	account, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-scheduledTransfer.Amount, account.Balance)
+}
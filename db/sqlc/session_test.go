@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomSession(t *testing.T, user User) Session {
+	id := uuid.New()
+
+	arg := CreateSessionParams{
+		ID:           id,
+		Username:     user.Username,
+		RefreshToken: utils.RandomString(32),
+		UserAgent:    utils.RandomString(10),
+		ClientIp:     "127.0.0.1",
+		IsBlocked:    false,
+		ExpiresAt:    time.Now().Add(time.Hour),
+		FamilyID:     id,
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.Username, session.Username)
+	require.Equal(t, arg.FamilyID, session.FamilyID)
+	require.False(t, session.IsBlocked)
+
+	return session
+}
+
+func TestListSessions(t *testing.T) {
+	user := CreateRandomUser(t)
+
+	for i := 0; i < 3; i++ {
+		CreateRandomSession(t, user)
+	}
+
+	blocked := CreateRandomSession(t, user)
+	_, err := testQueries.BlockSession(context.Background(), BlockSessionParams{
+		ID:       blocked.ID,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+
+	sessions, err := testQueries.ListSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+
+	for _, session := range sessions {
+		require.Equal(t, user.Username, session.Username)
+		require.False(t, session.IsBlocked)
+	}
+}
+
+func TestBlockSession(t *testing.T) {
+	user := CreateRandomUser(t)
+	session1 := CreateRandomSession(t, user)
+
+	// a session can only be blocked by its owner
+	_, err := testQueries.BlockSession(context.Background(), BlockSessionParams{
+		ID:       session1.ID,
+		Username: CreateRandomUser(t).Username,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	session2, err := testQueries.BlockSession(context.Background(), BlockSessionParams{
+		ID:       session1.ID,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, session1.ID, session2.ID)
+	require.True(t, session2.IsBlocked)
+}
+
+func TestBlockOtherSessions(t *testing.T) {
+	user := CreateRandomUser(t)
+	current := CreateRandomSession(t, user)
+
+	for i := 0; i < 3; i++ {
+		CreateRandomSession(t, user)
+	}
+
+	revoked, err := testQueries.BlockOtherSessions(context.Background(), BlockOtherSessionsParams{
+		Username: user.Username,
+		FamilyID: current.FamilyID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), revoked)
+
+	sessions, err := testQueries.ListSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, current.ID, sessions[0].ID)
+}
+
+func TestRotateSessionTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := CreateRandomUser(t)
+	session1 := CreateRandomSession(t, user)
+
+	arg := RotateSessionTxParams{
+		SessionID: session1.ID,
+		NewSession: CreateSessionParams{
+			ID:           uuid.New(),
+			Username:     user.Username,
+			RefreshToken: utils.RandomString(32),
+			UserAgent:    session1.UserAgent,
+			ClientIp:     session1.ClientIp,
+			ExpiresAt:    time.Now().Add(time.Hour),
+		},
+	}
+
+	result, err := store.RotateSessionTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, session1.ID, result.OldSession.ID)
+	require.True(t, result.OldSession.RotatedAt.Valid)
+
+	// the new session joins the family of the rotated one
+	require.Equal(t, arg.NewSession.ID, result.NewSession.ID)
+	require.Equal(t, session1.FamilyID, result.NewSession.FamilyID)
+	require.False(t, result.NewSession.RotatedAt.Valid)
+
+	// a session can only be rotated once
+	arg.NewSession.ID = uuid.New()
+	_, err = store.RotateSessionTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrSessionReused)
+
+	// blocking the family blocks every session of the chain
+	blocked, err := testQueries.BlockSessionFamily(context.Background(), session1.FamilyID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), blocked)
+
+	sessions, err := testQueries.ListSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
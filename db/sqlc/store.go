@@ -3,15 +3,83 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"simple_bank/utils"
+	"time"
 
 	_ "github.com/golang/mock/mockgen/model"
+	"github.com/google/uuid"
+)
+
+// Different types of error returned by the TransferTx function
+var (
+	// ErrInsufficientFunds is returned when a transfer would take the sender below its overdraft limit
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrCurrencyMismatch is returned when the accounts currencies differ and no exchange rate is given
+	ErrCurrencyMismatch = errors.New("accounts currency mismatch")
+	// ErrIdempotencyKeyUsed is returned by IdempotentTransferTx when the idempotency key already holds a response
+	ErrIdempotencyKeyUsed = errors.New("idempotency key was already used")
+	// ErrSessionReused is returned by RotateSessionTx when the session was already rotated or blocked
+	ErrSessionReused = errors.New("refresh token has already been used")
+	// ErrAccountNotActive is returned when money would move from or to a frozen or closed account
+	ErrAccountNotActive = errors.New("account is not active")
+	// ErrAccountNotEmpty is returned by CloseAccountTx when the balance is not zero and cannot be swept
+	// or when funds are still held on the account
+	ErrAccountNotEmpty = errors.New("account balance must be zero to close it")
+	// ErrSweepAccountOwner is returned by CloseAccountTx when the sweep account belongs to another owner
+	ErrSweepAccountOwner = errors.New("sweep account must belong to the owner of the closed account")
+	// ErrHoldNotActive is returned by CaptureHoldTx when the hold was already captured, voided or has expired
+	ErrHoldNotActive = errors.New("hold is not active")
+	// ErrCaptureExceedsHold is returned by CaptureHoldTx when the captured amount is not positive or above the held amount
+	ErrCaptureExceedsHold = errors.New("captured amount must be positive and not above the held amount")
+	// ErrTransferNotReversible is returned by ReverseTransferTx when the transfer is itself a reversal
+	ErrTransferNotReversible = errors.New("a reversal cannot be reversed")
+	// ErrTransferAlreadyReversed is returned by ReverseTransferTx when the whole transfer was already refunded
+	ErrTransferAlreadyReversed = errors.New("transfer is already reversed")
+	// ErrInvalidReversalAmount is returned by ReverseTransferTx when the amount is not positive or above what is left to refund
+	ErrInvalidReversalAmount = errors.New("reversed amount must be positive and not above the amount left to reverse")
+	// ErrCashAccount is returned when a cash account of the bank would take part in a plain transfer
+	ErrCashAccount = errors.New("cash accounts only take part in deposits and withdrawals")
+	// ErrFeeAccount is returned when a fee account of the bank would send or receive anything but fees
+	ErrFeeAccount = errors.New("fee accounts only receive fees")
+	// ErrFeeExceedsMax is returned when the fee of a transfer is above the maximum fee accepted by the sender
+	ErrFeeExceedsMax = errors.New("fee exceeds the maximum fee")
+	// ErrInterestAccount is returned when an interest account of the bank would take part in anything but paying interest
+	ErrInterestAccount = errors.New("interest accounts only pay interest")
+	// ErrTransactionLimitExceeded is returned when a transfer amount is above the per transaction limit of the sender
+	ErrTransactionLimitExceeded = errors.New("amount exceeds the per transaction limit")
+	// ErrDailyLimitExceeded is returned when a transfer would take the sender above his/her daily limit
+	ErrDailyLimitExceeded = errors.New("transfer exceeds the daily limit")
+	// ErrMonthlyLimitExceeded is returned when a transfer would take the sender above his/her monthly limit
+	ErrMonthlyLimitExceeded = errors.New("transfer exceeds the monthly limit")
 )
 
 // Store defines all functions to execute db queries and transactions
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	IdempotentTransferTx(ctx context.Context, arg IdempotentTransferTxParams) (TransferTxResult, error)
+	RotateSessionTx(ctx context.Context, arg RotateSessionTxParams) (RotateSessionTxResult, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (ChangePasswordTxResult, error)
+	CreateResetPasswordTx(ctx context.Context, arg CreateResetPasswordTxParams) (CreateResetPasswordTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error)
+	CloseAccountTx(ctx context.Context, arg CloseAccountTxParams) (CloseAccountTxResult, error)
+	ExecuteScheduledTransferTx(ctx context.Context, arg ExecuteScheduledTransferTxParams) (ExecuteScheduledTransferTxResult, error)
+	VerifyLedgerTx(ctx context.Context) (VerifyLedgerTxResult, error)
+	PlaceHoldTx(ctx context.Context, arg PlaceHoldTxParams) (PlaceHoldTxResult, error)
+	CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error)
+	DepositTx(ctx context.Context, arg CashTxParams) (TransferTxResult, error)
+	WithdrawTx(ctx context.Context, arg CashTxParams) (TransferTxResult, error)
+	CreateFeeScheduleTx(ctx context.Context, arg CreateFeeScheduleTxParams) (CreateFeeScheduleTxResult, error)
+	AccrueInterestTx(ctx context.Context, arg AccrueInterestTxParams) (AccrueInterestTxResult, error)
+	CapitalizeInterestTx(ctx context.Context, arg CapitalizeInterestTxParams) (CapitalizeInterestTxResult, error)
 }
 
 // SQLStore provides all functions to execute SQL queries and transactions
@@ -30,7 +98,12 @@ func NewStore(db *sql.DB) Store {
 
 // execTx Executes a function within the generated db transactions - fn is a callback function
 func (store *SQLStore) execTx(cxt context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(cxt, nil)
+	return store.execTxWithOptions(cxt, nil, fn)
+}
+
+// execTxWithOptions executes a function within a db transaction started with the given options
+func (store *SQLStore) execTxWithOptions(cxt context.Context, opts *sql.TxOptions, fn func(*Queries) error) error {
+	tx, err := store.db.BeginTx(cxt, opts)
 
 	if err != nil {
 		return err
@@ -65,7 +138,24 @@ func (store *SQLStore) execTx(cxt context.Context, fn func(*Queries) error) erro
 type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	Amount        int64 `json:"amount"` // debited from the sender, in the sender's currency
+
+	// ToAmount and ExchangeRate are only set for cross currency transfers
+	// ToAmount is credited to the receiver, in the receiver's currency
+	ToAmount     int64  `json:"to_amount"`
+	ExchangeRate string `json:"exchange_rate"`
+
+	// ReversalOf is only set for the reversals of a transfer
+	ReversalOf sql.NullInt64 `json:"reversal_of"`
+	// EntryType is recorded on both entries, they are plain transfers unless it is set
+	EntryType string `json:"entry_type"`
+
+	// MaxFee is the highest fee the sender accepts, any fee is accepted unless it is set
+	MaxFee sql.NullInt64 `json:"max_fee"`
+	// NoFee is set by the money movements the bank never charges, like the sweep of a closed account
+	NoFee bool `json:"no_fee"`
+	// NoLimits is set by the plain transfers the transfer limits do not apply to, like the sweep of a closed account
+	NoLimits bool `json:"no_limits"`
 }
 
 // TransferTxResult contains all the results of the transfer transaction
@@ -76,6 +166,9 @@ type TransferTxResult struct {
 	FromEntry   Entry    `json:"from_entry"` // the entry for the account money is debited from
 	ToEntry     Entry    `json:"to_entry"`   // the entry for the account money is credited to
 
+	// the fee entries are only set when a fee was charged
+	FeeEntry       *Entry `json:"fee_entry,omitempty"`        // the entry debiting the fee from the sender
+	FeeIncomeEntry *Entry `json:"fee_income_entry,omitempty"` // the entry crediting the fee to the bank
 }
 
 // TransferTx performs money transfer from one account to another
@@ -87,47 +180,369 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
+		result, err = transfer(ctx, q, arg)
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// IdempotentTransferTxParams contains the input of a transfer retried with the same idempotency key
+type IdempotentTransferTxParams struct {
+	TransferTxParams
+	IdempotencyKey CreateIdempotencyKeyParams `json:"idempotency_key"`
+	// ResponseStatus is stored with the result, so a replay returns the original response
+	ResponseStatus int32 `json:"response_status"`
+}
+
+// IdempotentTransferTx claims the idempotency key, performs the transfer and stores its result in a single db transaction.
+// A failed transfer leaves the key free to retry, and a committed one can always be replayed.
+// It returns ErrIdempotencyKeyUsed when the key already holds a response
+func (store *SQLStore) IdempotentTransferTx(ctx context.Context, arg IdempotentTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// a concurrent request with the same key waits on the primary key until this transaction ends,
+		// it then finds the key used, or claims it if this transaction rolled back
+		_, err := q.CreateIdempotencyKey(ctx, arg.IdempotencyKey)
 
 		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrIdempotencyKeyUsed
+			}
 			return err
 		}
 
-		// Creates the from entry records
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount, // negative arg since money is moving out
-		})
+		result, err = transfer(ctx, q, arg.TransferTxParams)
 
 		if err != nil {
 			return err
 		}
 
-		// Creates the to entry records
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
+		body, err := json.Marshal(result)
 
 		if err != nil {
 			return err
 		}
 
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
-		}
+		_, err = q.UpdateIdempotencyKeyResponse(ctx, UpdateIdempotencyKeyResponseParams{
+			Username:       arg.IdempotencyKey.Username,
+			Key:            arg.IdempotencyKey.Key,
+			ResponseStatus: arg.ResponseStatus,
+			ResponseBody:   body,
+		})
 
 		return err
 	})
 
 	return result, err
+}
+
+// transfer moves money between two accounts using the queries of an open db transaction
+func transfer(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	// an account currency never changes, so these reads need no lock
+	fromAccount, err := q.GetAccount(ctx, arg.FromAccountID)
+
+	if err != nil {
+		return result, err
+	}
+
+	toAccount, err := q.GetAccount(ctx, arg.ToAccountID)
+
+	if err != nil {
+		return result, err
+	}
+
+	entryType := arg.EntryType
+
+	if entryType == "" {
+		entryType = utils.EntryTypeTransfer
+	}
+
+	// money only moves in and out of the bank through deposits and withdrawals
+	if entryType == utils.EntryTypeTransfer &&
+		(fromAccount.Owner == utils.CashAccountOwner || toAccount.Owner == utils.CashAccountOwner) {
+		return result, ErrCashAccount
+	}
+
+	if fromAccount.Owner == utils.FeeAccountOwner || toAccount.Owner == utils.FeeAccountOwner {
+		return result, ErrFeeAccount
+	}
+
+	if entryType != utils.EntryTypeInterest &&
+		(fromAccount.Owner == utils.InterestAccountOwner || toAccount.Owner == utils.InterestAccountOwner) {
+		return result, ErrInterestAccount
+	}
+
+	// same currency transfers credit exactly what they debit
+	toAmount := arg.Amount
+	exchangeRate := "1"
+
+	if fromAccount.Currency != toAccount.Currency {
+		if arg.ExchangeRate == "" || arg.ToAmount <= 0 {
+			return result, ErrCurrencyMismatch
+		}
+
+		toAmount = arg.ToAmount
+		exchangeRate = arg.ExchangeRate
+	}
+
+	var fee int64
+	var feeScheduleID sql.NullInt64
+
+	if !arg.NoFee {
+		fee, feeScheduleID, err = transferFee(ctx, q, fromAccount, entryType, arg.Amount)
+
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if arg.MaxFee.Valid && fee > arg.MaxFee.Int64 {
+		return result, ErrFeeExceedsMax
+	}
+
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		ToAmount:      toAmount,
+		FromCurrency:  fromAccount.Currency,
+		ToCurrency:    toAccount.Currency,
+		ExchangeRate:  exchangeRate,
+		ReversalOf:    arg.ReversalOf,
+		Fee:           fee,
+		FeeScheduleID: feeScheduleID,
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	// Creates the from entry records
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:       arg.FromAccountID,
+		Amount:          -arg.Amount, // negative arg since money is moving out
+		TransactionType: entryType,
+		TransferID:      sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	// Creates the to entry records
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:       arg.ToAccountID,
+		Amount:          toAmount,
+		TransactionType: entryType,
+		TransferID:      sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	if arg.FromAccountID < arg.ToAccountID {
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, toAmount)
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, toAmount, arg.FromAccountID, -arg.Amount)
+	}
+
+	if err != nil {
+		return result, err
+	}
+
+	if fee > 0 {
+		err = chargeFee(ctx, q, &result, fee)
+
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// the status is read from the locked rows, so an account frozen or closed
+	// by a concurrent transaction is seen here
+	if result.FromAccount.Status != utils.AccountStatusActive || result.ToAccount.Status != utils.AccountStatusActive {
+		return result, ErrAccountNotActive
+	}
+
+	if entryType == utils.EntryTypeTransfer && !arg.NoLimits {
+		err = checkTransferLimits(ctx, q, result.FromAccount, arg.Amount)
+
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// the cash and interest account balances go down by every deposit and interest they pay out,
+	// so they have no funds to check
+	if result.FromAccount.Owner == utils.CashAccountOwner || result.FromAccount.Owner == utils.InterestAccountOwner {
+		return result, nil
+	}
+
+	// held funds are reserved for their holds and cannot be spent
+	heldAmount, err := q.GetAccountHeldAmount(ctx, arg.FromAccountID)
+
+	if err != nil {
+		return result, err
+	}
+
+	// the sender row is locked by the update above, so neither the new balance
+	// nor the held funds can be changed by a concurrent transfer or hold before we commit
+	if result.FromAccount.Balance-heldAmount < -result.FromAccount.OverdraftLimit {
+		return result, ErrInsufficientFunds
+	}
+
+	return result, nil
+}
+
+// checkTransferLimits checks a plain transfer against the per transaction, daily and monthly limits
+// of the sender in the currency of the account it is sent from. The entries of the transfer must already
+// be created, so they are counted in the totals
+func checkTransferLimits(ctx context.Context, q *Queries, fromAccount Account, amount int64) error {
+	// a user has a single open account per currency and the sender row is locked by the update of its balance,
+	// so the totals read here cannot be changed by a concurrent transfer before we commit
+	limit, err := q.GetTransferLimit(ctx, GetTransferLimitParams{
+		Username: fromAccount.Owner,
+		Currency: fromAccount.Currency,
+	})
+
+	if err != nil {
+		// the currency has no limits for the tier of the user
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	if amount > limit.PerTransactionLimit {
+		return ErrTransactionLimitExceeded
+	}
+
+	if limit.DailyTotal > limit.DailyLimit {
+		return ErrDailyLimitExceeded
+	}
+
+	if limit.MonthlyTotal > limit.MonthlyLimit {
+		return ErrMonthlyLimitExceeded
+	}
+
+	return nil
+}
+
+// transferFee computes the fee of a transfer from the current fee schedule of its currency and type
+// The bank never charges itself, and only plain transfers and withdrawals are charged
+func transferFee(ctx context.Context, q *Queries, fromAccount Account, entryType string, amount int64) (int64, sql.NullInt64, error) {
+	var scheduleID sql.NullInt64
+
+	if fromAccount.Owner == utils.CashAccountOwner ||
+		(entryType != utils.EntryTypeTransfer && entryType != utils.EntryTypeWithdrawal) {
+		return 0, scheduleID, nil
+	}
+
+	schedule, err := q.GetCurrentFeeSchedule(ctx, GetCurrentFeeScheduleParams{
+		Currency:     fromAccount.Currency,
+		TransferType: entryType,
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// no schedule, no fee
+			return 0, scheduleID, nil
+		}
+		return 0, scheduleID, err
+	}
+
+	tiers, err := q.ListFeeTiers(ctx, schedule.ID)
+
+	if err != nil {
+		return 0, scheduleID, err
+	}
+
+	scheduleID = sql.NullInt64{Int64: schedule.ID, Valid: true}
+
+	return calculateFee(tiers, amount), scheduleID, nil
+}
+
+// calculateFee returns the fee of an amount under the given tiers, sorted by min amount
+// The highest tier whose min amount is not above the amount applies: its flat fee is added to
+// its percentage of the amount, rounded half up. An amount below every tier is not charged
+func calculateFee(tiers []FeeTier, amount int64) int64 {
+	var fee int64
+
+	for _, tier := range tiers {
+		if tier.MinAmount > amount {
+			break
+		}
+
+		basisPoints := int64(tier.BasisPoints)
+
+		// split the amount so amount * basis points cannot overflow
+		fee = tier.FlatFee + amount/10000*basisPoints + (amount%10000*basisPoints+5000)/10000
+	}
+
+	return fee
+}
+
+// chargeFee debits the fee from the sender of a transfer and credits it to the fee account of its currency
+// The fee account is always locked after the transfer accounts, so it cannot take part in a deadlock
+func chargeFee(ctx context.Context, q *Queries, result *TransferTxResult, fee int64) error {
+	feeAccount, err := q.GetAccountByCurrency(ctx, GetAccountByCurrencyParams{
+		Owner:    utils.FeeAccountOwner,
+		Currency: result.FromAccount.Currency,
+	})
+
+	if err != nil {
+		// the fee accounts are created by the migrations, so a missing one is not the caller's fault
+		return fmt.Errorf("cannot find the %s fee account: %v", result.FromAccount.Currency, err)
+	}
+
+	feeEntry, err := q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:       result.FromAccount.ID,
+		Amount:          -fee,
+		TransactionType: utils.EntryTypeFee,
+		TransferID:      sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	feeIncomeEntry, err := q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:       feeAccount.ID,
+		Amount:          fee,
+		TransactionType: utils.EntryTypeFee,
+		TransferID:      sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	result.FeeEntry = &feeEntry
+	result.FeeIncomeEntry = &feeIncomeEntry
+
+	// the sender is already locked by the transfer
+	result.FromAccount, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     result.FromAccount.ID,
+		Amount: -fee,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     feeAccount.ID,
+		Amount: fee,
+	})
 
+	return err
 }
 
 func addMoney(
@@ -152,3 +567,1116 @@ func addMoney(
 	})
 	return
 }
+
+// RotateSessionTxParams contains all the input params of the rotate session transaction
+type RotateSessionTxParams struct {
+	SessionID  uuid.UUID           `json:"session_id"`  // the session of the refresh token being exchanged
+	NewSession CreateSessionParams `json:"new_session"` // the session of the newly issued refresh token
+}
+
+// RotateSessionTxResult contains all the results of the rotate session transaction
+type RotateSessionTxResult struct {
+	OldSession Session `json:"old_session"`
+	NewSession Session `json:"new_session"`
+}
+
+// RotateSessionTx retires a session and creates the session of its replacement refresh token
+// within a single db transaction. The new session joins the family of the old one.
+// If the old session was already rotated or blocked it returns ErrSessionReused
+func (store *SQLStore) RotateSessionTx(ctx context.Context, arg RotateSessionTxParams) (RotateSessionTxResult, error) {
+	var result RotateSessionTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		// the update only matches a live session,
+		// so of two concurrent renewals with the same token only one can win
+		result.OldSession, err = q.RotateSession(ctx, arg.SessionID)
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrSessionReused
+			}
+			return err
+		}
+
+		newSession := arg.NewSession
+		newSession.FamilyID = result.OldSession.FamilyID
+
+		result.NewSession, err = q.CreateSession(ctx, newSession)
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// CreateUserTxParams contains all the input params of the create user transaction
+type CreateUserTxParams struct {
+	CreateUserParams
+	VerifyEmailSecretCode string    `json:"verify_email_secret_code"` // sha256 hash of the code sent by email
+	VerifyEmailExpiredAt  time.Time `json:"verify_email_expired_at"`
+}
+
+// CreateUserTxResult contains all the results of the create user transaction
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// CreateUserTx creates a user and the code to verify his/her email within a single db transaction
+// the verification email is sent by the caller once the transaction is committed, so it never holds the transaction open
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:   result.User.Username,
+			Email:      result.User.Email,
+			SecretCode: arg.VerifyEmailSecretCode,
+			ExpiredAt:  arg.VerifyEmailExpiredAt,
+		})
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// VerifyEmailTxParams contains all the input params of the verify email transaction
+type VerifyEmailTxParams struct {
+	EmailID    int64  `json:"email_id"`
+	SecretCode string `json:"secret_code"` // sha256 hash of the code sent by email
+}
+
+// VerifyEmailTxResult contains all the results of the verify email transaction
+type VerifyEmailTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// VerifyEmailTx uses up a verification code and marks the email of its user as verified
+// within a single db transaction. It returns sql.ErrNoRows if the code is unknown, used or expired,
+// or if the user has changed his/her email since the code was sent
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.VerifyEmail, err = q.UpdateVerifyEmail(ctx, UpdateVerifyEmailParams{
+			ID:         arg.EmailID,
+			SecretCode: arg.SecretCode,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username: result.VerifyEmail.Username,
+			Email:    result.VerifyEmail.Email,
+		})
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// ChangePasswordTxParams contains all the input params of the change password transaction
+type ChangePasswordTxParams struct {
+	Username      string `json:"username"`
+	HarshPassword string `json:"harsh_password"`
+}
+
+// ChangePasswordTxResult contains all the results of the change password transaction
+type ChangePasswordTxResult struct {
+	User            User  `json:"user"`
+	BlockedSessions int64 `json:"blocked_sessions"`
+}
+
+// ChangePasswordTx updates the password of a user and blocks all of his/her sessions
+// within a single db transaction, so every device has to log in again with the new password
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (ChangePasswordTxResult, error) {
+	var result ChangePasswordTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, result.BlockedSessions, err = changePassword(ctx, q, arg.Username, arg.HarshPassword)
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// CreateResetPasswordTxParams contains all the input params of the create reset password transaction
+type CreateResetPasswordTxParams struct {
+	CreateResetPasswordParams
+}
+
+// CreateResetPasswordTxResult contains all the results of the create reset password transaction
+type CreateResetPasswordTxResult struct {
+	ResetPassword      ResetPassword `json:"reset_password"`
+	UsedResetPasswords int64         `json:"used_reset_passwords"` // the earlier codes no longer valid
+}
+
+// CreateResetPasswordTx creates a new reset code for a user and uses up his/her earlier ones
+// within a single db transaction, so only the code of the last email can reset the password
+func (store *SQLStore) CreateResetPasswordTx(ctx context.Context, arg CreateResetPasswordTxParams) (CreateResetPasswordTxResult, error) {
+	var result CreateResetPasswordTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.UsedResetPasswords, err = q.UseUserResetPasswords(ctx, arg.Username)
+
+		if err != nil {
+			return err
+		}
+
+		result.ResetPassword, err = q.CreateResetPassword(ctx, arg.CreateResetPasswordParams)
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// ResetPasswordTxParams contains all the input params of the reset password transaction
+type ResetPasswordTxParams struct {
+	ResetID       int64  `json:"reset_id"`
+	SecretCode    string `json:"secret_code"` // sha256 hash of the code sent by email
+	HarshPassword string `json:"harsh_password"`
+}
+
+// ResetPasswordTxResult contains all the results of the reset password transaction
+type ResetPasswordTxResult struct {
+	User            User          `json:"user"`
+	ResetPassword   ResetPassword `json:"reset_password"`
+	BlockedSessions int64         `json:"blocked_sessions"`
+}
+
+// ResetPasswordTx uses up a reset code along with the other codes of its user, then updates
+// the password of the user and blocks all of his/her sessions within a single db transaction.
+// It returns sql.ErrNoRows if the code is unknown, used or expired
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error) {
+	var result ResetPasswordTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.ResetPassword, err = q.UpdateResetPassword(ctx, UpdateResetPasswordParams{
+			ID:         arg.ResetID,
+			SecretCode: arg.SecretCode,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		// the codes emailed before or after this one can no longer reset the new password
+		_, err = q.UseUserResetPasswords(ctx, result.ResetPassword.Username)
+
+		if err != nil {
+			return err
+		}
+
+		result.User, result.BlockedSessions, err = changePassword(ctx, q, result.ResetPassword.Username, arg.HarshPassword)
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// changePassword sets the new password of a user and blocks all of his/her sessions
+// the change time is taken from the server clock that also stamps the tokens it issues,
+// so tokens issued after the change are never mistaken for older ones
+func changePassword(ctx context.Context, q *Queries, username string, harshPassword string) (user User, blockedSessions int64, err error) {
+	user, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		Username:         username,
+		HarshPassword:    harshPassword,
+		PasswordChangeAt: time.Now(),
+	})
+
+	if err != nil {
+		return
+	}
+
+	blockedSessions, err = q.BlockUserSessions(ctx, username)
+
+	return
+}
+
+// UpdateUserTxParams contains all the input params of the update user transaction
+type UpdateUserTxParams struct {
+	UpdateUserParams
+	ChangedBy string `json:"changed_by"`
+
+	// a new verification code is only created when the email changes
+	VerifyEmailSecretCode string    `json:"verify_email_secret_code"` // sha256 hash of the code sent by email
+	VerifyEmailExpiredAt  time.Time `json:"verify_email_expired_at"`
+}
+
+// UpdateUserTxResult contains all the results of the update user transaction
+type UpdateUserTxResult struct {
+	User        User          `json:"user"`
+	Histories   []UserHistory `json:"histories"`
+	VerifyEmail VerifyEmail   `json:"verify_email"` // only set when the email changed
+}
+
+// UpdateUserTx updates the profile of a user and records every changed field
+// in the user history within a single db transaction
+// a changed email has to be verified again, the caller emails the new code once the transaction is committed
+func (store *SQLStore) UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error) {
+	var result UpdateUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// lock the user so concurrent updates record the right old values
+		oldUser, err := q.GetUserForUpdate(ctx, arg.Username)
+
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.UpdateUser(ctx, arg.UpdateUserParams)
+
+		if err != nil {
+			return err
+		}
+
+		changes := []struct {
+			field    string
+			oldValue string
+			newValue string
+		}{
+			{"full_name", oldUser.FullName, result.User.FullName},
+			{"email", oldUser.Email, result.User.Email},
+		}
+
+		result.Histories = []UserHistory{}
+
+		for _, change := range changes {
+			if change.oldValue == change.newValue {
+				continue
+			}
+
+			history, err := q.CreateUserHistory(ctx, CreateUserHistoryParams{
+				Username:  arg.Username,
+				ChangedBy: arg.ChangedBy,
+				Field:     change.field,
+				OldValue:  change.oldValue,
+				NewValue:  change.newValue,
+			})
+
+			if err != nil {
+				return err
+			}
+
+			result.Histories = append(result.Histories, history)
+		}
+
+		if oldUser.Email == result.User.Email {
+			return nil
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:   result.User.Username,
+			Email:      result.User.Email,
+			SecretCode: arg.VerifyEmailSecretCode,
+			ExpiredAt:  arg.VerifyEmailExpiredAt,
+		})
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// CloseAccountTxParams contains all the input params of the close account transaction
+type CloseAccountTxParams struct {
+	AccountID int64 `json:"account_id"`
+	// SweepAccountID receives the remaining balance, it is only needed when the balance is not zero
+	SweepAccountID int64 `json:"sweep_account_id"`
+}
+
+// CloseAccountTxResult contains all the results of the close account transaction
+type CloseAccountTxResult struct {
+	Account Account           `json:"account"`
+	Sweep   *TransferTxResult `json:"sweep,omitempty"` // only set when a balance was swept
+}
+
+// CloseAccountTx closes an active account within a single db transaction
+// a positive balance is first swept to the sweep account, which must be active, belong to the same owner and hold the same currency.
+// It returns ErrAccountNotEmpty if the balance is not zero and cannot be swept
+func (store *SQLStore) CloseAccountTx(ctx context.Context, arg CloseAccountTxParams) (CloseAccountTxResult, error) {
+	var result CloseAccountTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// lock the accounts in the same order as transfers do to avoid deadlocks
+		if arg.SweepAccountID != 0 && arg.SweepAccountID < arg.AccountID {
+			_, err := q.GetAccountForUpdate(ctx, arg.SweepAccountID)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+
+		if err != nil {
+			return err
+		}
+
+		if account.Status != utils.AccountStatusActive {
+			return ErrAccountNotActive
+		}
+
+		if account.Balance < 0 || (account.Balance > 0 && (arg.SweepAccountID == 0 || arg.SweepAccountID == arg.AccountID)) {
+			return ErrAccountNotEmpty
+		}
+
+		// held funds still have to be captured or released
+		heldAmount, err := q.GetAccountHeldAmount(ctx, arg.AccountID)
+
+		if err != nil {
+			return err
+		}
+
+		if heldAmount > 0 {
+			return ErrAccountNotEmpty
+		}
+
+		if account.Balance > 0 {
+			sweepAccount, err := q.GetAccount(ctx, arg.SweepAccountID)
+
+			if err != nil {
+				return err
+			}
+
+			// the sweep skips the limits, so it may only move money between accounts of the same owner
+			if sweepAccount.Owner != account.Owner {
+				return ErrSweepAccountOwner
+			}
+
+			// sweeps never convert currencies, so a mismatch fails with ErrCurrencyMismatch
+			sweep, err := transfer(ctx, q, TransferTxParams{
+				FromAccountID: arg.AccountID,
+				ToAccountID:   arg.SweepAccountID,
+				Amount:        account.Balance,
+				NoFee:         true,
+				NoLimits:      true,
+			})
+
+			if err != nil {
+				return err
+			}
+
+			result.Sweep = &sweep
+		}
+
+		result.Account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			ID:        arg.AccountID,
+			OldStatus: utils.AccountStatusActive,
+			NewStatus: utils.AccountStatusClosed,
+		})
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// ExecuteScheduledTransferTxParams contains all the input params of the execute scheduled transfer transaction
+type ExecuteScheduledTransferTxParams struct {
+	Now         time.Time     `json:"now"`          // transfers due at or before this time can be claimed
+	MaxAttempts int32         `json:"max_attempts"` // attempts of a single run before it is given up
+	RetryDelay  time.Duration `json:"retry_delay"`  // wait between two attempts of the same run
+
+	// NextRunAt returns the first time the recurrence of a transfer falls after the given time
+	NextRunAt func(recurrence string, after time.Time) (time.Time, error) `json:"-"`
+}
+
+// ExecuteScheduledTransferTxResult contains all the results of the execute scheduled transfer transaction
+type ExecuteScheduledTransferTxResult struct {
+	ScheduledTransfer ScheduledTransfer    `json:"scheduled_transfer"`
+	Run               ScheduledTransferRun `json:"run"`
+	Transfer          *TransferTxResult    `json:"transfer,omitempty"` // only set when the run succeeded
+}
+
+// ExecuteScheduledTransferTx claims one due scheduled transfer, runs it and records the run
+// within a single db transaction. It returns sql.ErrNoRows if no transfer is due.
+// The claimed row stays locked until the run is recorded and skipped by every other claim,
+// so a run is never executed twice even with several servers executing transfers.
+// A failed attempt is retried after the retry delay until the max attempts are used up
+func (store *SQLStore) ExecuteScheduledTransferTx(ctx context.Context, arg ExecuteScheduledTransferTxParams) (ExecuteScheduledTransferTxResult, error) {
+	var result ExecuteScheduledTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		scheduledTransfer, err := q.ClaimDueScheduledTransfer(ctx, arg.Now)
+
+		if err != nil {
+			return err
+		}
+
+		var transferResult TransferTxResult
+
+		// a failed transfer only rolls back its own changes, so the failure can still be recorded
+		transferErr := savepoint(ctx, q, "scheduled_transfer", func() error {
+			var err error
+
+			transferResult, err = transfer(ctx, q, TransferTxParams{
+				FromAccountID: scheduledTransfer.FromAccountID,
+				ToAccountID:   scheduledTransfer.ToAccountID,
+				Amount:        scheduledTransfer.Amount,
+			})
+
+			return err
+		})
+
+		runArg := CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduledTransfer.ID,
+			Attempt:             scheduledTransfer.Attempts + 1,
+			Succeeded:           transferErr == nil,
+		}
+
+		if transferErr == nil {
+			runArg.TransferID = sql.NullInt64{Int64: transferResult.Transfer.ID, Valid: true}
+			result.Transfer = &transferResult
+		} else {
+			runArg.Error = transferErr.Error()
+		}
+
+		result.Run, err = q.CreateScheduledTransferRun(ctx, runArg)
+
+		if err != nil {
+			return err
+		}
+
+		result.ScheduledTransfer, err = q.RescheduleScheduledTransfer(ctx, nextScheduledRun(scheduledTransfer, result.Run, arg))
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// nextScheduledRun decides when a scheduled transfer runs again after the given run
+// missed occurrences of a recurring transfer are skipped rather than caught up
+func nextScheduledRun(scheduledTransfer ScheduledTransfer, run ScheduledTransferRun, arg ExecuteScheduledTransferTxParams) RescheduleScheduledTransferParams {
+	next := RescheduleScheduledTransferParams{
+		ID:        scheduledTransfer.ID,
+		Status:    utils.ScheduledTransferStatusActive,
+		NextRunAt: scheduledTransfer.NextRunAt,
+	}
+
+	if !run.Succeeded && run.Attempt < arg.MaxAttempts {
+		next.NextRunAt = arg.Now.Add(arg.RetryDelay)
+		next.Attempts = run.Attempt
+		return next
+	}
+
+	if scheduledTransfer.Recurrence == "" {
+		next.Status = utils.ScheduledTransferStatusCompleted
+
+		if !run.Succeeded {
+			next.Status = utils.ScheduledTransferStatusFailed
+		}
+		return next
+	}
+
+	nextRunAt, err := arg.NextRunAt(scheduledTransfer.Recurrence, arg.Now)
+
+	if err != nil {
+		// the recurrence has no further occurrence
+		next.Status = utils.ScheduledTransferStatusFailed
+		return next
+	}
+
+	next.NextRunAt = nextRunAt
+	return next
+}
+
+// savepoint runs fn within a savepoint of the open db transaction
+// the changes made by fn are rolled back if it fails, while the transaction itself stays usable
+func savepoint(ctx context.Context, q *Queries, name string, fn func() error) error {
+	_, err := q.db.ExecContext(ctx, "SAVEPOINT "+name)
+
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+
+	if err != nil {
+		_, rbErr := q.db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+
+		if rbErr != nil {
+			return fmt.Errorf("savepoint Error: %v, rollback Error: %v", err, rbErr)
+		}
+		return err
+	}
+
+	_, err = q.db.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+
+	return err
+}
+
+// VerifyLedgerTxResult contains all the results of the verify ledger transaction
+type VerifyLedgerTxResult struct {
+	Balanced           bool                              `json:"balanced"` // true when no mismatch was found
+	AccountMismatches  []ListAccountBalanceMismatchesRow `json:"account_mismatches"`
+	TransferMismatches []ListUnbalancedTransfersRow      `json:"transfer_mismatches"`
+	OrphanEntries      []Entry                           `json:"orphan_entries"` // entries posted by no transfer
+	CheckedAt          time.Time                         `json:"checked_at"`
+}
+
+// VerifyLedgerTx checks every account balance against the sum of its entries, every transfer
+// against its debit and credit entries and that every entry was posted by a transfer. The checks read the same snapshot of the db,
+// so transfers committed while the ledger is verified cannot show up as mismatches
+func (store *SQLStore) VerifyLedgerTx(ctx context.Context) (VerifyLedgerTxResult, error) {
+	var result VerifyLedgerTxResult
+
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+
+	err := store.execTxWithOptions(ctx, opts, func(q *Queries) error {
+		var err error
+
+		result.AccountMismatches, err = q.ListAccountBalanceMismatches(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		result.TransferMismatches, err = q.ListUnbalancedTransfers(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		result.OrphanEntries, err = q.ListOrphanEntries(ctx)
+
+		return err
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	result.Balanced = len(result.AccountMismatches) == 0 && len(result.TransferMismatches) == 0 && len(result.OrphanEntries) == 0
+	result.CheckedAt = time.Now()
+
+	return result, nil
+
+}
+
+// PlaceHoldTxParams contains all the input params of the place hold transaction
+type PlaceHoldTxParams struct {
+	CreateHoldParams
+}
+
+// PlaceHoldTxResult contains all the results of the place hold transaction
+type PlaceHoldTxResult struct {
+	Hold             Hold  `json:"hold"`
+	AvailableBalance int64 `json:"available_balance"` // available balance of the account once the funds are held
+}
+
+// PlaceHoldTx reserves funds of an active account within a single db transaction
+// It returns ErrInsufficientFunds if the available balance cannot cover the hold
+func (store *SQLStore) PlaceHoldTx(ctx context.Context, arg PlaceHoldTxParams) (PlaceHoldTxResult, error) {
+	var result PlaceHoldTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// lock the account so concurrent holds and transfers cannot spend the same funds
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+
+		if err != nil {
+			return err
+		}
+
+		if account.Status != utils.AccountStatusActive {
+			return ErrAccountNotActive
+		}
+
+		heldAmount, err := q.GetAccountHeldAmount(ctx, arg.AccountID)
+
+		if err != nil {
+			return err
+		}
+
+		result.AvailableBalance = account.Balance - heldAmount - arg.Amount
+
+		if result.AvailableBalance < -account.OverdraftLimit {
+			return ErrInsufficientFunds
+		}
+
+		result.Hold, err = q.CreateHold(ctx, arg.CreateHoldParams)
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// CaptureHoldTxParams contains all the input params of the capture hold transaction
+type CaptureHoldTxParams struct {
+	HoldID int64 `json:"hold_id"`
+	Amount int64 `json:"amount"` // at most the held amount, the remainder is released
+}
+
+// CaptureHoldTxResult contains all the results of the capture hold transaction
+type CaptureHoldTxResult struct {
+	Hold     Hold             `json:"hold"`
+	Transfer TransferTxResult `json:"transfer"`
+}
+
+// CaptureHoldTx transfers the captured amount of a hold to its receiving account
+// and releases the hold within a single db transaction.
+// It returns ErrHoldNotActive if the hold was already captured, voided or has expired,
+// and the limit errors of TransferTx if the payer cannot send the captured amount
+func (store *SQLStore) CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	var result CaptureHoldTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// lock the hold so it can only be captured once
+		hold, err := q.GetHoldForUpdate(ctx, arg.HoldID)
+
+		if err != nil {
+			return err
+		}
+
+		if hold.Status != utils.HoldStatusHeld || !hold.ExpiresAt.After(time.Now()) {
+			return ErrHoldNotActive
+		}
+
+		if arg.Amount <= 0 || arg.Amount > hold.Amount {
+			return ErrCaptureExceedsHold
+		}
+
+		// the hold is released before the transfer, so its funds are available to it
+		_, err = q.CaptureHold(ctx, CaptureHoldParams{
+			ID:             hold.ID,
+			CapturedAmount: arg.Amount,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		// the money leaves the payer, so the capture counts against the limits of the payer
+		// even though it is made by the payee
+		result.Transfer, err = transfer(ctx, q, TransferTxParams{
+			FromAccountID: hold.AccountID,
+			ToAccountID:   hold.ToAccountID,
+			Amount:        arg.Amount,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.UpdateHoldTransfer(ctx, UpdateHoldTransferParams{
+			ID:         hold.ID,
+			TransferID: sql.NullInt64{Int64: result.Transfer.Transfer.ID, Valid: true},
+		})
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// ReverseTransferTxParams contains all the input params of the reverse transfer transaction
+type ReverseTransferTxParams struct {
+	TransferID int64 `json:"transfer_id"`
+	Amount     int64 `json:"amount"` // refunded to the sender, in the sender's currency
+}
+
+// ReverseTransferTxResult contains all the results of the reverse transfer transaction
+type ReverseTransferTxResult struct {
+	Transfer Transfer         `json:"transfer"` // the reversed transfer, with its new reversed amount
+	Reversal TransferTxResult `json:"reversal"`
+}
+
+// ReverseTransferTx refunds the sender of a transfer, in full or in part, with a reversal transfer
+// in the opposite direction linked to it, within a single db transaction.
+// The refunds of a transfer can never add up to more than its amount, and its fee is not refunded
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	var result ReverseTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// lock the transfer so concurrent reversals cannot refund it twice
+		original, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+
+		if err != nil {
+			return err
+		}
+
+		if original.ReversalOf.Valid {
+			return ErrTransferNotReversible
+		}
+
+		if original.IsReversed {
+			return ErrTransferAlreadyReversed
+		}
+
+		if arg.Amount <= 0 || original.ReversedAmount+arg.Amount > original.Amount {
+			return ErrInvalidReversalAmount
+		}
+
+		// the receiver gives back the same share of what he/she was credited,
+		// so once the whole transfer is reversed exactly its to amount has been given back
+		alreadyReversedToAmount := reversedToAmount(original, original.ReversedAmount)
+		toAmount := reversedToAmount(original, original.ReversedAmount+arg.Amount) - alreadyReversedToAmount
+
+		if toAmount <= 0 {
+			return ErrInvalidReversalAmount
+		}
+
+		// a cross currency reversal converts back at the rate of the transfer
+		exchangeRate, ok := new(big.Rat).SetString(original.ExchangeRate)
+
+		if !ok || exchangeRate.Sign() <= 0 {
+			return fmt.Errorf("invalid exchange rate %q of transfer %d", original.ExchangeRate, original.ID)
+		}
+
+		result.Reversal, err = transfer(ctx, q, TransferTxParams{
+			FromAccountID: original.ToAccountID,
+			ToAccountID:   original.FromAccountID,
+			Amount:        toAmount,
+			ToAmount:      arg.Amount,
+			ExchangeRate:  new(big.Rat).Inv(exchangeRate).FloatString(10),
+			ReversalOf:    sql.NullInt64{Int64: original.ID, Valid: true},
+			EntryType:     utils.EntryTypeReversal,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = q.AddTransferReversedAmount(ctx, AddTransferReversedAmountParams{
+			ID:     original.ID,
+			Amount: arg.Amount,
+		})
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// reversedToAmount returns the share of the to amount of a transfer given back once reversedAmount of it is refunded,
+// rounded down. The product of the two amounts is computed with big integers so it cannot overflow,
+// and the whole to amount is given back once the whole transfer is refunded
+func reversedToAmount(transfer Transfer, reversedAmount int64) int64 {
+	if reversedAmount >= transfer.Amount {
+		return transfer.ToAmount
+	}
+
+	share := new(big.Int).Mul(big.NewInt(transfer.ToAmount), big.NewInt(reversedAmount))
+
+	return share.Quo(share, big.NewInt(transfer.Amount)).Int64()
+}
+
+// CashTxParams contains all the input params of the deposit and withdraw transactions
+type CashTxParams struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"` // in the currency of the account
+}
+
+// DepositTx pays cash into an account out of the cash account of its currency within a single db transaction
+func (store *SQLStore) DepositTx(ctx context.Context, arg CashTxParams) (TransferTxResult, error) {
+	return store.cashTx(ctx, arg, utils.EntryTypeDeposit)
+}
+
+// WithdrawTx pays cash out of an account into the cash account of its currency within a single db transaction
+// It returns ErrInsufficientFunds if the available balance of the account cannot cover the withdrawal
+func (store *SQLStore) WithdrawTx(ctx context.Context, arg CashTxParams) (TransferTxResult, error) {
+	return store.cashTx(ctx, arg, utils.EntryTypeWithdrawal)
+}
+
+// cashTx moves money between an account and the cash account of its currency,
+// so cash movements are recorded by double entries like any transfer
+func (store *SQLStore) cashTx(ctx context.Context, arg CashTxParams, entryType string) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		account, err := q.GetAccount(ctx, arg.AccountID)
+
+		if err != nil {
+			return err
+		}
+
+		if account.Owner == utils.CashAccountOwner {
+			return ErrCashAccount
+		}
+
+		cashAccount, err := q.GetAccountByCurrency(ctx, GetAccountByCurrencyParams{
+			Owner:    utils.CashAccountOwner,
+			Currency: account.Currency,
+		})
+
+		if err != nil {
+			// the cash accounts are created by the migrations, so a missing one is not the caller's fault
+			return fmt.Errorf("cannot find the %s cash account: %v", account.Currency, err)
+		}
+
+		transferArg := TransferTxParams{
+			FromAccountID: cashAccount.ID,
+			ToAccountID:   account.ID,
+			Amount:        arg.Amount,
+			EntryType:     entryType,
+		}
+
+		if entryType == utils.EntryTypeWithdrawal {
+			transferArg.FromAccountID, transferArg.ToAccountID = account.ID, cashAccount.ID
+		}
+
+		result, err = transfer(ctx, q, transferArg)
+
+		return err
+	})
+
+	return result, err
+
+}
+
+// CreateFeeScheduleTxParams contains all the input params of the create fee schedule transaction
+type CreateFeeScheduleTxParams struct {
+	CreateFeeScheduleParams
+	Tiers []CreateFeeTierParams `json:"tiers"` // their fee schedule id is set by the transaction
+}
+
+// CreateFeeScheduleTxResult contains all the results of the create fee schedule transaction
+type CreateFeeScheduleTxResult struct {
+	FeeSchedule FeeSchedule `json:"fee_schedule"`
+	Tiers       []FeeTier   `json:"tiers"`
+}
+
+// CreateFeeScheduleTx creates the next version of the fee schedule of a currency and transfer type
+// along with its tiers within a single db transaction.
+// The previous versions are kept, so the transfers charged by them still show the schedule that applied
+func (store *SQLStore) CreateFeeScheduleTx(ctx context.Context, arg CreateFeeScheduleTxParams) (CreateFeeScheduleTxResult, error) {
+	var result CreateFeeScheduleTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.FeeSchedule, err = q.CreateFeeSchedule(ctx, arg.CreateFeeScheduleParams)
+
+		if err != nil {
+			return err
+		}
+
+		result.Tiers = make([]FeeTier, 0, len(arg.Tiers))
+
+		for _, tierArg := range arg.Tiers {
+			tierArg.FeeScheduleID = result.FeeSchedule.ID
+
+			tier, err := q.CreateFeeTier(ctx, tierArg)
+
+			if err != nil {
+				return err
+			}
+
+			result.Tiers = append(result.Tiers, tier)
+		}
+
+		return nil
+	})
+
+	return result, err
+
+}
+
+// AccrueInterestTxParams contains all the input params of the accrue interest transaction
+type AccrueInterestTxParams struct {
+	Date time.Time `json:"date"` // the UTC day interest is accrued for, at midnight
+}
+
+// AccrueInterestTxResult contains all the results of the accrue interest transaction
+type AccrueInterestTxResult struct {
+	Accruals int64 `json:"accruals"` // accruals created, the accounts already accrued for the day are not counted
+}
+
+// AccrueInterestTx accrues a day of interest on every active account with an interest rate,
+// based on its balance at the end of the day, within a single db transaction.
+// A day is accrued at most once per account, so it can safely be re-run
+func (store *SQLStore) AccrueInterestTx(ctx context.Context, arg AccrueInterestTxParams) (AccrueInterestTxResult, error) {
+	var result AccrueInterestTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		dayEnd := arg.Date.AddDate(0, 0, 1)
+
+		rates, err := q.ListAccruingInterestRates(ctx, dayEnd)
+
+		if err != nil {
+			return err
+		}
+
+		for _, rate := range rates {
+			balance, err := q.GetAccountBalanceAt(ctx, GetAccountBalanceAtParams{
+				At:        dayEnd,
+				AccountID: rate.AccountID,
+			})
+
+			if err != nil {
+				return err
+			}
+
+			amountMicros, err := utils.DailyInterestMicros(balance, rate.AnnualRateBps, rate.DayCountConvention, arg.Date)
+
+			if err != nil {
+				return err
+			}
+
+			accruals, err := q.CreateInterestAccrual(ctx, CreateInterestAccrualParams{
+				AccountID:          rate.AccountID,
+				AccrualDate:        arg.Date,
+				Balance:            balance,
+				AnnualRateBps:      rate.AnnualRateBps,
+				DayCountConvention: rate.DayCountConvention,
+				AmountMicros:       amountMicros,
+			})
+
+			if err != nil {
+				return err
+			}
+
+			result.Accruals += accruals
+		}
+
+		return nil
+	})
+
+	return result, err
+
+}
+
+// CapitalizeInterestTxParams contains all the input params of the capitalize interest transaction
+type CapitalizeInterestTxParams struct {
+	AccountID int64     `json:"account_id"`
+	PeriodEnd time.Time `json:"period_end"` // the last day capitalized, usually the last day of a month
+}
+
+// CapitalizeInterestTxResult contains all the results of the capitalize interest transaction
+type CapitalizeInterestTxResult struct {
+	Capitalization InterestCapitalization `json:"capitalization"`
+	Transfer       *TransferTxResult      `json:"transfer,omitempty"` // only set when at least a minor unit was paid
+}
+
+// CapitalizeInterestTx pays the interest accrued on an account up to the end of a period out of the interest
+// account of its currency, within a single db transaction. The fraction of a minor unit left over is carried
+// to the next capitalization. It returns sql.ErrNoRows if the period was already capitalized, so it can
+// safely be re-run
+func (store *SQLStore) CapitalizeInterestTx(ctx context.Context, arg CapitalizeInterestTxParams) (CapitalizeInterestTxResult, error) {
+	var result CapitalizeInterestTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		accruedMicros, err := q.GetUncapitalizedInterest(ctx, GetUncapitalizedInterestParams{
+			AccountID: arg.AccountID,
+			PeriodEnd: arg.PeriodEnd,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		carryMicros, err := q.GetInterestCarry(ctx, arg.AccountID)
+
+		if err != nil {
+			return err
+		}
+
+		accruedMicros += carryMicros
+
+		// a concurrent capitalization of the same period waits on the unique index and then inserts nothing
+		result.Capitalization, err = q.CreateInterestCapitalization(ctx, CreateInterestCapitalizationParams{
+			AccountID:     arg.AccountID,
+			PeriodEnd:     arg.PeriodEnd,
+			AccruedMicros: accruedMicros,
+			Amount:        accruedMicros / utils.MicrosPerUnit,
+			CarryMicros:   accruedMicros % utils.MicrosPerUnit,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CapitalizeInterestAccruals(ctx, CapitalizeInterestAccrualsParams{
+			CapitalizationID: sql.NullInt64{Int64: result.Capitalization.ID, Valid: true},
+			AccountID:        arg.AccountID,
+			PeriodEnd:        arg.PeriodEnd,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if result.Capitalization.Amount == 0 {
+			return nil
+		}
+
+		account, err := q.GetAccount(ctx, arg.AccountID)
+
+		if err != nil {
+			return err
+		}
+
+		interestAccount, err := q.GetAccountByCurrency(ctx, GetAccountByCurrencyParams{
+			Owner:    utils.InterestAccountOwner,
+			Currency: account.Currency,
+		})
+
+		if err != nil {
+			// the interest accounts are created by the migrations, so a missing one is not the caller's fault
+			return fmt.Errorf("cannot find the %s interest account: %v", account.Currency, err)
+		}
+
+		transferResult, err := transfer(ctx, q, TransferTxParams{
+			FromAccountID: interestAccount.ID,
+			ToAccountID:   account.ID,
+			Amount:        result.Capitalization.Amount,
+			EntryType:     utils.EntryTypeInterest,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		result.Transfer = &transferResult
+
+		result.Capitalization, err = q.UpdateInterestCapitalizationTransfer(ctx, UpdateInterestCapitalizationTransferParams{
+			ID:         result.Capitalization.ID,
+			TransferID: sql.NullInt64{Int64: transferResult.Transfer.ID, Valid: true},
+		})
+
+		return err
+	})
+
+	return result, err
+
+}
@@ -2,7 +2,9 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"simple_bank/utils"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -12,18 +14,19 @@ func TestTransferTx(t *testing.T) {
 	// Create a NewStore by passing the db connection
 	store := NewStore(testDB)
 
-	// Create two random accounts to transfer from and to
-	account1 := CreateRandomAccount(t)
-	account2 := CreateRandomAccount(t)
-
-	// Print initial Balances
-	fmt.Printf(">> Before Transfer Balance: Account1: %d, Account2: %d", account1.Balance, account2.Balance)
-
 	// Note: run a concurrent transfer transaction
 	n := 5 //run 5 concurrent transactions
 
 	amount := int64(100) // transfer an amount of 100 from account1 to account2
 
+	// Create two random accounts to transfer from and to
+	// account1 must hold enough money to cover all the transfers
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), int64(n)*amount)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	// Print initial Balances
+	fmt.Printf(">> Before Transfer Balance: Account1: %d, Account2: %d", account1.Balance, account2.Balance)
+
 	// declare and create a channel to send to the results and error of the goroutine below
 	errs := make(chan error)
 	results := make(chan TransferTxResult)
@@ -152,12 +155,13 @@ func TestTransferTx(t *testing.T) {
 func TestTransferTxDeadlock(t *testing.T) {
 	store := NewStore(testDB)
 
-	account1 := CreateRandomAccount(t)
-	account2 := CreateRandomAccount(t)
-	fmt.Println(">> before:", account1.Balance, account2.Balance)
-
 	n := 10
 	amount := int64(10)
+
+	// either account may send every transfer before it receives any
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), int64(n)*amount)
+	account2 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), int64(n)*amount)
+
 	errs := make(chan error)
 
 	for i := 0; i < n; i++ {
@@ -196,3 +200,426 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        account1.Balance + 1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// nothing must have been written by the rolled back transaction
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestTransferTxOverdraftLimit(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 0)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	account1, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 50,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(50), account1.OverdraftLimit)
+
+	// run concurrent transfers that together exceed the overdraft limit
+	n := 10
+	amount := int64(10)
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        amount,
+			})
+
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrInsufficientFunds)
+	}
+
+	require.Equal(t, 5, succeeded)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(-50), updatedAccount1.Balance)
+}
+
+// fundAccount sets the account balance so transfers out of it do not run into insufficient funds
+func fundAccount(t *testing.T, account Account, balance int64) Account {
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: balance,
+	})
+	require.NoError(t, err)
+	require.Equal(t, balance, account.Balance)
+
+	return account
+}
+
+func TestTransferTxCrossCurrency(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.KES)
+
+	// a cross currency transfer needs an exchange rate
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		ToAmount:      1305,
+		ExchangeRate:  "130.5000000000",
+	})
+	require.NoError(t, err)
+
+	transfer := result.Transfer
+	require.Equal(t, int64(10), transfer.Amount)
+	require.Equal(t, int64(1305), transfer.ToAmount)
+	require.Equal(t, utils.USD, transfer.FromCurrency)
+	require.Equal(t, utils.KES, transfer.ToCurrency)
+	require.Equal(t, "130.5000000000", transfer.ExchangeRate)
+
+	require.Equal(t, int64(-10), result.FromEntry.Amount)
+	require.Equal(t, int64(1305), result.ToEntry.Amount)
+
+	require.Equal(t, account1.Balance-10, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+1305, result.ToAccount.Balance)
+}
+
+func TestTransferTxInactiveAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	account2, err := testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:        account2.ID,
+		OldStatus: utils.AccountStatusActive,
+		NewStatus: utils.AccountStatusFrozen,
+	})
+	require.NoError(t, err)
+	require.Equal(t, utils.AccountStatusFrozen, account2.Status)
+
+	// a frozen account can neither receive nor send money
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountNotActive)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account1.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountNotActive)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+}
+
+func TestCloseAccountTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 0)
+
+	result, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account1.ID})
+	require.NoError(t, err)
+	require.Equal(t, utils.AccountStatusClosed, result.Account.Status)
+	require.Nil(t, result.Sweep)
+
+	// a closed account stays closed
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account1.ID})
+	require.ErrorIs(t, err, ErrAccountNotActive)
+
+	// the owner can open a new account in the same currency
+	account2, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    account1.Owner,
+		Currency: account1.Currency,
+	})
+	require.NoError(t, err)
+	require.Equal(t, utils.AccountStatusActive, account2.Status)
+}
+
+func TestCloseAccountTxSweep(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	account3, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    account1.Owner,
+		Balance:  0,
+		Currency: utils.KES,
+	})
+	require.NoError(t, err)
+
+	// a balance is only swept when a sweep account is given
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account1.ID})
+	require.ErrorIs(t, err, ErrAccountNotEmpty)
+
+	// sweeps skip the limits, so they never go to another owner
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID:      account1.ID,
+		SweepAccountID: account2.ID,
+	})
+	require.ErrorIs(t, err, ErrSweepAccountOwner)
+
+	// sweeps never convert currencies
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID:      account1.ID,
+		SweepAccountID: account3.ID,
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	// the failed sweeps are rolled back
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, utils.AccountStatusActive, updatedAccount1.Status)
+	require.Equal(t, int64(100), updatedAccount1.Balance)
+}
+
+func TestCloseAccountTxNegativeBalance(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), -10)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	// a debt cannot be swept away
+	_, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID:      account1.ID,
+		SweepAccountID: account2.ID,
+	})
+	require.ErrorIs(t, err, ErrAccountNotEmpty)
+}
+
+func TestReversedToAmount(t *testing.T) {
+	// 3 units sent for 7 received
+	transfer := Transfer{Amount: 3, ToAmount: 7}
+	// amounts whose product overflows an int64
+	large := Transfer{Amount: 1 << 50, ToAmount: 1 << 55}
+
+	testCases := []struct {
+		name           string
+		transfer       Transfer
+		reversedAmount int64
+		toAmount       int64
+	}{
+		{name: "Nothing", transfer: transfer, reversedAmount: 0, toAmount: 0},
+		{name: "RoundedDown", transfer: transfer, reversedAmount: 1, toAmount: 2},
+		{name: "Part", transfer: transfer, reversedAmount: 2, toAmount: 4},
+		{name: "Whole", transfer: transfer, reversedAmount: 3, toAmount: 7},
+		{name: "NoOverflow", transfer: large, reversedAmount: 1 << 49, toAmount: 1 << 54},
+		{name: "WholeLarge", transfer: large, reversedAmount: 1 << 50, toAmount: 1 << 55},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.toAmount, reversedToAmount(tc.transfer, tc.reversedAmount))
+		})
+	}
+
+	// the reversals of a transfer give back exactly its to amount
+	given := int64(0)
+
+	for reversed := int64(1); reversed <= transfer.Amount; reversed++ {
+		given += reversedToAmount(transfer, reversed) - reversedToAmount(transfer, reversed-1)
+	}
+
+	require.Equal(t, transfer.ToAmount, given)
+}
+
+func TestReverseTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	transferred, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        50,
+	})
+	require.NoError(t, err)
+
+	// part of the transfer is refunded first
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transferred.Transfer.ID,
+		Amount:     20,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(20), result.Transfer.ReversedAmount)
+	require.False(t, result.Transfer.IsReversed)
+
+	reversal := result.Reversal.Transfer
+	require.Equal(t, account2.ID, reversal.FromAccountID)
+	require.Equal(t, account1.ID, reversal.ToAccountID)
+	require.Equal(t, int64(20), reversal.Amount)
+	require.Equal(t, sql.NullInt64{Int64: transferred.Transfer.ID, Valid: true}, reversal.ReversalOf)
+	require.Equal(t, int64(-20), result.Reversal.FromEntry.Amount)
+	require.Equal(t, int64(20), result.Reversal.ToEntry.Amount)
+	require.Equal(t, utils.EntryTypeReversal, result.Reversal.FromEntry.TransactionType)
+
+	// the refunds cannot add up to more than the transfer
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transferred.Transfer.ID,
+		Amount:     31,
+	})
+	require.ErrorIs(t, err, ErrInvalidReversalAmount)
+
+	result, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transferred.Transfer.ID,
+		Amount:     30,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(50), result.Transfer.ReversedAmount)
+	require.True(t, result.Transfer.IsReversed)
+	require.Equal(t, account1.Balance, result.Reversal.ToAccount.Balance)
+	require.Equal(t, account2.Balance, result.Reversal.FromAccount.Balance)
+
+	// neither the reversed transfer nor a reversal can be reversed again
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transferred.Transfer.ID,
+		Amount:     1,
+	})
+	require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: result.Reversal.Transfer.ID,
+		Amount:     1,
+	})
+	require.ErrorIs(t, err, ErrTransferNotReversible)
+}
+
+func TestReverseTransferTxCrossCurrency(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 100)
+	account2 := CreateRandomAccountWithCurrency(t, utils.KES)
+
+	transferred, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		ToAmount:      1305,
+		ExchangeRate:  "130.5000000000",
+	})
+	require.NoError(t, err)
+
+	// the receiver gives back the same share of what he/she was credited
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transferred.Transfer.ID,
+		Amount:     3,
+	})
+	require.NoError(t, err)
+
+	reversal := result.Reversal.Transfer
+	require.Equal(t, int64(391), reversal.Amount)
+	require.Equal(t, int64(3), reversal.ToAmount)
+	require.Equal(t, utils.KES, reversal.FromCurrency)
+	require.Equal(t, utils.USD, reversal.ToCurrency)
+
+	// the rounding of the partial refunds never adds up to more than the credited amount
+	result, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transferred.Transfer.ID,
+		Amount:     7,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(1305-391), result.Reversal.Transfer.Amount)
+	require.True(t, result.Transfer.IsReversed)
+	require.Equal(t, account1.Balance, result.Reversal.ToAccount.Balance)
+	require.Equal(t, account2.Balance, result.Reversal.FromAccount.Balance)
+}
+
+func TestDepositAndWithdrawTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.EUR), 0)
+
+	cashAccount, err := testQueries.GetAccountByCurrency(context.Background(), GetAccountByCurrencyParams{
+		Owner:    utils.CashAccountOwner,
+		Currency: utils.EUR,
+	})
+	require.NoError(t, err)
+
+	// the deposit is paid out of the cash account
+	deposit, err := store.DepositTx(context.Background(), CashTxParams{
+		AccountID: account.ID,
+		Amount:    100,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, cashAccount.ID, deposit.Transfer.FromAccountID)
+	require.Equal(t, account.ID, deposit.Transfer.ToAccountID)
+	require.Equal(t, int64(100), deposit.ToAccount.Balance)
+	require.Equal(t, int64(-100), deposit.FromEntry.Amount)
+	require.Equal(t, utils.EntryTypeDeposit, deposit.FromEntry.TransactionType)
+	require.Equal(t, utils.EntryTypeDeposit, deposit.ToEntry.TransactionType)
+
+	// a withdrawal cannot take more than the available balance
+	_, err = store.WithdrawTx(context.Background(), CashTxParams{
+		AccountID: account.ID,
+		Amount:    101,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	withdrawal, err := store.WithdrawTx(context.Background(), CashTxParams{
+		AccountID: account.ID,
+		Amount:    40,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, account.ID, withdrawal.Transfer.FromAccountID)
+	require.Equal(t, cashAccount.ID, withdrawal.Transfer.ToAccountID)
+	require.Equal(t, int64(60), withdrawal.FromAccount.Balance)
+	require.Equal(t, utils.EntryTypeWithdrawal, withdrawal.FromEntry.TransactionType)
+
+	// the cash accounts never take part in plain transfers
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   cashAccount.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrCashAccount)
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"simple_bank/utils"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setTransferLimit(t *testing.T, account Account, perTransaction, daily, monthly int64) {
+	_, err := testQueries.UpsertUserTransferLimit(context.Background(), UpsertUserTransferLimitParams{
+		Username:            account.Owner,
+		Currency:            account.Currency,
+		PerTransactionLimit: sql.NullInt64{Int64: perTransaction, Valid: perTransaction > 0},
+		DailyLimit:          sql.NullInt64{Int64: daily, Valid: daily > 0},
+		MonthlyLimit:        sql.NullInt64{Int64: monthly, Valid: monthly > 0},
+	})
+	require.NoError(t, err)
+}
+
+func TestTransferTxLimits(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.USD), 1000)
+	account2 := CreateRandomAccountWithCurrency(t, utils.USD)
+
+	arg := GetTransferLimitParams{
+		Username: account1.Owner,
+		Currency: utils.USD,
+	}
+
+	// the limits of the user tier apply until they are overridden
+	limit, err := testQueries.GetTransferLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, utils.StandardTier, limit.LimitTier)
+	require.Equal(t, int64(100000), limit.PerTransactionLimit)
+	require.Equal(t, int64(200000), limit.DailyLimit)
+	require.Equal(t, int64(1000000), limit.MonthlyLimit)
+	require.Zero(t, limit.DailyTotal)
+	require.Zero(t, limit.MonthlyTotal)
+
+	setTransferLimit(t, account1, 100, 150, 200)
+
+	testCases := []struct {
+		name   string
+		amount int64
+		err    error
+	}{
+		{name: "AbovePerTransactionLimit", amount: 101, err: ErrTransactionLimitExceeded},
+		{name: "PerTransactionLimit", amount: 100},
+		{name: "AboveDailyLimit", amount: 60, err: ErrDailyLimitExceeded},
+		{name: "DailyLimit", amount: 50},
+	}
+
+	for _, tc := range testCases {
+		_, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        tc.amount,
+		})
+
+		if tc.err != nil {
+			require.ErrorIs(t, err, tc.err, tc.name)
+		} else {
+			require.NoError(t, err, tc.name)
+		}
+	}
+
+	// the transfers that failed are rolled back, so they are not counted
+	limit, err = testQueries.GetTransferLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(150), limit.DailyTotal)
+	require.Equal(t, int64(150), limit.MonthlyTotal)
+
+	// the incoming transfers are not counted
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account1.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// a limit left unset falls back to the tier, the monthly limit still applies
+	setTransferLimit(t, account1, 0, 0, 200)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        60,
+	})
+	require.ErrorIs(t, err, ErrMonthlyLimitExceeded)
+
+	limit, err = testQueries.GetTransferLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(100000), limit.PerTransactionLimit)
+	require.Equal(t, int64(200000), limit.DailyLimit)
+	require.Equal(t, int64(200), limit.MonthlyLimit)
+	require.Equal(t, int64(150), limit.MonthlyTotal)
+}
+
+func TestTransferTxLimitsConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, CreateRandomAccountWithCurrency(t, utils.EUR), 1000)
+	account2 := CreateRandomAccountWithCurrency(t, utils.EUR)
+
+	setTransferLimit(t, account1, 50, 200, 0)
+
+	n := 10
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        50,
+			})
+
+			errs <- err
+		}()
+	}
+
+	// the transfers are serialized on the sender account, so only 4 of them fit in the daily limit
+	succeeded := 0
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrDailyLimitExceeded)
+	}
+
+	require.Equal(t, 4, succeeded)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-200, updatedAccount1.Balance)
+}
+
+func TestListTransferLimits(t *testing.T) {
+	account := CreateRandomAccountWithCurrency(t, utils.KES)
+
+	setTransferLimit(t, account, 0, 5000, 0)
+
+	limits, err := testQueries.ListTransferLimits(context.Background(), account.Owner)
+	require.NoError(t, err)
+	require.Len(t, limits, 3)
+
+	for _, limit := range limits {
+		require.Equal(t, utils.StandardTier, limit.LimitTier)
+
+		if limit.Currency == utils.KES {
+			require.Equal(t, int64(5000), limit.DailyLimit)
+		} else {
+			require.NotEqual(t, int64(5000), limit.DailyLimit)
+		}
+	}
+
+	// moving the user to another tier changes the limits that are not overridden
+	user, err := testQueries.UpdateUserLimitTier(context.Background(), UpdateUserLimitTierParams{
+		Username:  account.Owner,
+		LimitTier: utils.PremiumTier,
+	})
+	require.NoError(t, err)
+	require.Equal(t, utils.PremiumTier, user.LimitTier)
+
+	limit, err := testQueries.GetTransferLimit(context.Background(), GetTransferLimitParams{
+		Username: account.Owner,
+		Currency: utils.KES,
+	})
+	require.NoError(t, err)
+	require.Equal(t, utils.PremiumTier, limit.LimitTier)
+	require.Equal(t, int64(50000000), limit.PerTransactionLimit)
+	require.Equal(t, int64(5000), limit.DailyLimit)
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"simple_bank/utils"
 	"testing"
 	"time"
@@ -10,10 +11,15 @@ import (
 )
 
 func CreateRandomTransfer(t *testing.T, account1, account2 Account) Transfer {
+	amount := utils.RandomMoney()
 	arg := CreateTransferParams{
 		FromAccountID: account1.ID,
 		ToAccountID:   account2.ID,
-		Amount:        utils.RandomMoney(),
+		Amount:        amount,
+		ToAmount:      amount,
+		FromCurrency:  account1.Currency,
+		ToCurrency:    account2.Currency,
+		ExchangeRate:  "1",
 	}
 
 	transfer, err := testQueries.CreateTransfer(context.Background(), arg)
@@ -23,6 +29,9 @@ func CreateRandomTransfer(t *testing.T, account1, account2 Account) Transfer {
 	require.Equal(t, arg.FromAccountID, transfer.FromAccountID)
 	require.Equal(t, arg.ToAccountID, transfer.ToAccountID)
 	require.Equal(t, arg.Amount, transfer.Amount)
+	require.Equal(t, arg.ToAmount, transfer.ToAmount)
+	require.Equal(t, arg.FromCurrency, transfer.FromCurrency)
+	require.Equal(t, arg.ToCurrency, transfer.ToCurrency)
 
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
@@ -62,10 +71,9 @@ func TestListTransfer(t *testing.T) {
 	}
 
 	arg := ListTransfersParams{
-		FromAccountID: account1.ID,
-		ToAccountID:   account1.ID,
-		Limit:         5,
-		Offset:        5,
+		AccountID: account1.ID,
+		Limit:     5,
+		Offset:    5,
 	}
 
 	transfers, err := testQueries.ListTransfers(context.Background(), arg)
@@ -77,3 +85,64 @@ func TestListTransfer(t *testing.T) {
 		require.True(t, transfer.FromAccountID == account1.ID || transfer.ToAccountID == account1.ID)
 	}
 }
+
+func TestListUserTransfers(t *testing.T) {
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	account3 := CreateRandomAccount(t)
+
+	for i := 0; i < 5; i++ {
+		CreateRandomTransfer(t, account1, account2)
+		CreateRandomTransfer(t, account2, account1)
+		CreateRandomTransfer(t, account3, account1)
+	}
+
+	arg := ListUserTransfersParams{
+		Direction: "outgoing",
+		Owner:     account1.Owner,
+		Limit:     10,
+		Offset:    0,
+	}
+
+	transfers, err := testQueries.ListUserTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 5)
+
+	for _, transfer := range transfers {
+		require.Equal(t, account1.ID, transfer.FromAccountID)
+	}
+
+	arg = ListUserTransfersParams{
+		Direction:      "incoming",
+		Owner:          account1.Owner,
+		CounterpartyID: sql.NullInt64{Int64: account3.ID, Valid: true},
+		Limit:          10,
+		Offset:         0,
+	}
+
+	transfers, err = testQueries.ListUserTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 5)
+
+	for _, transfer := range transfers {
+		require.Equal(t, account3.ID, transfer.FromAccountID)
+		require.Equal(t, account1.ID, transfer.ToAccountID)
+	}
+
+	arg = ListUserTransfersParams{
+		Direction: "all",
+		Owner:     account1.Owner,
+		MinAmount: sql.NullInt64{Int64: 500, Valid: true},
+		FromTime:  sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+		Limit:     15,
+		Offset:    0,
+	}
+
+	transfers, err = testQueries.ListUserTransfers(context.Background(), arg)
+	require.NoError(t, err)
+
+	for _, transfer := range transfers {
+		require.GreaterOrEqual(t, transfer.Amount, int64(500))
+		require.True(t, transfer.FromAccountID == account1.ID || transfer.ToAccountID == account1.ID)
+	}
+}
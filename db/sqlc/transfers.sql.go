@@ -7,27 +7,90 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
+const addTransferReversedAmount = `-- name: AddTransferReversedAmount :one
+UPDATE transfers
+SET
+  reversed_amount = reversed_amount + $1,
+  is_reversed = reversed_amount + $1 = amount
+WHERE id = $2
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, from_currency, to_currency, exchange_rate, reversal_of, reversed_amount, is_reversed, fee, fee_schedule_id
+`
+
+type AddTransferReversedAmountParams struct {
+	Amount int64 `json:"amount"`
+	ID     int64 `json:"id"`
+}
+
+// the transfer is marked as reversed once its whole amount is refunded
+func (q *Queries) AddTransferReversedAmount(ctx context.Context, arg AddTransferReversedAmountParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, addTransferReversedAmount, arg.Amount, arg.ID)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.FromCurrency,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.ReversalOf,
+		&i.ReversedAmount,
+		&i.IsReversed,
+		&i.Fee,
+		&i.FeeScheduleID,
+	)
+	return i, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  to_amount,
+  from_currency,
+  to_currency,
+  exchange_rate,
+  reversal_of,
+  fee,
+  fee_schedule_id
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 ) 
-RETURNING id, from_account_id, to_account_id, amount, created_at
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, from_currency, to_currency, exchange_rate, reversal_of, reversed_amount, is_reversed, fee, fee_schedule_id
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64         `json:"from_account_id"`
+	ToAccountID   int64         `json:"to_account_id"`
+	Amount        int64         `json:"amount"`
+	ToAmount      int64         `json:"to_amount"`
+	FromCurrency  string        `json:"from_currency"`
+	ToCurrency    string        `json:"to_currency"`
+	ExchangeRate  string        `json:"exchange_rate"`
+	ReversalOf    sql.NullInt64 `json:"reversal_of"`
+	Fee           int64         `json:"fee"`
+	FeeScheduleID sql.NullInt64 `json:"fee_schedule_id"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.FromCurrency,
+		arg.ToCurrency,
+		arg.ExchangeRate,
+		arg.ReversalOf,
+		arg.Fee,
+		arg.FeeScheduleID,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -35,12 +98,21 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.FromCurrency,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.ReversalOf,
+		&i.ReversedAmount,
+		&i.IsReversed,
+		&i.Fee,
+		&i.FeeScheduleID,
 	)
 	return i, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, from_currency, to_currency, exchange_rate, reversal_of, reversed_amount, is_reversed, fee, fee_schedule_id FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -53,31 +125,143 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.FromCurrency,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.ReversalOf,
+		&i.ReversedAmount,
+		&i.IsReversed,
+		&i.Fee,
+		&i.FeeScheduleID,
+	)
+	return i, err
+}
+
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, from_currency, to_currency, exchange_rate, reversal_of, reversed_amount, is_reversed, fee, fee_schedule_id FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.FromCurrency,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.ReversalOf,
+		&i.ReversedAmount,
+		&i.IsReversed,
+		&i.Fee,
+		&i.FeeScheduleID,
 	)
 	return i, err
 }
 
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, from_currency, to_currency, exchange_rate, reversal_of, reversed_amount, is_reversed, fee, fee_schedule_id FROM transfers
 WHERE 
     from_account_id = $1 OR
-    to_account_id = $2
+    to_account_id = $1
 ORDER BY id
-LIMIT $3
-OFFSET $4
+LIMIT $2
+OFFSET $3
 `
 
 type ListTransfersParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Limit         int32 `json:"limit"`
-	Offset        int32 `json:"offset"`
+	AccountID int64 `json:"account_id"`
+	Limit     int32 `json:"limit"`
+	Offset    int32 `json:"offset"`
 }
 
 func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
-	rows, err := q.db.QueryContext(ctx, listTransfers,
-		arg.FromAccountID,
-		arg.ToAccountID,
+	rows, err := q.db.QueryContext(ctx, listTransfers, arg.AccountID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.FromCurrency,
+			&i.ToCurrency,
+			&i.ExchangeRate,
+			&i.ReversalOf,
+			&i.ReversedAmount,
+			&i.IsReversed,
+			&i.Fee,
+			&i.FeeScheduleID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserTransfers = `-- name: ListUserTransfers :many
+SELECT t.id, t.from_account_id, t.to_account_id, t.amount, t.created_at, t.to_amount, t.from_currency, t.to_currency, t.exchange_rate, t.reversal_of, t.reversed_amount, t.is_reversed, t.fee, t.fee_schedule_id FROM transfers t
+JOIN accounts fa ON fa.id = t.from_account_id
+JOIN accounts ta ON ta.id = t.to_account_id
+WHERE
+    (
+      ($1::text IN ('all', 'outgoing') AND fa.owner = $2) OR
+      ($1::text IN ('all', 'incoming') AND ta.owner = $2)
+    )
+    AND ($3::bigint IS NULL OR
+      t.from_account_id = $3 OR
+      t.to_account_id = $3)
+    AND ($4::bigint IS NULL OR t.amount >= $4)
+    AND ($5::bigint IS NULL OR t.amount <= $5)
+    AND ($6::timestamptz IS NULL OR t.created_at >= $6)
+    AND ($7::timestamptz IS NULL OR t.created_at <= $7)
+ORDER BY t.id
+LIMIT $8
+OFFSET $9
+`
+
+type ListUserTransfersParams struct {
+	Direction      string        `json:"direction"`
+	Owner          string        `json:"owner"`
+	CounterpartyID sql.NullInt64 `json:"counterparty_id"`
+	MinAmount      sql.NullInt64 `json:"min_amount"`
+	MaxAmount      sql.NullInt64 `json:"max_amount"`
+	FromTime       sql.NullTime  `json:"from_time"`
+	ToTime         sql.NullTime  `json:"to_time"`
+	Limit          int32         `json:"limit"`
+	Offset         int32         `json:"offset"`
+}
+
+func (q *Queries) ListUserTransfers(ctx context.Context, arg ListUserTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listUserTransfers,
+		arg.Direction,
+		arg.Owner,
+		arg.CounterpartyID,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.FromTime,
+		arg.ToTime,
 		arg.Limit,
 		arg.Offset,
 	)
@@ -94,6 +278,15 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.FromCurrency,
+			&i.ToCurrency,
+			&i.ExchangeRate,
+			&i.ReversalOf,
+			&i.ReversedAmount,
+			&i.IsReversed,
+			&i.Fee,
+			&i.FeeScheduleID,
 		); err != nil {
 			return nil, err
 		}
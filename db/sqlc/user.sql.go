@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -17,7 +19,7 @@ INSERT INTO users (
   email
 ) VALUES (
   $1, $2, $3, $4
-) RETURNING username, harsh_password, full_name, email, password_change_at, created_at
+) RETURNING username, harsh_password, full_name, email, password_change_at, created_at, role, is_email_verified, limit_tier, role_change_at
 `
 
 type CreateUserParams struct {
@@ -42,12 +44,16 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangeAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.LimitTier,
+		&i.RoleChangeAt,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, harsh_password, full_name, email, password_change_at, created_at FROM users
+SELECT username, harsh_password, full_name, email, password_change_at, created_at, role, is_email_verified, limit_tier, role_change_at FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -61,6 +67,236 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.Email,
 		&i.PasswordChangeAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.LimitTier,
+		&i.RoleChangeAt,
+	)
+	return i, err
+}
+
+const getUserAuthChangeAt = `-- name: GetUserAuthChangeAt :one
+SELECT password_change_at, role_change_at FROM users
+WHERE username = $1 LIMIT 1
+`
+
+type GetUserAuthChangeAtRow struct {
+	PasswordChangeAt time.Time `json:"password_change_at"`
+	RoleChangeAt     time.Time `json:"role_change_at"`
+}
+
+// the access tokens issued before the last password or role change are no longer valid
+func (q *Queries) GetUserAuthChangeAt(ctx context.Context, username string) (GetUserAuthChangeAtRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserAuthChangeAt, username)
+	var i GetUserAuthChangeAtRow
+	err := row.Scan(&i.PasswordChangeAt, &i.RoleChangeAt)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, harsh_password, full_name, email, password_change_at, created_at, role, is_email_verified, limit_tier, role_change_at FROM users
+WHERE email = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HarshPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.LimitTier,
+		&i.RoleChangeAt,
+	)
+	return i, err
+}
+
+const getUserForUpdate = `-- name: GetUserForUpdate :one
+SELECT username, harsh_password, full_name, email, password_change_at, created_at, role, is_email_verified, limit_tier, role_change_at FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetUserForUpdate(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserForUpdate, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HarshPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.LimitTier,
+		&i.RoleChangeAt,
+	)
+	return i, err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET
+  full_name = COALESCE($1, full_name),
+  email = COALESCE($2, email),
+  is_email_verified = CASE
+    WHEN $2::varchar IS NULL OR $2 = email THEN is_email_verified
+    ELSE false
+  END
+WHERE username = $3
+RETURNING username, harsh_password, full_name, email, password_change_at, created_at, role, is_email_verified, limit_tier, role_change_at
+`
+
+type UpdateUserParams struct {
+	FullName sql.NullString `json:"full_name"`
+	Email    sql.NullString `json:"email"`
+	Username string         `json:"username"`
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUser, arg.FullName, arg.Email, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HarshPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.LimitTier,
+		&i.RoleChangeAt,
+	)
+	return i, err
+}
+
+const updateUserLimitTier = `-- name: UpdateUserLimitTier :one
+UPDATE users
+SET limit_tier = $1
+WHERE username = $2
+RETURNING username, harsh_password, full_name, email, password_change_at, created_at, role, is_email_verified, limit_tier, role_change_at
+`
+
+type UpdateUserLimitTierParams struct {
+	LimitTier string `json:"limit_tier"`
+	Username  string `json:"username"`
+}
+
+func (q *Queries) UpdateUserLimitTier(ctx context.Context, arg UpdateUserLimitTierParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserLimitTier, arg.LimitTier, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HarshPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.LimitTier,
+		&i.RoleChangeAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET harsh_password = $1,
+  password_change_at = $2
+WHERE username = $3
+RETURNING username, harsh_password, full_name, email, password_change_at, created_at, role, is_email_verified, limit_tier, role_change_at
+`
+
+type UpdateUserPasswordParams struct {
+	HarshPassword    string    `json:"harsh_password"`
+	PasswordChangeAt time.Time `json:"password_change_at"`
+	Username         string    `json:"username"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.HarshPassword, arg.PasswordChangeAt, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HarshPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.LimitTier,
+		&i.RoleChangeAt,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET role = $1,
+  role_change_at = CASE WHEN role = $1 THEN role_change_at ELSE now() END
+WHERE username = $2
+RETURNING username, harsh_password, full_name, email, password_change_at, created_at, role, is_email_verified, limit_tier, role_change_at
+`
+
+type UpdateUserRoleParams struct {
+	Role     string `json:"role"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.Role, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HarshPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.LimitTier,
+		&i.RoleChangeAt,
+	)
+	return i, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1
+AND email = $2
+RETURNING username, harsh_password, full_name, email, password_change_at, created_at, role, is_email_verified, limit_tier, role_change_at
+`
+
+type VerifyUserEmailParams struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.Username, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HarshPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.LimitTier,
+		&i.RoleChangeAt,
 	)
 	return i, err
 }
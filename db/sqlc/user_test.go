@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"simple_bank/utils"
 	"testing"
 	"time"
@@ -33,6 +34,8 @@ func CreateRandomUser(t *testing.T) User {
 	
 	require.Equal(t, arg.FullName, user.FullName)
 	require.Equal(t, arg.Email, user.Email)
+	require.Equal(t, utils.CustomerRole, user.Role)
+	require.Equal(t, utils.StandardTier, user.LimitTier)
 
 	
 	
@@ -66,3 +69,139 @@ func TestGetUse(t *testing.T) {
 	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
 
 }
+
+func TestUpdateUserRole(t *testing.T) {
+	user1 := CreateRandomUser(t)
+
+	arg := UpdateUserRoleParams{
+		Username: user1.Username,
+		Role:     utils.BankerRole,
+	}
+
+	user2, err := testQueries.UpdateUserRole(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, user2)
+
+	require.Equal(t, user1.Username, user2.Username)
+	require.Equal(t, arg.Role, user2.Role)
+	require.True(t, user2.RoleChangeAt.After(user1.RoleChangeAt))
+
+	// setting the same role again keeps the access tokens issued since the change valid
+	user3, err := testQueries.UpdateUserRole(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user2.RoleChangeAt, user3.RoleChangeAt)
+
+	authChangeAt, err := testQueries.GetUserAuthChangeAt(context.Background(), user1.Username)
+	require.NoError(t, err)
+	require.WithinDuration(t, user2.RoleChangeAt, authChangeAt.RoleChangeAt, time.Microsecond)
+}
+
+func TestUpdateUserOnlyFullName(t *testing.T) {
+	oldUser := CreateRandomUser(t)
+
+	newFullName := utils.RandomOwner()
+	updatedUser, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username: oldUser.Username,
+		FullName: sql.NullString{String: newFullName, Valid: true},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, newFullName, updatedUser.FullName)
+	require.Equal(t, oldUser.Email, updatedUser.Email)
+	require.Equal(t, oldUser.HarshPassword, updatedUser.HarshPassword)
+}
+
+func TestUpdateUserTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	oldUser := CreateRandomUser(t)
+	admin := CreateRandomUser(t)
+
+	_, err := testQueries.VerifyUserEmail(context.Background(), VerifyUserEmailParams{
+		Username: oldUser.Username,
+		Email:    oldUser.Email,
+	})
+	require.NoError(t, err)
+
+	arg := UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username: oldUser.Username,
+			FullName: sql.NullString{String: utils.RandomOwner(), Valid: true},
+			Email:    sql.NullString{String: utils.RandomEmail(), Valid: true},
+		},
+		ChangedBy:             admin.Username,
+		VerifyEmailSecretCode: utils.RandomString(64),
+		VerifyEmailExpiredAt:  time.Now().Add(time.Hour),
+	}
+
+	result, err := store.UpdateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.FullName.String, result.User.FullName)
+	require.Equal(t, arg.Email.String, result.User.Email)
+
+	// the new email has to be verified again
+	require.False(t, result.User.IsEmailVerified)
+	require.NotZero(t, result.VerifyEmail.ID)
+	require.Equal(t, arg.Email.String, result.VerifyEmail.Email)
+
+	// every changed field is recorded
+	require.Len(t, result.Histories, 2)
+
+	histories, err := testQueries.ListUserHistories(context.Background(), ListUserHistoriesParams{
+		Username: oldUser.Username,
+		Limit:    5,
+		Offset:   0,
+	})
+	require.NoError(t, err)
+	require.Len(t, histories, 2)
+
+	require.Equal(t, "full_name", histories[0].Field)
+	require.Equal(t, oldUser.FullName, histories[0].OldValue)
+	require.Equal(t, arg.FullName.String, histories[0].NewValue)
+
+	require.Equal(t, "email", histories[1].Field)
+	require.Equal(t, oldUser.Email, histories[1].OldValue)
+	require.Equal(t, arg.Email.String, histories[1].NewValue)
+
+	for _, history := range histories {
+		require.Equal(t, admin.Username, history.ChangedBy)
+	}
+}
+
+func TestUpdateUserTxUnchanged(t *testing.T) {
+	store := NewStore(testDB)
+	oldUser := CreateRandomUser(t)
+
+	// setting the current values changes nothing, so nothing is recorded
+	result, err := store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username: oldUser.Username,
+			FullName: sql.NullString{String: oldUser.FullName, Valid: true},
+			Email:    sql.NullString{String: oldUser.Email, Valid: true},
+		},
+		ChangedBy: oldUser.Username,
+	})
+	require.NoError(t, err)
+	require.Empty(t, result.Histories)
+	require.Zero(t, result.VerifyEmail.ID)
+}
+
+func TestUpdateUserTxDuplicateEmail(t *testing.T) {
+	store := NewStore(testDB)
+
+	user1 := CreateRandomUser(t)
+	user2 := CreateRandomUser(t)
+
+	_, err := store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username: user1.Username,
+			Email:    sql.NullString{String: user2.Email, Valid: true},
+		},
+		ChangedBy:             user1.Username,
+		VerifyEmailSecretCode: utils.RandomString(64),
+		VerifyEmailExpiredAt:  time.Now().Add(time.Hour),
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateUserTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	hashedPassword, err := utils.HashPassword(utils.RandomString(6))
+	require.NoError(t, err)
+
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:      utils.RandomOwner(),
+			HarshPassword: hashedPassword,
+			FullName:      utils.RandomOwner(),
+			Email:         utils.RandomEmail(),
+		},
+		VerifyEmailSecretCode: utils.RandomString(64),
+		VerifyEmailExpiredAt:  time.Now().Add(time.Hour),
+	}
+
+	result, err := store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.Username, result.User.Username)
+	require.False(t, result.User.IsEmailVerified)
+
+	require.NotZero(t, result.VerifyEmail.ID)
+	require.Equal(t, arg.Username, result.VerifyEmail.Username)
+	require.Equal(t, arg.Email, result.VerifyEmail.Email)
+	require.Equal(t, arg.VerifyEmailSecretCode, result.VerifyEmail.SecretCode)
+	require.False(t, result.VerifyEmail.IsUsed)
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+
+	verifyEmail, err := testQueries.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: utils.RandomString(64),
+		ExpiredAt:  time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// a wrong code is rejected
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: utils.RandomString(64),
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	arg := VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	}
+
+	result, err := store.VerifyEmailTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.VerifyEmail.IsUsed)
+	require.True(t, result.User.IsEmailVerified)
+
+	// the code can only be used once
+	_, err = store.VerifyEmailTx(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestVerifyEmailTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+
+	verifyEmail, err := testQueries.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: utils.RandomString(64),
+		ExpiredAt:  time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
@@ -0,0 +1,63 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"math/big"
+)
+
+// exchangeRateScale is the number of decimal places an exchange rate is stored with
+const exchangeRateScale = 10
+
+// Different types of error returned by the fx package
+var (
+	// ErrRateNotFound is returned when the provider has no rate for a currency pair
+	ErrRateNotFound = errors.New("exchange rate not found")
+	// ErrAmountTooSmall is returned by Convert when the converted amount rounds down to zero
+	ErrAmountTooSmall = errors.New("amount is too small to convert")
+	// ErrAmountTooLarge is returned by Convert when the converted amount does not fit in an int64
+	ErrAmountTooLarge = errors.New("converted amount is too large")
+)
+
+// RateProvider is an interface for looking up foreign exchange rates
+type RateProvider interface {
+	// GetRate returns the rate to convert an amount in the from currency to the to currency
+	GetRate(ctx context.Context, from, to string) (Rate, error)
+}
+
+// Rate is the number of units of the To currency bought by one unit of the From currency
+type Rate struct {
+	From  string
+	To    string
+	Value *big.Rat
+}
+
+// Convert converts an amount in the From currency to the To currency
+// the amount is multiplied by the rate as it is stored on the transfer, so the result can be reproduced from it,
+// and rounded down so the bank never credits more than it debits
+func (rate Rate) Convert(amount int64) (int64, error) {
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), rate.stored())
+
+	result := new(big.Int).Quo(converted.Num(), converted.Denom())
+
+	if !result.IsInt64() {
+		return 0, ErrAmountTooLarge
+	}
+
+	if result.Sign() <= 0 {
+		return 0, ErrAmountTooSmall
+	}
+
+	return result.Int64(), nil
+}
+
+// String returns the rate as a decimal string, the way it is stored on a transfer
+func (rate Rate) String() string {
+	return rate.Value.FloatString(exchangeRateScale)
+}
+
+// stored returns the rate rounded to the decimal places it is stored with
+func (rate Rate) stored() *big.Rat {
+	value, _ := new(big.Rat).SetString(rate.String())
+	return value
+}
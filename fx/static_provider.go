@@ -0,0 +1,71 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"simple_bank/utils"
+	"strings"
+)
+
+// StaticRateProvider serves fixed exchange rates loaded from configuration
+type StaticRateProvider struct {
+	rates map[string]*big.Rat
+}
+
+// NewStaticRateProvider creates a new static rate provider from a comma separated
+// list of rates such as "USD/KES=130.50,EUR/USD=1.08"
+// the inverse of every configured pair is served as well
+func NewStaticRateProvider(spec string) (RateProvider, error) {
+	provider := &StaticRateProvider{
+		rates: make(map[string]*big.Rat),
+	}
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+
+		if item == "" {
+			continue
+		}
+
+		pair, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid exchange rate %q: must be of the form FROM/TO=RATE", item)
+		}
+
+		from, to, ok := strings.Cut(strings.TrimSpace(pair), "/")
+		if !ok || !utils.IsCurrencySupported(from) || !utils.IsCurrencySupported(to) || from == to {
+			return nil, fmt.Errorf("invalid currency pair %q", pair)
+		}
+
+		rate, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid exchange rate %q for %s: must be a positive number", value, pair)
+		}
+
+		provider.rates[rateKey(from, to)] = rate
+	}
+
+	return provider, nil
+}
+
+// GetRate returns the configured rate for the currency pair, or the inverse of the reverse pair
+func (provider *StaticRateProvider) GetRate(ctx context.Context, from, to string) (Rate, error) {
+	if from == to {
+		return Rate{From: from, To: to, Value: big.NewRat(1, 1)}, nil
+	}
+
+	if rate, ok := provider.rates[rateKey(from, to)]; ok {
+		return Rate{From: from, To: to, Value: rate}, nil
+	}
+
+	if rate, ok := provider.rates[rateKey(to, from)]; ok {
+		return Rate{From: from, To: to, Value: new(big.Rat).Inv(rate)}, nil
+	}
+
+	return Rate{}, fmt.Errorf("%w: %s to %s", ErrRateNotFound, from, to)
+}
+
+func rateKey(from, to string) string {
+	return from + "/" + to
+}
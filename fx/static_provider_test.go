@@ -0,0 +1,84 @@
+package fx
+
+import (
+	"context"
+	"math"
+	"simple_bank/utils"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticRateProvider(t *testing.T) {
+	provider, err := NewStaticRateProvider("USD/KES=130.50, EUR/USD=1.08")
+	require.NoError(t, err)
+
+	rate, err := provider.GetRate(context.Background(), utils.USD, utils.KES)
+	require.NoError(t, err)
+	require.Equal(t, utils.USD, rate.From)
+	require.Equal(t, utils.KES, rate.To)
+	require.Equal(t, "130.5000000000", rate.String())
+	requireConvert(t, rate, 100, 13050)
+
+	// the inverse of a configured pair is served as well
+	rate, err = provider.GetRate(context.Background(), utils.USD, utils.EUR)
+	require.NoError(t, err)
+	require.Equal(t, "0.9259259259", rate.String())
+	requireConvert(t, rate, 100, 92) // rounded down
+
+	// the amount is converted with the stored rate, not the exact inverse
+	requireConvert(t, rate, 10000000000, 9259259259)
+
+	rate, err = provider.GetRate(context.Background(), utils.EUR, utils.EUR)
+	require.NoError(t, err)
+	requireConvert(t, rate, 100, 100)
+
+	_, err = provider.GetRate(context.Background(), utils.EUR, utils.KES)
+	require.ErrorIs(t, err, ErrRateNotFound)
+}
+
+func TestInvalidStaticRates(t *testing.T) {
+	specs := []string{
+		"USD-KES=130",
+		"USD/KES",
+		"USD/XXX=1",
+		"USD/USD=1",
+		"USD/KES=abc",
+		"USD/KES=-1",
+		"USD/KES=0",
+	}
+
+	for _, spec := range specs {
+		provider, err := NewStaticRateProvider(spec)
+		require.Error(t, err, spec)
+		require.Nil(t, provider)
+	}
+
+	provider, err := NewStaticRateProvider("")
+	require.NoError(t, err)
+	require.NotNil(t, provider)
+}
+
+func TestConvertOutOfRange(t *testing.T) {
+	provider, err := NewStaticRateProvider("USD/KES=130.50")
+	require.NoError(t, err)
+
+	rate, err := provider.GetRate(context.Background(), utils.USD, utils.KES)
+	require.NoError(t, err)
+
+	// the converted amount cannot wrap around
+	_, err = rate.Convert(math.MaxInt64 / 100)
+	require.ErrorIs(t, err, ErrAmountTooLarge)
+
+	rate, err = provider.GetRate(context.Background(), utils.KES, utils.USD)
+	require.NoError(t, err)
+
+	_, err = rate.Convert(1)
+	require.ErrorIs(t, err, ErrAmountTooSmall)
+}
+
+func requireConvert(t *testing.T, rate Rate, amount int64, expected int64) {
+	converted, err := rate.Convert(amount)
+	require.NoError(t, err)
+	require.Equal(t, expected, converted)
+}
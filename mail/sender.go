@@ -0,0 +1,110 @@
+package mail
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+// EmailSender is an interface for sending emails
+type EmailSender interface {
+	// SendEmail sends an html email to the given addresses
+	SendEmail(subject string, content string, to []string) error
+}
+
+// SMTPSender sends emails through an SMTP server using plain auth
+type SMTPSender struct {
+	host        string
+	port        int
+	username    string
+	password    string
+	fromName    string
+	fromAddress string
+}
+
+// NewSMTPSender creates a new SMTP sender
+func NewSMTPSender(host string, port int, username, password, fromName, fromAddress string) EmailSender {
+	return &SMTPSender{
+		host:        host,
+		port:        port,
+		username:    username,
+		password:    password,
+		fromName:    fromName,
+		fromAddress: fromAddress,
+	}
+}
+
+// SendEmail sends an html email to the given addresses
+func (sender *SMTPSender) SendEmail(subject string, content string, to []string) error {
+	if len(to) == 0 {
+		return fmt.Errorf("no recipient")
+	}
+
+	// header lines must not let a subject break out into other headers
+	subject = strings.NewReplacer("\r", "", "\n", "").Replace(subject)
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s <%s>\r\n", sender.fromName, sender.fromAddress)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=\"UTF-8\"\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(content)
+
+	auth := smtp.PlainAuth("", sender.username, sender.password, sender.host)
+	address := fmt.Sprintf("%s:%d", sender.host, sender.port)
+
+	return smtp.SendMail(address, auth, sender.fromAddress, to, []byte(msg.String()))
+}
+
+// Email is an email recorded by the FakeSender
+type Email struct {
+	Subject string
+	Content string
+	To      []string
+}
+
+// FakeSender keeps sent emails in memory instead of sending them, for tests
+type FakeSender struct {
+	mu     sync.Mutex
+	emails []Email
+	err    error
+}
+
+// NewFakeSender creates a new in-memory sender
+func NewFakeSender() *FakeSender {
+	return &FakeSender{}
+}
+
+// SetError makes the next emails fail with err instead of being recorded, nil makes them succeed again
+func (sender *FakeSender) SetError(err error) {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	sender.err = err
+}
+
+// SendEmail records the email
+func (sender *FakeSender) SendEmail(subject string, content string, to []string) error {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	if sender.err != nil {
+		return sender.err
+	}
+
+	sender.emails = append(sender.emails, Email{Subject: subject, Content: content, To: to})
+	return nil
+}
+
+// Emails returns all the emails recorded so far
+func (sender *FakeSender) Emails() []Email {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	emails := make([]Email, len(sender.emails))
+	copy(emails, sender.emails)
+	return emails
+}
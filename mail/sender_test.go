@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeSender(t *testing.T) {
+	sender := NewFakeSender()
+
+	err := sender.SendEmail("subject", "<p>content</p>", []string{"user@email.com"})
+	require.NoError(t, err)
+
+	emails := sender.Emails()
+	require.Len(t, emails, 1)
+	require.Equal(t, "subject", emails[0].Subject)
+	require.Equal(t, "<p>content</p>", emails[0].Content)
+	require.Equal(t, []string{"user@email.com"}, emails[0].To)
+
+	// failed emails are not recorded
+	sender.SetError(errors.New("send failed"))
+
+	err = sender.SendEmail("subject", "<p>content</p>", []string{"user@email.com"})
+	require.Error(t, err)
+	require.Len(t, sender.Emails(), 1)
+}
+
+func TestSMTPSenderNoRecipient(t *testing.T) {
+	sender := NewSMTPSender("localhost", 25, "user", "secret", "Simple Bank", "bank@email.com")
+
+	err := sender.SendEmail("subject", "content", nil)
+	require.Error(t, err)
+}
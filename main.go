@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"log"
+	"os"
 	"simple_bank/api"
 	db "simple_bank/db/sqlc"
 	"simple_bank/utils"
@@ -27,15 +30,28 @@ func main() {
 	// create a new store and pass it the db connection
 	store := db.NewStore(conn)
 
+	// the verify-ledger command checks the books instead of serving requests
+	if len(os.Args) > 1 && os.Args[1] == "verify-ledger" {
+		verifyLedger(store)
+		return
+	}
+
 	// create a new server and pass the store
 	server, err := api.NewServer(config, store)
 
 	if err != nil {
-
-		//log.Fatal("cannot create server:", err)
-		log.Printf("%v cannot create server", err.Error())
+		log.Fatal("Cannot create server: ", err)
 	}
 
+	// rotate the token keys whenever the .env file changes
+	utils.WatchConfigs(func(config utils.Config) {
+		err := server.UpdateTokenKeys(config)
+
+		if err != nil {
+			log.Printf("cannot update token keys: %v", err)
+		}
+	})
+
 	// start the server by calling Start func and passing it the server address
 	err = server.Start(config.ServerAddress)
 
@@ -44,3 +60,26 @@ func main() {
 	}
 
 }
+
+// verifyLedger prints the ledger verification report as JSON
+// it exits with status 1 when the books do not balance, so it can be used in scripts
+func verifyLedger(store db.Store) {
+	result, err := store.VerifyLedgerTx(context.Background())
+
+	if err != nil {
+		log.Fatal("Cannot verify ledger: ", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	err = encoder.Encode(result)
+
+	if err != nil {
+		log.Fatal("Cannot print ledger report: ", err)
+	}
+
+	if !result.Balanced {
+		os.Exit(1)
+	}
+}
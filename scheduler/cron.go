@@ -0,0 +1,210 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCronSearch bounds the search for the next time of a schedule
+// so a schedule that never fires, such as "0 0 30 2 *", cannot loop forever
+const maxCronSearch = 5 * 366 * 24 * time.Hour
+
+// ErrNoNextRun is returned when a schedule has no time left to fire at
+var ErrNoNextRun = errors.New("schedule has no next run")
+
+// cronMacros are the shortcuts accepted in place of the five fields
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField describes the range of values a cron field accepts
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // both 0 and 7 are sunday
+}
+
+// CronSchedule is a parsed cron expression, its times are evaluated in UTC
+type CronSchedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+
+	// when both day fields are restricted a day matches if either of them does, like cron does
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+// ParseCron parses a standard five field cron expression such as "30 8 * * 1-5"
+// every field accepts *, single values, ranges, lists and steps such as */15 or 1-10/2.
+// The @yearly, @monthly, @weekly, @daily and @hourly shortcuts are accepted as well
+func ParseCron(spec string) (*CronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if macro, ok := cronMacros[spec]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: must have %d fields", spec, len(cronFields))
+	}
+
+	bits := make([]uint64, len(cronFields))
+
+	for i, field := range fields {
+		var err error
+
+		bits[i], err = parseCronField(field, cronFields[i])
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+		}
+	}
+
+	schedule := &CronSchedule{
+		minute:        bits[0],
+		hour:          bits[1],
+		dayOfMonth:    bits[2],
+		month:         bits[3],
+		dayOfWeek:     bits[4],
+		anyDayOfMonth: strings.HasPrefix(fields[2], "*"),
+		anyDayOfWeek:  strings.HasPrefix(fields[4], "*"),
+	}
+
+	// sunday may be written as 7
+	if schedule.dayOfWeek&(1<<7) != 0 {
+		schedule.dayOfWeek |= 1
+	}
+
+	return schedule, nil
+}
+
+// parseCronField returns a bit set of all the values a cron field matches
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var bits uint64
+
+	for _, item := range strings.Split(field, ",") {
+		expr, stepValue, hasStep := strings.Cut(item, "/")
+
+		step := 1
+
+		if hasStep {
+			var err error
+
+			step, err = strconv.Atoi(stepValue)
+
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepValue, bounds.name)
+			}
+		}
+
+		start, end := bounds.min, bounds.max
+
+		if expr != "*" {
+			from, to, isRange := strings.Cut(expr, "-")
+
+			var err error
+
+			start, err = parseCronValue(from, bounds)
+
+			if err != nil {
+				return 0, err
+			}
+
+			end = start
+
+			if isRange {
+				end, err = parseCronValue(to, bounds)
+
+				if err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// a step after a single value runs to the end of the range, e.g. 5/15
+				end = bounds.max
+			}
+
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q in %s field", expr, bounds.name)
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseCronValue(value string, bounds cronField) (int, error) {
+	number, err := strconv.Atoi(value)
+
+	if err != nil || number < bounds.min || number > bounds.max {
+		return 0, fmt.Errorf("invalid value %q in %s field: must be between %d and %d", value, bounds.name, bounds.min, bounds.max)
+	}
+
+	return number, nil
+}
+
+// Next returns the first time after the given time that the schedule fires at
+// It returns ErrNoNextRun if the schedule never fires again
+func (schedule *CronSchedule) Next(after time.Time) (time.Time, error) {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxCronSearch)
+
+	for t.Before(limit) {
+		if schedule.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if !schedule.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if schedule.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+
+		if schedule.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t, nil
+	}
+
+	return time.Time{}, ErrNoNextRun
+}
+
+func (schedule *CronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := schedule.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := schedule.dayOfWeek&(1<<uint(t.Weekday())) != 0
+
+	if schedule.anyDayOfMonth || schedule.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
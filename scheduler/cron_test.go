@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+		"@every",
+	}
+
+	for _, spec := range specs {
+		_, err := ParseCron(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// a wednesday
+	after := time.Date(2023, time.March, 15, 10, 30, 20, 0, time.UTC)
+
+	testCases := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2023, time.March, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2023, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2023, time.March, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 9 1 * *", time.Date(2023, time.April, 1, 9, 0, 0, 0, time.UTC)},
+		{"30 8 * * 1-5", time.Date(2023, time.March, 16, 8, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,20 * *", time.Date(2023, time.March, 20, 12, 0, 0, 0, time.UTC)},
+		// when both days are restricted either of them matches
+		{"0 0 1 * 5", time.Date(2023, time.March, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2023, time.March, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2023, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.spec, func(t *testing.T) {
+			schedule, err := ParseCron(tc.spec)
+			require.NoError(t, err)
+
+			next, err := schedule.Next(after)
+			require.NoError(t, err)
+			require.Equal(t, tc.next, next)
+		})
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	schedule, err := ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+
+	_, err = schedule.Next(time.Now())
+	require.ErrorIs(t, err, ErrNoNextRun)
+}
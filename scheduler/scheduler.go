@@ -0,0 +1,233 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	db "simple_bank/db/sqlc"
+	"simple_bank/utils"
+	"time"
+)
+
+// snapshotSettleTime is how long after the end of a day its balance snapshots are taken,
+// so the transfers that started before midnight have committed by then
+const snapshotSettleTime = time.Hour
+
+// Scheduler runs the background jobs of the bank: it executes the scheduled transfers once they are due,
+// releases the expired holds, takes the balance snapshots and accrues the interest at the end of every day
+// and capitalizes the interest at the end of every month.
+// Several schedulers can safely run against the same db, every run is claimed by exactly one of them
+// and the snapshots, accruals and capitalizations of a day are only made once
+type Scheduler struct {
+	store       db.Store
+	interval    time.Duration // how often due transfers are looked for
+	maxAttempts int32         // attempts of a single run before it is given up
+	retryDelay  time.Duration // wait between two attempts of the same run
+
+	lastSnapshotAt time.Time // the end of the last day this scheduler took the snapshots of
+	lastAccruedDay time.Time // the last day this scheduler caught the interest up to
+}
+
+// NewScheduler creates a new scheduler from the configured interval and retry policy
+func NewScheduler(store db.Store, config utils.Config) (*Scheduler, error) {
+	if config.SchedulerInterval <= 0 {
+		return nil, fmt.Errorf("invalid scheduler interval %v: must be positive", config.SchedulerInterval)
+	}
+
+	if config.ScheduledTransferMaxAttempts < 1 {
+		return nil, fmt.Errorf("invalid scheduled transfer max attempts %d: must be at least 1", config.ScheduledTransferMaxAttempts)
+	}
+
+	if config.ScheduledTransferRetryDelay <= 0 {
+		return nil, fmt.Errorf("invalid scheduled transfer retry delay %v: must be positive", config.ScheduledTransferRetryDelay)
+	}
+
+	return &Scheduler{
+		store:       store,
+		interval:    config.SchedulerInterval,
+		maxAttempts: config.ScheduledTransferMaxAttempts,
+		retryDelay:  config.ScheduledTransferRetryDelay,
+	}, nil
+}
+
+// Run executes the due transfers, expires the holds, takes the missing snapshots and accrues the interest
+// every interval until the context is done
+func (scheduler *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(scheduler.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			_, err := scheduler.RunDue(ctx, now)
+
+			if err != nil {
+				log.Printf("cannot run scheduled transfers: %v", err)
+			}
+
+			_, err = scheduler.store.ExpireHolds(ctx)
+
+			if err != nil {
+				log.Printf("cannot expire holds: %v", err)
+			}
+
+			_, err = scheduler.TakeBalanceSnapshots(ctx, now)
+
+			if err != nil {
+				log.Printf("cannot take balance snapshots: %v", err)
+			}
+
+			_, err = scheduler.AccrueInterest(ctx, now)
+
+			if err != nil {
+				log.Printf("cannot accrue interest: %v", err)
+			}
+		}
+	}
+}
+
+// RunDue executes every transfer due at the given time and returns the number of runs
+// a failed run is recorded and retried later, it does not stop the other transfers
+func (scheduler *Scheduler) RunDue(ctx context.Context, now time.Time) (int, error) {
+	runs := 0
+
+	for {
+		result, err := scheduler.store.ExecuteScheduledTransferTx(ctx, db.ExecuteScheduledTransferTxParams{
+			Now:         now,
+			MaxAttempts: scheduler.maxAttempts,
+			RetryDelay:  scheduler.retryDelay,
+			NextRunAt:   NextRunAt,
+		})
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return runs, nil
+			}
+			return runs, err
+		}
+
+		runs++
+
+		if !result.Run.Succeeded {
+			log.Printf("scheduled transfer %d failed attempt %d: %s", result.ScheduledTransfer.ID, result.Run.Attempt, result.Run.Error)
+		}
+	}
+}
+
+// TakeBalanceSnapshots snapshots the balance of every account at the end of the last settled day
+// and returns the number of snapshots taken. Days are in UTC, a day already snapshotted is skipped.
+// It must not be called concurrently with Run
+func (scheduler *Scheduler) TakeBalanceSnapshots(ctx context.Context, now time.Time) (int64, error) {
+	takenAt := now.UTC().Add(-snapshotSettleTime).Truncate(24 * time.Hour)
+
+	if takenAt.Equal(scheduler.lastSnapshotAt) {
+		return 0, nil
+	}
+
+	snapshots, err := scheduler.store.CreateBalanceSnapshots(ctx, takenAt)
+
+	if err != nil {
+		return 0, err
+	}
+
+	scheduler.lastSnapshotAt = takenAt
+
+	return snapshots, nil
+}
+
+// AccrueInterest accrues the interest of every day since the last day accrued in the db, up to the last settled day,
+// and returns the number of accruals. The days are accrued in order and every month is capitalized once its last day
+// is accrued, so the days missed while no scheduler was running are caught up. Days are in UTC, a day already accrued
+// adds nothing. It must not be called concurrently with Run
+func (scheduler *Scheduler) AccrueInterest(ctx context.Context, now time.Time) (int64, error) {
+	dayEnd := now.UTC().Add(-snapshotSettleTime).Truncate(24 * time.Hour)
+	lastDay := dayEnd.AddDate(0, 0, -1)
+
+	if lastDay.Equal(scheduler.lastAccruedDay) {
+		return 0, nil
+	}
+
+	// the last day accrued in the db is accrued again, which adds nothing,
+	// so a capitalization that failed after that day was accrued is retried
+	day := lastDay
+
+	lastAccrued, err := scheduler.store.GetLastInterestAccrualDate(ctx)
+
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	if err == nil && lastAccrued.Before(lastDay) {
+		day = lastAccrued.UTC().Truncate(24 * time.Hour)
+	}
+
+	var accruals int64
+
+	for ; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+		result, err := scheduler.store.AccrueInterestTx(ctx, db.AccrueInterestTxParams{Date: day})
+
+		if err != nil {
+			return accruals, err
+		}
+
+		accruals += result.Accruals
+
+		// a month is capitalized before the next one is accrued, so its interest earns interest from the first day
+		if day.AddDate(0, 0, 1).Day() == 1 {
+			err = scheduler.capitalizeInterest(ctx, day)
+
+			if err != nil {
+				return accruals, err
+			}
+		}
+	}
+
+	scheduler.lastAccruedDay = lastDay
+
+	return accruals, nil
+}
+
+// capitalizeInterest pays the interest accrued up to the end of a period to every account
+// a failed capitalization does not stop the other accounts
+func (scheduler *Scheduler) capitalizeInterest(ctx context.Context, periodEnd time.Time) error {
+	accountIDs, err := scheduler.store.ListAccountsToCapitalize(ctx, periodEnd)
+
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+
+	for _, accountID := range accountIDs {
+		_, err := scheduler.store.CapitalizeInterestTx(ctx, db.CapitalizeInterestTxParams{
+			AccountID: accountID,
+			PeriodEnd: periodEnd,
+		})
+
+		// no rows means another scheduler already capitalized the account
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("cannot capitalize the interest of account %d: %v", accountID, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("cannot capitalize the interest of %d accounts", failed)
+	}
+
+	return nil
+}
+
+// NextRunAt returns the first time the cron expression of a recurrence fires after the given time
+func NextRunAt(recurrence string, after time.Time) (time.Time, error) {
+	schedule, err := ParseCron(recurrence)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return schedule.Next(after)
+}
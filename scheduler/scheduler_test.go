@@ -0,0 +1,364 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfig() utils.Config {
+	return utils.Config{
+		SchedulerInterval:            time.Minute,
+		ScheduledTransferMaxAttempts: 3,
+		ScheduledTransferRetryDelay:  time.Minute,
+	}
+}
+
+func TestNewSchedulerInvalidConfig(t *testing.T) {
+	testCases := []struct {
+		name   string
+		update func(config *utils.Config)
+	}{
+		{
+			name:   "NoInterval",
+			update: func(config *utils.Config) { config.SchedulerInterval = 0 },
+		},
+		{
+			name:   "NoAttempts",
+			update: func(config *utils.Config) { config.ScheduledTransferMaxAttempts = 0 },
+		},
+		{
+			name:   "NoRetryDelay",
+			update: func(config *utils.Config) { config.ScheduledTransferRetryDelay = 0 },
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			config := newTestConfig()
+			tc.update(&config)
+
+			scheduler, err := NewScheduler(nil, config)
+			require.Error(t, err)
+			require.Nil(t, scheduler)
+		})
+	}
+}
+
+func TestRunDue(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, runs int, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := gomock.AssignableToTypeOf(db.ExecuteScheduledTransferTxParams{})
+
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), arg).
+						Times(2).
+						DoAndReturn(func(_ context.Context, arg db.ExecuteScheduledTransferTxParams) (db.ExecuteScheduledTransferTxResult, error) {
+							require.Equal(t, now, arg.Now)
+							require.Equal(t, int32(3), arg.MaxAttempts)
+							require.Equal(t, time.Minute, arg.RetryDelay)
+							require.NotNil(t, arg.NextRunAt)
+
+							return db.ExecuteScheduledTransferTxResult{
+								Run: db.ScheduledTransferRun{Attempt: 1, Succeeded: true},
+							}, nil
+						}),
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), arg).
+						Times(1).
+						Return(db.ExecuteScheduledTransferTxResult{}, sql.ErrNoRows),
+				)
+			},
+			check: func(t *testing.T, runs int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 2, runs)
+			},
+		},
+		{
+			name: "FailedRunDoesNotStop",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.ExecuteScheduledTransferTxResult{
+							Run: db.ScheduledTransferRun{Attempt: 1, Succeeded: false, Error: db.ErrInsufficientFunds.Error()},
+						}, nil),
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.ExecuteScheduledTransferTxResult{}, sql.ErrNoRows),
+				)
+			},
+			check: func(t *testing.T, runs int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 1, runs)
+			},
+		},
+		{
+			name: "NothingDue",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ExecuteScheduledTransferTxResult{}, sql.ErrNoRows)
+			},
+			check: func(t *testing.T, runs int, err error) {
+				require.NoError(t, err)
+				require.Zero(t, runs)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ExecuteScheduledTransferTxResult{}, sql.ErrConnDone)
+			},
+			check: func(t *testing.T, runs int, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+				require.Zero(t, runs)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			scheduler, err := NewScheduler(store, newTestConfig())
+			require.NoError(t, err)
+
+			runs, err := scheduler.RunDue(context.Background(), now)
+			tc.check(t, runs, err)
+		})
+	}
+}
+
+func TestNextRunAt(t *testing.T) {
+	after := time.Date(2023, time.March, 15, 10, 30, 0, 0, time.UTC)
+
+	next, err := NextRunAt("0 9 * * *", after)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, time.March, 16, 9, 0, 0, 0, time.UTC), next)
+
+	_, err = NextRunAt("not a cron", after)
+	require.Error(t, err)
+
+	_, err = NextRunAt("0 0 31 4 *", after)
+	require.True(t, errors.Is(err, ErrNoNextRun))
+}
+
+func TestTakeBalanceSnapshots(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	scheduler, err := NewScheduler(store, newTestConfig())
+	require.NoError(t, err)
+
+	endOfDay := time.Date(2023, time.March, 15, 0, 0, 0, 0, time.UTC)
+
+	// the day is only snapshotted once it has settled
+	store.EXPECT().
+		CreateBalanceSnapshots(gomock.Any(), gomock.Eq(endOfDay.AddDate(0, 0, -1))).
+		Times(1).
+		Return(int64(2), nil)
+
+	snapshots, err := scheduler.TakeBalanceSnapshots(context.Background(), endOfDay.Add(snapshotSettleTime/2))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), snapshots)
+
+	store.EXPECT().
+		CreateBalanceSnapshots(gomock.Any(), gomock.Eq(endOfDay)).
+		Times(1).
+		Return(int64(3), nil)
+
+	snapshots, err = scheduler.TakeBalanceSnapshots(context.Background(), endOfDay.Add(snapshotSettleTime))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), snapshots)
+
+	// the same day is not snapshotted twice
+	snapshots, err = scheduler.TakeBalanceSnapshots(context.Background(), endOfDay.Add(2*snapshotSettleTime))
+	require.NoError(t, err)
+	require.Zero(t, snapshots)
+}
+
+func TestAccrueInterest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	scheduler, err := NewScheduler(store, newTestConfig())
+	require.NoError(t, err)
+
+	endOfDay := time.Date(2023, time.March, 15, 0, 0, 0, 0, time.UTC)
+	day := endOfDay.AddDate(0, 0, -1)
+
+	// a day in the middle of a month is only accrued
+	store.EXPECT().
+		GetLastInterestAccrualDate(gomock.Any()).
+		Times(1).
+		Return(day.AddDate(0, 0, -1), nil)
+	store.EXPECT().
+		AccrueInterestTx(gomock.Any(), gomock.Eq(db.AccrueInterestTxParams{Date: day.AddDate(0, 0, -1)})).
+		Times(1).
+		Return(db.AccrueInterestTxResult{}, nil)
+	store.EXPECT().
+		AccrueInterestTx(gomock.Any(), gomock.Eq(db.AccrueInterestTxParams{Date: day})).
+		Times(1).
+		Return(db.AccrueInterestTxResult{Accruals: 2}, nil)
+	store.EXPECT().ListAccountsToCapitalize(gomock.Any(), gomock.Any()).Times(0)
+
+	accruals, err := scheduler.AccrueInterest(context.Background(), endOfDay.Add(snapshotSettleTime))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), accruals)
+
+	// the same day is not accrued twice
+	accruals, err = scheduler.AccrueInterest(context.Background(), endOfDay.Add(2*snapshotSettleTime))
+	require.NoError(t, err)
+	require.Zero(t, accruals)
+}
+
+func TestAccrueInterestCapitalization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	scheduler, err := NewScheduler(store, newTestConfig())
+	require.NoError(t, err)
+
+	endOfMonth := time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)
+	lastDay := time.Date(2023, time.March, 31, 0, 0, 0, 0, time.UTC)
+	now := endOfMonth.Add(snapshotSettleTime)
+
+	// no interest was accrued yet, then the last day was accrued by the failed run
+	gomock.InOrder(
+		store.EXPECT().
+			GetLastInterestAccrualDate(gomock.Any()).
+			Times(1).
+			Return(time.Time{}, sql.ErrNoRows),
+		store.EXPECT().
+			GetLastInterestAccrualDate(gomock.Any()).
+			Times(1).
+			Return(lastDay, nil),
+	)
+
+	store.EXPECT().
+		AccrueInterestTx(gomock.Any(), gomock.Eq(db.AccrueInterestTxParams{Date: lastDay})).
+		Times(2).
+		Return(db.AccrueInterestTxResult{Accruals: 3}, nil)
+
+	store.EXPECT().
+		ListAccountsToCapitalize(gomock.Any(), gomock.Eq(lastDay)).
+		Times(1).
+		Return([]int64{1, 2, 3}, nil)
+
+	// an account capitalized by another scheduler is not a failure
+	store.EXPECT().
+		CapitalizeInterestTx(gomock.Any(), gomock.Eq(db.CapitalizeInterestTxParams{AccountID: 1, PeriodEnd: lastDay})).
+		Times(1).
+		Return(db.CapitalizeInterestTxResult{}, sql.ErrNoRows)
+	store.EXPECT().
+		CapitalizeInterestTx(gomock.Any(), gomock.Eq(db.CapitalizeInterestTxParams{AccountID: 2, PeriodEnd: lastDay})).
+		Times(1).
+		Return(db.CapitalizeInterestTxResult{}, errors.New("account is not active"))
+	store.EXPECT().
+		CapitalizeInterestTx(gomock.Any(), gomock.Eq(db.CapitalizeInterestTxParams{AccountID: 3, PeriodEnd: lastDay})).
+		Times(1).
+		Return(db.CapitalizeInterestTxResult{}, nil)
+
+	accruals, err := scheduler.AccrueInterest(context.Background(), now)
+	require.Error(t, err)
+	require.Equal(t, int64(3), accruals)
+
+	// the failed capitalization is retried on the next run
+	store.EXPECT().
+		ListAccountsToCapitalize(gomock.Any(), gomock.Eq(lastDay)).
+		Times(1).
+		Return([]int64{2}, nil)
+	store.EXPECT().
+		CapitalizeInterestTx(gomock.Any(), gomock.Eq(db.CapitalizeInterestTxParams{AccountID: 2, PeriodEnd: lastDay})).
+		Times(1).
+		Return(db.CapitalizeInterestTxResult{}, nil)
+
+	_, err = scheduler.AccrueInterest(context.Background(), now.Add(time.Minute))
+	require.NoError(t, err)
+
+	// the month is done
+	_, err = scheduler.AccrueInterest(context.Background(), now.Add(2*time.Minute))
+	require.NoError(t, err)
+}
+
+func TestAccrueInterestCatchUp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	scheduler, err := NewScheduler(store, newTestConfig())
+	require.NoError(t, err)
+
+	// the scheduler was down from March 30 to April 3
+	lastAccrued := time.Date(2023, time.March, 29, 0, 0, 0, 0, time.UTC)
+	endOfMonth := time.Date(2023, time.March, 31, 0, 0, 0, 0, time.UTC)
+	lastDay := time.Date(2023, time.April, 2, 0, 0, 0, 0, time.UTC)
+	now := lastDay.AddDate(0, 0, 1).Add(snapshotSettleTime)
+
+	store.EXPECT().
+		GetLastInterestAccrualDate(gomock.Any()).
+		Times(1).
+		Return(lastAccrued, nil)
+
+	// every missed day is accrued in order, and the month is capitalized before April is accrued
+	var calls []*gomock.Call
+
+	for day := lastAccrued; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+		calls = append(calls, store.EXPECT().
+			AccrueInterestTx(gomock.Any(), gomock.Eq(db.AccrueInterestTxParams{Date: day})).
+			Times(1).
+			Return(db.AccrueInterestTxResult{Accruals: 1}, nil))
+
+		if day.Equal(endOfMonth) {
+			calls = append(calls, store.EXPECT().
+				ListAccountsToCapitalize(gomock.Any(), gomock.Eq(endOfMonth)).
+				Times(1).
+				Return([]int64{}, nil))
+		}
+	}
+
+	gomock.InOrder(calls...)
+
+	accruals, err := scheduler.AccrueInterest(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), accruals)
+}
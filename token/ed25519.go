@@ -0,0 +1,43 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+)
+
+// Algorithms of the public keys exported by the asymmetric token makers
+const (
+	AlgorithmPasetoV2Public = "v2.public"
+	AlgorithmEdDSA          = "EdDSA"
+)
+
+// PublicKey is a public key other services can use to verify tokens
+type PublicKey struct {
+	Algorithm string            `json:"algorithm"`
+	Key       ed25519.PublicKey `json:"public_key"` // base64 encoded when marshalled to json
+}
+
+// PublicKeyMaker is a Maker that signs its tokens with a private key,
+// so anyone holding the matching public keys can verify them
+type PublicKeyMaker interface {
+	Maker
+
+	// PublicKeys returns the public keys that verify the tokens of the maker
+	PublicKeys() []PublicKey
+}
+
+// ParseEd25519Seed decodes a hex encoded 32 bytes seed into an Ed25519 private key
+func ParseEd25519Seed(seed string) (ed25519.PrivateKey, error) {
+	data, err := hex.DecodeString(seed)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	if len(data) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid key size: must be exactly %d bytes", ed25519.SeedSize)
+	}
+
+	return ed25519.NewKeyFromSeed(data), nil
+}
@@ -0,0 +1,29 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"simple_bank/utils"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEd25519Seed(t *testing.T) {
+	seed := hex.EncodeToString([]byte(utils.RandomString(ed25519.SeedSize)))
+
+	privateKey, err := ParseEd25519Seed(seed)
+	require.NoError(t, err)
+	require.Len(t, privateKey, ed25519.PrivateKeySize)
+
+	// the same seed always gives the same key
+	privateKey2, err := ParseEd25519Seed(seed)
+	require.NoError(t, err)
+	require.Equal(t, privateKey, privateKey2)
+
+	_, err = ParseEd25519Seed("not hex")
+	require.Error(t, err)
+
+	_, err = ParseEd25519Seed(hex.EncodeToString([]byte("short")))
+	require.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+// JWTEdDSAMaker is JSON Web Token maker signing with EdDSA
+// tokens are signed with an Ed25519 private key and verified with its public key
+type JWTEdDSAMaker struct {
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+// NewJWTEdDSAMaker creates a new EdDSA JWT maker
+func NewJWTEdDSAMaker(privateKey ed25519.PrivateKey) (PublicKeyMaker, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid key size: must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+
+	maker := &JWTEdDSAMaker{
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+	}
+
+	return maker, nil
+
+}
+
+// CreateToken creates and signs a new token of the given type for a specific username, role, session and a valid duration
+func (maker *JWTEdDSAMaker) CreateToken(username string, role string, sessionID uuid.UUID, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, sessionID, tokenType, duration)
+
+	if err != nil {
+		return "", payload, err
+	}
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodEdDSA, payload)
+	token, err := jwtToken.SignedString(maker.privateKey)
+	return token, payload, err
+
+}
+
+// VerifyToken takes a token to verify and returns a Payload stored inside the body of the token
+func (maker *JWTEdDSAMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		// only accept EdDSA, so the public key can never be used as an HMAC secret
+		_, ok := token.Method.(*jwt.SigningMethodEd25519)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		return maker.publicKey, nil
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
+	if err != nil {
+		verr, ok := err.(*jwt.ValidationError)
+		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	payload, ok := jwtToken.Claims.(*Payload)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Verify(tokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// PublicKeys returns the public key that verifies the tokens of the maker
+func (maker *JWTEdDSAMaker) PublicKeys() []PublicKey {
+	return []PublicKey{{Algorithm: AlgorithmEdDSA, Key: maker.publicKey}}
+}
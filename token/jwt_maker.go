@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 )
 
 const minSecreteKeySize = 32
@@ -27,10 +28,10 @@ func NewJWTMaker(secretKey string) (Maker, error) {
 
 }
 
-// CreateToken creates and signs a new token for a specific username and a valid duration
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
+// CreateToken creates and signs a new token of the given type for a specific username, role, session and a valid duration
+func (maker *JWTMaker) CreateToken(username string, role string, sessionID uuid.UUID, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
 	// create a new payload by calling NewPayload fuction
-	payload, err := NewPayload(username, duration)
+	payload, err := NewPayload(username, role, sessionID, tokenType, duration)
 
 	if err != nil {
 		return "", payload, err
@@ -46,7 +47,7 @@ func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (str
 
 // VerifyToken takes a token to verify and returns a Payload stored inside the body of the token
 // VerifyToken checks if the token is valid or not
-func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
+func (maker *JWTMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
 		_, ok := token.Method.(*jwt.SigningMethodHMAC)
 		if !ok {
@@ -69,5 +70,11 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
+	// expiry was already checked by ParseWithClaims, this adds the issuer, audience and type checks
+	err = payload.Verify(tokenType)
+	if err != nil {
+		return nil, err
+	}
+
 	return payload, nil
 }
@@ -0,0 +1,161 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aead/chacha20poly1305"
+)
+
+// DefaultKeyID is the id of the key used when no key ids are configured.
+// Tokens without a key id in their footer were minted before key rotation existed
+// and are verified with this key
+const DefaultKeyID = "default"
+
+// ErrUnknownKey is returned when a token names a key the keyring does not hold or no longer accepts
+var ErrUnknownKey = errors.New("unknown or retired token key")
+
+// SymmetricKey is a key of the keyring
+type SymmetricKey struct {
+	ID  string
+	Key []byte
+	// RetiredAt is set once the key stops being accepted for new rotations.
+	// Tokens minted with it are still accepted until the grace period has passed
+	RetiredAt time.Time
+}
+
+// Keyring holds one active key tokens are signed with and any number of verify-only keys.
+// It is safe for concurrent use and its keys can be swapped at runtime with Update
+type Keyring struct {
+	mu          sync.RWMutex
+	keys        map[string]SymmetricKey
+	activeID    string
+	gracePeriod time.Duration
+}
+
+// NewKeyring creates a new keyring signing with the key activeID
+func NewKeyring(keys []SymmetricKey, activeID string, gracePeriod time.Duration) (*Keyring, error) {
+	keyring := &Keyring{}
+
+	err := keyring.Update(keys, activeID, gracePeriod)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return keyring, nil
+}
+
+// Update replaces all the keys of the keyring
+// the keyring is left untouched when the new keys are invalid
+func (keyring *Keyring) Update(keys []SymmetricKey, activeID string, gracePeriod time.Duration) error {
+	keyMap := make(map[string]SymmetricKey, len(keys))
+
+	for _, key := range keys {
+		if key.ID == "" {
+			return errors.New("key id must not be empty")
+		}
+
+		if len(key.Key) != chacha20poly1305.KeySize {
+			return fmt.Errorf("invalid size of key %s: must be exactly %d characters", key.ID, chacha20poly1305.KeySize)
+		}
+
+		if _, ok := keyMap[key.ID]; ok {
+			return fmt.Errorf("duplicate key id %s", key.ID)
+		}
+
+		keyMap[key.ID] = key
+	}
+
+	active, ok := keyMap[activeID]
+
+	if !ok {
+		return fmt.Errorf("active key %s not found", activeID)
+	}
+
+	if !active.RetiredAt.IsZero() {
+		return fmt.Errorf("active key %s must not be retired", activeID)
+	}
+
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+
+	keyring.keys = keyMap
+	keyring.activeID = activeID
+	keyring.gracePeriod = gracePeriod
+
+	return nil
+}
+
+// signingKey returns the active key
+func (keyring *Keyring) signingKey() SymmetricKey {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	return keyring.keys[keyring.activeID]
+}
+
+// verifyingKey returns the key with the given id if tokens minted with it are still accepted
+func (keyring *Keyring) verifyingKey(id string) ([]byte, error) {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	key, ok := keyring.keys[id]
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if !key.RetiredAt.IsZero() && time.Now().After(key.RetiredAt.Add(keyring.gracePeriod)) {
+		return nil, ErrUnknownKey
+	}
+
+	return key.Key, nil
+}
+
+// ParseSymmetricKeys parses a comma separated list of keys in the form
+// id=key or id=key@retired_at, where retired_at is an RFC3339 time
+// e.g. "k2=12345678901234567890123456789012,k1=abcdefghijklmnopqrstuvwxyz123456@2022-11-01T00:00:00Z"
+func ParseSymmetricKeys(spec string) ([]SymmetricKey, error) {
+	var keys []SymmetricKey
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+
+		if entry == "" {
+			continue
+		}
+
+		fields := strings.SplitN(entry, "=", 2)
+
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid key %q: must be id=key", entry)
+		}
+
+		key := SymmetricKey{
+			ID:  strings.TrimSpace(fields[0]),
+			Key: []byte(fields[1]),
+		}
+
+		// the retirement time is after the last @, since a key may contain an @ itself
+		if i := strings.LastIndex(fields[1], "@"); i >= 0 {
+			retiredAt, err := time.Parse(time.RFC3339, fields[1][i+1:])
+
+			if err == nil {
+				key.Key = []byte(fields[1][:i])
+				key.RetiredAt = retiredAt
+			}
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no keys found")
+	}
+
+	return keys, nil
+}
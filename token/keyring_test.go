@@ -0,0 +1,118 @@
+package token
+
+import (
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomSymmetricKey(id string) SymmetricKey {
+	return SymmetricKey{ID: id, Key: []byte(utils.RandomString(32))}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	key1 := randomSymmetricKey("k1")
+	key2 := randomSymmetricKey("k2")
+
+	keyring, err := NewKeyring([]SymmetricKey{key1}, key1.ID, time.Hour)
+	require.NoError(t, err)
+
+	maker, err := NewPasetoKeyringMaker(keyring)
+	require.NoError(t, err)
+
+	token1, _, err := maker.CreateToken(utils.RandomOwner(), utils.CustomerRole, uuid.New(), TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+
+	// start signing with k2, k1 stays within its grace period
+	key1.RetiredAt = time.Now()
+	err = keyring.Update([]SymmetricKey{key2, key1}, key2.ID, time.Hour)
+	require.NoError(t, err)
+
+	token2, _, err := maker.CreateToken(utils.RandomOwner(), utils.CustomerRole, uuid.New(), TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(token1, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(token2, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// once the grace period has ended tokens of k1 are rejected
+	key1.RetiredAt = time.Now().Add(-2 * time.Hour)
+	err = keyring.Update([]SymmetricKey{key2, key1}, key2.ID, time.Hour)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token1, TokenTypeAccessToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+
+	_, err = maker.VerifyToken(token2, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// and so are they once k1 is dropped from the keyring
+	err = keyring.Update([]SymmetricKey{key2}, key2.ID, time.Hour)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(token1, TokenTypeAccessToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
+
+func TestKeyringUpdateInvalid(t *testing.T) {
+	key1 := randomSymmetricKey("k1")
+
+	keyring, err := NewKeyring([]SymmetricKey{key1}, key1.ID, time.Hour)
+	require.NoError(t, err)
+
+	retired := randomSymmetricKey("k2")
+	retired.RetiredAt = time.Now()
+
+	testCases := []struct {
+		name     string
+		keys     []SymmetricKey
+		activeID string
+	}{
+		{"MissingActiveKey", []SymmetricKey{key1}, "k2"},
+		{"RetiredActiveKey", []SymmetricKey{key1, retired}, "k2"},
+		{"DuplicateKeyID", []SymmetricKey{key1, key1}, "k1"},
+		{"InvalidKeySize", []SymmetricKey{{ID: "k3", Key: []byte("short")}}, "k3"},
+		{"EmptyKeyID", []SymmetricKey{{ID: "", Key: key1.Key}}, ""},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := keyring.Update(tc.keys, tc.activeID, time.Hour)
+			require.Error(t, err)
+
+			// a failed update leaves the keyring untouched
+			require.Equal(t, key1.ID, keyring.signingKey().ID)
+		})
+	}
+}
+
+func TestParseSymmetricKeys(t *testing.T) {
+	key1 := utils.RandomString(32)
+	key2 := utils.RandomString(32)
+
+	keys, err := ParseSymmetricKeys("k2=" + key2 + ", k1=" + key1 + "@2022-11-01T00:00:00Z")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	require.Equal(t, "k2", keys[0].ID)
+	require.Equal(t, []byte(key2), keys[0].Key)
+	require.True(t, keys[0].RetiredAt.IsZero())
+
+	require.Equal(t, "k1", keys[1].ID)
+	require.Equal(t, []byte(key1), keys[1].Key)
+	require.Equal(t, time.Date(2022, time.November, 1, 0, 0, 0, 0, time.UTC), keys[1].RetiredAt.UTC())
+
+	_, err = ParseSymmetricKeys("")
+	require.Error(t, err)
+
+	_, err = ParseSymmetricKeys("no_key_id")
+	require.Error(t, err)
+}
@@ -2,13 +2,16 @@ package token
 
 import (
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Maker is an interface for managing tokens
 type Maker interface {
-	// CreateToken creates and signs a new token for a specific username and a valid duration
-	CreateToken(username string, duration time.Duration) (string, *Payload, error)
+	// CreateToken creates and signs a new token of the given type for a specific username, role, session and a valid duration
+	CreateToken(username string, role string, sessionID uuid.UUID, tokenType TokenType, duration time.Duration) (string, *Payload, error)
 
 	// VerifyToken takes a token to verify and returns a Payload stored inside the body of the token
-	VerifyToken(token string) (*Payload, error)
+	// tokens of another type than tokenType are rejected
+	VerifyToken(token string, tokenType TokenType) (*Payload, error)
 }
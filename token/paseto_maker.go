@@ -1,30 +1,47 @@
 package token
 
 import (
-	"fmt"
 	"time"
 
-	"github.com/aead/chacha20poly1305"
+	"github.com/google/uuid"
 	"github.com/o1egl/paseto"
 )
 
 // Pasetomaker is PASETO token maker
 type Pasetomaker struct {
-	paseto       *paseto.V2
-	symmetricKey []byte
+	paseto  *paseto.V2
+	keyring *Keyring
 }
 
-// NewPasetoMaker creates a new paseto maker instance
+// pasetoFooter is the unencrypted footer of the tokens
+// it names the key of the keyring the token was encrypted with
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// NewPasetoMaker creates a new paseto maker instance with a single key
 func NewPasetoMaker(symmetricKey string) (Maker, error) {
-	if len(symmetricKey) != chacha20poly1305.KeySize {
+	keyring, err := NewKeyring(
+		[]SymmetricKey{{ID: DefaultKeyID, Key: []byte(symmetricKey)}},
+		DefaultKeyID,
+		0,
+	)
+
+	if err != nil {
 		// return a nil object and an error
-		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", chacha20poly1305.KeySize)
+		return nil, err
 	}
-	//otherwise, create a new paseto object
-	maker := &Pasetomaker{
-		paseto:       paseto.NewV2(),
-		symmetricKey: []byte(symmetricKey), // convert the input string to byte slice
 
+	return NewPasetoKeyringMaker(keyring)
+
+}
+
+// NewPasetoKeyringMaker creates a new paseto maker instance
+// signing with the active key of the keyring
+func NewPasetoKeyringMaker(keyring *Keyring) (Maker, error) {
+	maker := &Pasetomaker{
+		paseto:  paseto.NewV2(),
+		keyring: keyring,
 	}
 
 	// note: PasetoMaker receiver maker must implement the Maker interface as below
@@ -32,36 +49,59 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 
 }
 
-// CreateToken creates and signs a new token for a specific username and a valid duration
-func (maker *Pasetomaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
+// Keyring returns the keyring of the maker, to rotate its keys
+func (maker *Pasetomaker) Keyring() *Keyring {
+	return maker.keyring
+}
+
+// CreateToken creates and signs a new token of the given type for a specific username, role, session and a valid duration
+func (maker *Pasetomaker) CreateToken(username string, role string, sessionID uuid.UUID, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
 	// create a new payload by calling NewPayload fuction
-	payload, err := NewPayload(username, duration)
+	payload, err := NewPayload(username, role, sessionID, tokenType, duration)
 
 	if err != nil {
 		// return an empty string and an error
-		return "", payload,  err
+		return "", payload, err
 	}
 
-	// otherwise, we return maker encrypted
-	token, err := maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	// otherwise, we return maker encrypted with the active key
+	// whose id goes in the footer so the token can still be verified after a rotation
+	key := maker.keyring.signingKey()
+
+	token, err := maker.paseto.Encrypt(key.Key, payload, pasetoFooter{KeyID: key.ID})
 
 	return token, payload, err
 
 }
 
 // VerifyToken takes a token to verify and returns a Payload stored inside the body of the token
-func (maker *Pasetomaker) VerifyToken(token string) (*Payload, error) {
+func (maker *Pasetomaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
+	footer := pasetoFooter{}
+
+	// tokens without a footer were minted before keys had ids
+	err := paseto.ParseFooter(token, &footer)
+
+	if err != nil || footer.KeyID == "" {
+		footer.KeyID = DefaultKeyID
+	}
+
+	key, err := maker.keyring.verifyingKey(footer.KeyID)
+
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
 	payload := &Payload{}
 
-	err := maker.paseto.Decrypt(token, maker.symmetricKey, payload, nil)
+	err = maker.paseto.Decrypt(token, key, payload, nil)
 
 	if err != nil {
 		// return a nil object and errorInvalidToken object
 		return nil, ErrInvalidToken
 	}
 
-	// otherwise if there is an error
-	err = payload.Valid()
+	// otherwise check the payload has not expired and is of the expected type
+	err = payload.Verify(tokenType)
 	if err != nil {
 		// return nil and an error
 		return nil, err
@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/o1egl/paseto"
 	"github.com/stretchr/testify/require"
 )
 
@@ -13,22 +15,29 @@ func TestPasetoMaker(t *testing.T) {
 	require.NoError(t, err)
 
 	username := utils.RandomOwner()
+	role := utils.CustomerRole
+	sessionID := uuid.New()
 	duration := time.Minute
 
 	issuedAt := time.Now()
 	expiredAt := issuedAt.Add(duration)
 
-	token, payload, err := maker.CreateToken(username, duration)
+	token, payload, err := maker.CreateToken(username, role, sessionID, TokenTypeAccessToken, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 	require.NotEmpty(t, payload)
 
-	payload, err = maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 
 	require.NotZero(t, payload.ID)
 	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.Equal(t, sessionID, payload.SessionID)
+	require.Equal(t, TokenTypeAccessToken, payload.Type)
+	require.Equal(t, tokenIssuer, payload.Issuer)
+	require.Equal(t, tokenAudience, payload.Audience)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
 }
@@ -37,13 +46,47 @@ func TestExpiredPasetoToken(t *testing.T) {
 	maker, err := NewPasetoMaker(utils.RandomString(32))
 	require.NoError(t, err)
 
-	token, payload, err := maker.CreateToken(utils.RandomOwner(), -time.Minute)
+	token, payload, err := maker.CreateToken(utils.RandomOwner(), utils.CustomerRole, uuid.New(), TokenTypeAccessToken, -time.Minute)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 	require.NotEmpty(t, payload)
 
-	payload, err = maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
 	require.Error(t, err)
 	require.EqualError(t, err, ErrExpiredToken.Error())
 	require.Nil(t, payload)
 }
+
+func TestWrongTypePasetoToken(t *testing.T) {
+	maker, err := NewPasetoMaker(utils.RandomString(32))
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(utils.RandomOwner(), utils.CustomerRole, uuid.Nil, TokenTypeRefreshToken, time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	// a refresh token must not be accepted as an access token
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrWrongTokenType.Error())
+	require.Nil(t, payload)
+}
+
+func TestLegacyPasetoToken(t *testing.T) {
+	symmetricKey := utils.RandomString(32)
+
+	maker, err := NewPasetoMaker(symmetricKey)
+	require.NoError(t, err)
+
+	// tokens minted before key ids existed have no footer
+	payload, err := NewPayload(utils.RandomOwner(), utils.CustomerRole, uuid.New(), TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+
+	token, err := paseto.NewV2().Encrypt([]byte(symmetricKey), payload, nil)
+	require.NoError(t, err)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+}
@@ -0,0 +1,72 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/o1egl/paseto"
+)
+
+// PasetoPublicMaker is PASETO v2.public token maker
+// tokens are signed with an Ed25519 private key and verified with its public key
+type PasetoPublicMaker struct {
+	paseto     *paseto.V2
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+// NewPasetoPublicMaker creates a new paseto public maker instance
+func NewPasetoPublicMaker(privateKey ed25519.PrivateKey) (PublicKeyMaker, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid key size: must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+
+	maker := &PasetoPublicMaker{
+		paseto:     paseto.NewV2(),
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+	}
+
+	return maker, nil
+
+}
+
+// CreateToken creates and signs a new token of the given type for a specific username, role, session and a valid duration
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, sessionID uuid.UUID, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, sessionID, tokenType, duration)
+
+	if err != nil {
+		return "", payload, err
+	}
+
+	token, err := maker.paseto.Sign(maker.privateKey, payload, nil)
+
+	return token, payload, err
+
+}
+
+// VerifyToken takes a token to verify and returns a Payload stored inside the body of the token
+func (maker *PasetoPublicMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
+	payload := &Payload{}
+
+	err := maker.paseto.Verify(token, maker.publicKey, payload, nil)
+
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Verify(tokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+
+}
+
+// PublicKeys returns the public key that verifies the tokens of the maker
+func (maker *PasetoPublicMaker) PublicKeys() []PublicKey {
+	return []PublicKey{{Algorithm: AlgorithmPasetoV2Public, Key: maker.publicKey}}
+}
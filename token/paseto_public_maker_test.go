@@ -0,0 +1,96 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"simple_bank/utils"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newPasetoPublicTestMaker(t *testing.T) PublicKeyMaker {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	maker, err := NewPasetoPublicMaker(privateKey)
+	require.NoError(t, err)
+
+	return maker
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	maker := newPasetoPublicTestMaker(t)
+
+	username := utils.RandomOwner()
+	role := utils.CustomerRole
+	sessionID := uuid.New()
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, sessionID, TokenTypeAccessToken, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.Equal(t, sessionID, payload.SessionID)
+	require.Equal(t, TokenTypeAccessToken, payload.Type)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+
+	publicKeys := maker.PublicKeys()
+	require.Len(t, publicKeys, 1)
+	require.Equal(t, AlgorithmPasetoV2Public, publicKeys[0].Algorithm)
+	require.Len(t, publicKeys[0].Key, ed25519.PublicKeySize)
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	maker := newPasetoPublicTestMaker(t)
+
+	token, payload, err := maker.CreateToken(utils.RandomOwner(), utils.CustomerRole, uuid.New(), TokenTypeAccessToken, -time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestOtherKeyPasetoPublicToken(t *testing.T) {
+	maker1 := newPasetoPublicTestMaker(t)
+	maker2 := newPasetoPublicTestMaker(t)
+
+	token, _, err := maker1.CreateToken(utils.RandomOwner(), utils.CustomerRole, uuid.New(), TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+
+	// a token signed with another private key must not verify
+	payload, err := maker2.VerifyToken(token, TokenTypeAccessToken)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestWrongTypePasetoPublicToken(t *testing.T) {
+	maker := newPasetoPublicTestMaker(t)
+
+	token, _, err := maker.CreateToken(utils.RandomOwner(), utils.CustomerRole, uuid.Nil, TokenTypeRefreshToken, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token, TokenTypeAccessToken)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrWrongTokenType.Error())
+	require.Nil(t, payload)
+}
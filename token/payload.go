@@ -9,20 +9,44 @@ import (
 
 // Different types of error returned by the VerifyToken function
 var (
-	ErrInvalidToken = errors.New("token is invalid")
-	ErrExpiredToken = errors.New("token has expired")
+	ErrInvalidToken   = errors.New("token is invalid")
+	ErrExpiredToken   = errors.New("token has expired")
+	ErrWrongTokenType = errors.New("token is of the wrong type")
+)
+
+// TokenType tells access tokens and refresh tokens apart
+type TokenType string
+
+// Different types of token issued by the token makers
+const (
+	TokenTypeAccessToken  TokenType = "access"
+	TokenTypeRefreshToken TokenType = "refresh"
+)
+
+// issuer and audience of every token, so tokens signed with the same key
+// for another service are never accepted by this one
+const (
+	tokenIssuer   = "simple_bank"
+	tokenAudience = "simple_bank_api"
 )
 
 // Payload contains the payload data of the token
 type Payload struct {
 	ID        uuid.UUID `json:"id"` // id to uniquely identify each token
+	Type      TokenType `json:"token_type"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Issuer    string    `json:"issuer"`
+	Audience  string    `json:"audience"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+	// SessionID is the session an access token was issued along with, it is not set on refresh tokens
+	// since the id of a refresh token is the id of its session
+	SessionID uuid.UUID `json:"session_id"`
 }
 
-// creates a new token payload for a specific username and duration
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// creates a new token payload for a specific username, role, session, token type and duration
+func NewPayload(username string, role string, sessionID uuid.UUID, tokenType TokenType, duration time.Duration) (*Payload, error) {
 	// call uuid.NewRandom() to generate a new token id
 	tokenID, err := uuid.NewRandom()
 
@@ -33,7 +57,12 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 	// if we got here. create a new payload
 	payload := &Payload{
 		ID:        tokenID,
+		SessionID: sessionID,
+		Type:      tokenType,
 		Username:  username,
+		Role:      role,
+		Issuer:    tokenIssuer,
+		Audience:  tokenAudience,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
@@ -50,3 +79,22 @@ func (payload *Payload) Valid() error {
 	}
 	return nil
 }
+
+// Verify checks the token payload is valid and is of the expected token type
+func (payload *Payload) Verify(tokenType TokenType) error {
+	err := payload.Valid()
+
+	if err != nil {
+		return err
+	}
+
+	if payload.Issuer != tokenIssuer || payload.Audience != tokenAudience {
+		return ErrInvalidToken
+	}
+
+	if payload.Type != tokenType {
+		return ErrWrongTokenType
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package utils
+
+// constants for all account statuses
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen" // temporarily blocked, can be unfrozen
+	AccountStatusClosed = "closed" // permanently blocked
+)
+
+// accountStatusTransitions lists the statuses every status can move to
+// a closed account can never be reopened
+var accountStatusTransitions = map[string][]string{
+	AccountStatusActive: {AccountStatusFrozen, AccountStatusClosed},
+	AccountStatusFrozen: {AccountStatusActive},
+	AccountStatusClosed: {},
+}
+
+// CanTransitionAccountStatus returns true if an account may move from one status to the other
+func CanTransitionAccountStatus(from string, to string) bool {
+	for _, status := range accountStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
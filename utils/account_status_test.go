@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanTransitionAccountStatus(t *testing.T) {
+	testCases := []struct {
+		from    string
+		to      string
+		allowed bool
+	}{
+		{AccountStatusActive, AccountStatusFrozen, true},
+		{AccountStatusActive, AccountStatusClosed, true},
+		{AccountStatusFrozen, AccountStatusActive, true},
+		{AccountStatusFrozen, AccountStatusClosed, false},
+		{AccountStatusClosed, AccountStatusActive, false},
+		{AccountStatusClosed, AccountStatusFrozen, false},
+		{AccountStatusActive, AccountStatusActive, false},
+		{"unknown", AccountStatusActive, false},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.allowed, CanTransitionAccountStatus(tc.from, tc.to), "%s -> %s", tc.from, tc.to)
+	}
+}
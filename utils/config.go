@@ -1,20 +1,55 @@
 package utils
 
 import (
+	"log"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
 )
 
+// DevelopmentEnvironment is the environment of a local setup
+const DevelopmentEnvironment = "development"
+
+// DevTokenPrivateKey is the placeholder private key of app.env, the hex encoding of
+// "dev-only-not-for-production-key!". It is only accepted in the development environment
+const DevTokenPrivateKey = "6465762d6f6e6c792d6e6f742d666f722d70726f64756374696f6e2d6b657921"
+
+// DevTokenSymmetricKey is the placeholder symmetric key of app.env. Like DevTokenPrivateKey
+// it is only accepted in the development environment
+const DevTokenSymmetricKey = "12345678901234567890123456789012"
+
 //Config stores all the configuration varaibles of the applications
 //the values are read by viper from the .env file
 type Config struct {
-	DBDriver             string        `mapstructure:"DB_DRIVER"`
-	DBSource             string        `mapstructure:"DB_SOURCE"`
-	ServerAddress        string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmeticKey     string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
-	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	Environment                  string        `mapstructure:"ENVIRONMENT"`
+	DBDriver                     string        `mapstructure:"DB_DRIVER"`
+	DBSource                     string        `mapstructure:"DB_SOURCE"`
+	ServerAddress                string        `mapstructure:"SERVER_ADDRESS"`
+	TokenMaker                   string        `mapstructure:"TOKEN_MAKER"`
+	TokenSymmeticKey             string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenSymmetricKeys           string        `mapstructure:"TOKEN_SYMMETRIC_KEYS"`
+	TokenActiveKeyID             string        `mapstructure:"TOKEN_ACTIVE_KEY_ID"`
+	TokenKeyGracePeriod          time.Duration `mapstructure:"TOKEN_KEY_GRACE_PERIOD"`
+	TokenPrivateKey              string        `mapstructure:"TOKEN_PRIVATE_KEY"`
+	AccessTokenDuration          time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration         time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	IdempotencyKeyTTL            time.Duration `mapstructure:"IDEMPOTENCY_KEY_TTL"`
+	FXRates                      string        `mapstructure:"FX_RATES"`
+	AppBaseURL                   string        `mapstructure:"APP_BASE_URL"`
+	VerifyEmailDuration          time.Duration `mapstructure:"VERIFY_EMAIL_DURATION"`
+	ResetPasswordDuration        time.Duration `mapstructure:"RESET_PASSWORD_DURATION"`
+	ResetPasswordURL             string        `mapstructure:"RESET_PASSWORD_URL"`
+	SMTPHost                     string        `mapstructure:"SMTP_HOST"`
+	SMTPPort                     int           `mapstructure:"SMTP_PORT"`
+	SMTPUsername                 string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword                 string        `mapstructure:"SMTP_PASSWORD"`
+	EmailSenderName              string        `mapstructure:"EMAIL_SENDER_NAME"`
+	EmailSenderAddress           string        `mapstructure:"EMAIL_SENDER_ADDRESS"`
+	SchedulerInterval            time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	ScheduledTransferMaxAttempts int32         `mapstructure:"SCHEDULED_TRANSFER_MAX_ATTEMPTS"`
+	ScheduledTransferRetryDelay  time.Duration `mapstructure:"SCHEDULED_TRANSFER_RETRY_DELAY"`
+	HoldDuration                 time.Duration `mapstructure:"HOLD_DURATION"`
 }
 
 // LoadConfig reads configurations from .env file
@@ -36,3 +71,22 @@ func LoadConfigs(path string) (config Config, err error) {
 	return
 
 }
+
+// WatchConfigs calls onChange with the reloaded configurations every time the .env file changes
+// it must be called after LoadConfigs
+func WatchConfigs(onChange func(config Config)) {
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		var config Config
+
+		err := viper.Unmarshal(&config)
+
+		if err != nil {
+			log.Printf("cannot reload app configs: %v", err)
+			return
+		}
+
+		onChange(config)
+	})
+
+	viper.WatchConfig()
+}
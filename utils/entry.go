@@ -0,0 +1,26 @@
+package utils
+
+// constants for all entry transaction types
+const (
+	EntryTypeTransfer   = "transfer"   // money moved between two accounts
+	EntryTypeReversal   = "reversal"   // a transfer refunded by a banker
+	EntryTypeDeposit    = "deposit"    // cash paid in, out of the cash account of the bank
+	EntryTypeWithdrawal = "withdrawal" // cash paid out, into the cash account of the bank
+	EntryTypeFee        = "fee"        // a fee charged on a transfer, into the fee account of the bank
+	EntryTypeInterest   = "interest"   // accrued interest paid out of the interest account of the bank
+)
+
+// CashAccountOwner is the system user owning the cash accounts of the bank, one per currency.
+// It is not alphanumeric, so no customer can register it
+const CashAccountOwner = "simple_bank"
+
+// FeeAccountOwner is the system user owning the fee income accounts of the bank, one per currency
+const FeeAccountOwner = "simple_bank_fees"
+
+// InterestAccountOwner is the system user owning the interest expense accounts of the bank, one per currency
+const InterestAccountOwner = "simple_bank_interest"
+
+// IsSystemAccountOwner returns true if the owner is one of the system users of the bank
+func IsSystemAccountOwner(owner string) bool {
+	return owner == CashAccountOwner || owner == FeeAccountOwner || owner == InterestAccountOwner
+}
@@ -0,0 +1,9 @@
+package utils
+
+// constants for all hold statuses
+const (
+	HoldStatusHeld     = "held"     // the funds are reserved until the hold expires
+	HoldStatusCaptured = "captured" // the funds were transferred, an uncaptured remainder is released
+	HoldStatusVoided   = "voided"   // released before it expired
+	HoldStatusExpired  = "expired"  // released once it expired
+)
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// constants for all day count conventions, they split an annual interest rate into daily rates
+const (
+	DayCountActual365 = "actual/365" // every day earns 1/365 of the annual rate, leap years included
+	DayCountActual360 = "actual/360" // every day earns 1/360 of the annual rate
+	DayCount30360     = "30/360"     // every month earns 30/360 of the annual rate, however many days it has
+)
+
+// MicrosPerUnit is the number of micros in a minor unit of a currency, accrued interest is kept in micros
+// so the interest of a small balance is not rounded away day after day
+const MicrosPerUnit = 1000000
+
+// IsDayCountConventionSupported returns true if the day count convention is supported
+func IsDayCountConventionSupported(convention string) bool {
+	switch convention {
+	case DayCountActual365, DayCountActual360, DayCount30360:
+		return true
+	}
+	return false
+}
+
+// InterestDays returns the days of interest earned on a date and the days of a year under a day count convention
+// Under 30/360 (the European 30E/360 rule) the 31st earns nothing and the last day of February
+// earns the days February is short of 30, so every month earns 30 days
+func InterestDays(convention string, date time.Time) (days int64, yearDays int64, err error) {
+	switch convention {
+	case DayCountActual365:
+		return 1, 365, nil
+	case DayCountActual360:
+		return 1, 360, nil
+	case DayCount30360:
+		next := date.AddDate(0, 0, 1)
+
+		day1 := min30(date.Day())
+		day2 := min30(next.Day())
+
+		days = 360*int64(next.Year()-date.Year()) + 30*int64(next.Month()-date.Month()) + int64(day2-day1)
+
+		return days, 360, nil
+	}
+	return 0, 0, fmt.Errorf("unsupported day count convention %q", convention)
+}
+
+func min30(day int) int {
+	if day > 30 {
+		return 30
+	}
+	return day
+}
+
+// DailyInterestMicros returns the interest a balance earns on a date at an annual rate in basis points, in micros
+// The interest is rounded down, and a balance that is not positive earns nothing
+func DailyInterestMicros(balance int64, annualRateBps int32, convention string, date time.Time) (int64, error) {
+	days, yearDays, err := InterestDays(convention, date)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if balance <= 0 || annualRateBps <= 0 || days == 0 {
+		return 0, nil
+	}
+
+	// balance * rate * days * micros / (10000 * year days) overflows an int64 for large balances
+	interest := new(big.Int).SetInt64(balance)
+	interest.Mul(interest, big.NewInt(int64(annualRateBps)*days*MicrosPerUnit))
+	interest.Quo(interest, big.NewInt(10000*yearDays))
+
+	if !interest.IsInt64() {
+		return 0, fmt.Errorf("daily interest of balance %d is too large", balance)
+	}
+
+	return interest.Int64(), nil
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterestDays(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	testCases := []struct {
+		convention string
+		date       time.Time
+		days       int64
+		yearDays   int64
+	}{
+		{DayCountActual365, date(2024, time.February, 29), 1, 365},
+		{DayCountActual360, date(2023, time.January, 31), 1, 360},
+		{DayCount30360, date(2023, time.January, 15), 1, 360},
+		{DayCount30360, date(2023, time.January, 30), 0, 360},
+		{DayCount30360, date(2023, time.January, 31), 1, 360},
+		{DayCount30360, date(2023, time.February, 28), 3, 360},
+		{DayCount30360, date(2024, time.February, 28), 1, 360},
+		{DayCount30360, date(2024, time.February, 29), 2, 360},
+		{DayCount30360, date(2023, time.December, 31), 1, 360},
+	}
+
+	for _, tc := range testCases {
+		days, yearDays, err := InterestDays(tc.convention, tc.date)
+		require.NoError(t, err)
+		require.Equal(t, tc.days, days, "%s %s", tc.convention, tc.date.Format("2006-01-02"))
+		require.Equal(t, tc.yearDays, yearDays)
+	}
+
+	// every month earns 30 days under 30/360
+	for month := time.January; month <= time.December; month++ {
+		var total int64
+
+		for day := date(2023, month, 1); day.Month() == month; day = day.AddDate(0, 0, 1) {
+			days, _, err := InterestDays(DayCount30360, day)
+			require.NoError(t, err)
+			total += days
+		}
+		require.Equal(t, int64(30), total, month.String())
+	}
+
+	_, _, err := InterestDays("actual/actual", date(2023, time.January, 1))
+	require.Error(t, err)
+}
+
+func TestDailyInterestMicros(t *testing.T) {
+	date := time.Date(2023, time.March, 10, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		balance    int64
+		rateBps    int32
+		convention string
+		micros     int64
+	}{
+		{"Actual365", 36500, 1000, DayCountActual365, 10 * MicrosPerUnit},
+		{"Actual360", 36000, 500, DayCountActual360, 5 * MicrosPerUnit},
+		{"RoundedDown", 100, 500, DayCountActual365, 13698},
+		{"NegativeBalance", -36500, 1000, DayCountActual365, 0},
+		{"ZeroRate", 36500, 0, DayCountActual365, 0},
+		{"NoOverflow", 1000000000000000, 10000, DayCountActual360, 2777777777777777777},
+	}
+
+	for _, tc := range testCases {
+		micros, err := DailyInterestMicros(tc.balance, tc.rateBps, tc.convention, date)
+		require.NoError(t, err)
+		require.Equal(t, tc.micros, micros, tc.name)
+	}
+
+	_, err := DailyInterestMicros(1<<62, 10000, DayCountActual360, date)
+	require.Error(t, err)
+}
@@ -0,0 +1,16 @@
+package utils
+
+// constants for all supported transfer limit tiers
+const (
+	StandardTier = "standard"
+	PremiumTier  = "premium"
+)
+
+// IsLimitTierSupported returns true if the transfer limit tier is supported
+func IsLimitTierSupported(tier string) bool {
+	switch tier {
+	case StandardTier, PremiumTier:
+		return true
+	}
+	return false
+}
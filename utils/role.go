@@ -0,0 +1,17 @@
+package utils
+
+// constants for all supported user roles
+const (
+	CustomerRole = "customer"
+	BankerRole   = "banker"
+	AdminRole    = "admin"
+)
+
+// IsRoleSupported returns true if the role is supported
+func IsRoleSupported(role string) bool {
+	switch role {
+	case CustomerRole, BankerRole, AdminRole:
+		return true
+	}
+	return false
+}
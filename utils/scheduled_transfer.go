@@ -0,0 +1,9 @@
+package utils
+
+// constants for all scheduled transfer statuses
+const (
+	ScheduledTransferStatusActive    = "active"    // waiting for its next run
+	ScheduledTransferStatusCompleted = "completed" // a one-off transfer that ran successfully
+	ScheduledTransferStatusFailed    = "failed"    // a one-off transfer that used up all its attempts
+	ScheduledTransferStatusCancelled = "cancelled" // cancelled by its owner
+)